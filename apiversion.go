@@ -0,0 +1,59 @@
+package telemetrydeck
+
+import "bytes"
+
+// APIVersion identifies a version of the TelemetryDeck Ingest API, which
+// determines the format signals are encoded in.
+type APIVersion int
+
+const (
+	// The legacy Ingest API v1, see WithAPIVersion.
+	APIVersion1 APIVersion = 1
+
+	// Ingest API v2, used by default.
+	APIVersion2 APIVersion = 2
+)
+
+// Encodes signals in the request body format of an Ingest API version.
+// Request bodies are arrays of the encoded signals.
+type ingestFormat interface {
+	// Appends the encoding of the signal to the buffer.
+	appendSignal(buf *bytes.Buffer, s *SignalBody) error
+
+	// Returns the approximate size of the encoding of the signal, used to
+	// size batches and buffers. Takes the signal by value, as passing a
+	// pointer through the interface would make enqueued signals escape to
+	// the heap.
+	estimateSignalSize(s SignalBody) int
+
+	// Returns the endpoint to send signals to instead of the configured
+	// one, e.g. the equivalent of a TelemetryDeck endpoint in the
+	// version's URL scheme.
+	endpoint(configured string) string
+}
+
+// Constructors of the formats of the supported API versions, taking the
+// client, once all options have been applied, and the settings which may
+// change while the client is running, like the hashed user ID (see
+// Reconfigure) and the session ID.
+var ingestFormats = map[APIVersion]func(c *Client, l *liveConfig) ingestFormat{
+	APIVersion1: newV1Format,
+	APIVersion2: newV2Format,
+}
+
+// WithAPIVersion specifies the version of the Ingest API whose format
+// signals are encoded in. Defaults to APIVersion2. NewClient returns an
+// error wrapping ErrUnsupportedAPIVersion for versions this package
+// doesn't support. The endpoint must accept the format of the version.
+//
+// APIVersion1 is meant for setups that only understand the legacy v1
+// format, like older self-hosted proxies. TelemetryDeck endpoints,
+// including those of regions, are replaced by their v1 equivalents, other
+// endpoints are used as given. Payload values are sent as strings.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithAPIVersion(version APIVersion) func(*Client) {
+	return func(c *Client) {
+		c.apiVersion = version
+	}
+}
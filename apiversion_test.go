@@ -0,0 +1,74 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Format encoding signals as their type only, to check that the client
+// encodes signals in the format of the configured version.
+type typeOnlyFormat struct{}
+
+func (typeOnlyFormat) appendSignal(buf *bytes.Buffer, s *SignalBody) error {
+	writeJSONString(buf, s.Type)
+	return nil
+}
+
+func (typeOnlyFormat) estimateSignalSize(s SignalBody) int {
+	return len(s.Type) + 2
+}
+
+func (typeOnlyFormat) endpoint(configured string) string {
+	return configured
+}
+
+func TestWithAPIVersion(t *testing.T) {
+	const testVersion APIVersion = 99
+	ingestFormats[testVersion] = func(*Client, *liveConfig) ingestFormat { return typeOnlyFormat{} }
+	defer delete(ingestFormats, testVersion)
+
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithAPIVersion(testVersion))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.versionTest", nil); err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+	if got, want := <-bodies, `["TestNamespace.versionTest"]`; got != want {
+		t.Errorf("request body = %s, want %s", got, want)
+	}
+
+	_, err = NewClient("my-app-id", WithAPIVersion(3))
+	if !errors.Is(err, ErrUnsupportedAPIVersion) {
+		t.Errorf("NewClient() error = %v, want ErrUnsupportedAPIVersion", err)
+	}
+}
+
+func TestWithAPIVersion_default(t *testing.T) {
+	c, err := NewClient("my-app-id", WithAPIVersion(APIVersion2))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	signal := c.newSignal("TestNamespace.versionTest", map[string]interface{}{"key": "value"})
+	if err := c.appendSignal(&buf, &signal); err != nil {
+		t.Fatalf("Client.appendSignal() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"appID":"my-app-id","clientUser":`) || !strings.Contains(buf.String(), `"key":"value"`) {
+		t.Errorf("Client.appendSignal() = %s, want v2 format", buf.String())
+	}
+}
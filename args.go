@@ -0,0 +1,116 @@
+package telemetrydeck
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Replacement for the values of sensitive flags in SanitizeArgs.
+const redacted = "REDACTED"
+
+// Arguments longer than this are truncated by SanitizeArgs, unless
+// configured otherwise via MaxArgLength.
+const defaultMaxArgLength = 64
+
+// DefaultSensitiveFlags are the flags whose values SanitizeArgs redacts,
+// unless configured otherwise via SensitiveFlags. Names are matched
+// case-insensitively, with any number of leading dashes.
+var DefaultSensitiveFlags = []string{
+	"token", "password", "passwd", "secret", "api-key", "apikey",
+	"access-key", "private-key", "auth", "credentials",
+}
+
+// Home directories of other users, e.g. /home/alice or C:\Users\alice.
+var homeDirPattern = regexp.MustCompile(`(?i)(/home/|/Users/|[A-Z]:\\Users\\)[^/\\]+`)
+
+// SanitizeRule configures SanitizeArgs.
+type SanitizeRule func(*argSanitizer)
+
+type argSanitizer struct {
+	sensitive map[string]bool
+	maxLength int
+	homeDir   string
+}
+
+// SensitiveFlags replaces the flags whose values are redacted, by default
+// DefaultSensitiveFlags.
+func SensitiveFlags(names ...string) SanitizeRule {
+	return func(s *argSanitizer) {
+		s.sensitive = map[string]bool{}
+		for _, name := range names {
+			s.sensitive[strings.ToLower(strings.TrimLeft(name, "-"))] = true
+		}
+	}
+}
+
+// MaxArgLength specifies the length after which arguments are truncated,
+// by default 64. Zero or less disables truncation.
+func MaxArgLength(n int) SanitizeRule {
+	return func(s *argSanitizer) {
+		s.maxLength = n
+	}
+}
+
+// SanitizeArgs returns a copy of the command line arguments, e.g.
+// os.Args[1:], that can be included in signals safely:
+//
+//   - Values of sensitive flags, like --token secret or --password=secret,
+//     are replaced with "REDACTED" (see SensitiveFlags).
+//   - The home directory of the current user is replaced with "~", and the
+//     user name in other home directories, like /home/alice, as well, so
+//     that user names don't leak via file paths.
+//   - Long arguments are truncated (see MaxArgLength).
+func SanitizeArgs(args []string, rules ...SanitizeRule) []string {
+	s := &argSanitizer{maxLength: defaultMaxArgLength}
+	SensitiveFlags(DefaultSensitiveFlags...)(s)
+	s.homeDir, _ = os.UserHomeDir()
+	for _, rule := range rules {
+		rule(s)
+	}
+
+	sanitized := make([]string, len(args))
+	redactNext := false
+	for i, arg := range args {
+		switch {
+		case redactNext:
+			arg = redacted
+			redactNext = false
+		case strings.HasPrefix(arg, "-"):
+			name, _, hasValue := strings.Cut(arg, "=")
+			if s.sensitive[strings.ToLower(strings.TrimLeft(name, "-"))] {
+				if hasValue {
+					arg = name + "=" + redacted
+				} else {
+					redactNext = true
+				}
+			}
+		}
+
+		sanitized[i] = s.truncate(s.redactHomeDirs(arg))
+	}
+	return sanitized
+}
+
+// Replaces home directories in the argument.
+func (s *argSanitizer) redactHomeDirs(arg string) string {
+	if s.homeDir != "" && len(s.homeDir) > 1 {
+		arg = strings.ReplaceAll(arg, s.homeDir, "~")
+	}
+	return homeDirPattern.ReplaceAllString(arg, "$1~")
+}
+
+// Truncates the argument to the maximum length, marking it with an
+// ellipsis.
+func (s *argSanitizer) truncate(arg string) string {
+	if s.maxLength <= 0 || len(arg) <= s.maxLength {
+		return arg
+	}
+	// Don't cut UTF-8 sequences in half
+	cut := s.maxLength
+	for cut > 0 && !utf8.RuneStart(arg[cut]) {
+		cut--
+	}
+	return arg[:cut] + "…"
+}
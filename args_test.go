@@ -0,0 +1,70 @@
+package telemetrydeck
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeArgs(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil || len(home) <= 1 {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		rules []SanitizeRule
+		want  []string
+	}{
+		{
+			name: "sensitive flag with separate value",
+			args: []string{"login", "--token", "secret", "--verbose"},
+			want: []string{"login", "--token", "REDACTED", "--verbose"},
+		},
+		{
+			name: "sensitive flag with inline value",
+			args: []string{"-Password=secret", "--name=value"},
+			want: []string{"-Password=REDACTED", "--name=value"},
+		},
+		{
+			name:  "custom sensitive flags",
+			args:  []string{"--token", "visible", "--kubeconfig", "secret"},
+			rules: []SanitizeRule{SensitiveFlags("--kubeconfig")},
+			want:  []string{"--token", "visible", "--kubeconfig", "REDACTED"},
+		},
+		{
+			name: "home directories",
+			args: []string{filepath.Join(home, "project"), "--file=/home/alice/notes.txt", `C:\Users\bob\file`},
+			want: []string{"~" + string(filepath.Separator) + "project", "--file=/home/~/notes.txt", `C:\Users\~\file`},
+		},
+		{
+			name: "long argument",
+			args: []string{strings.Repeat("a", 70)},
+			want: []string{strings.Repeat("a", 64) + "…"},
+		},
+		{
+			name:  "long multi-byte argument",
+			args:  []string{"ääää"},
+			rules: []SanitizeRule{MaxArgLength(3)},
+			want:  []string{"ä…"},
+		},
+		{
+			name:  "no truncation",
+			args:  []string{strings.Repeat("a", 70)},
+			rules: []SanitizeRule{MaxArgLength(0)},
+			want:  []string{strings.Repeat("a", 70)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeArgs(tt.args, tt.rules...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SanitizeArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package telemetrydeck
+
+// Default queue watermarks, in percent of the queue size.
+const (
+	defaultHighWatermarkPercent = 80
+	defaultLowWatermarkPercent  = 20
+)
+
+// BackpressureEvent reports that the number of queued signals crossed a
+// watermark (see WithQueueWatermarks).
+type BackpressureEvent struct {
+	// True if the high watermark has been reached, false if the queue
+	// length dropped to the low watermark afterwards.
+	High bool
+
+	// Number of queued signals when the watermark was crossed.
+	Queued int
+}
+
+// WithQueueWatermarks specifies when the OnBackpressure hook is called:
+// once the number of queued signals reaches high, and once it drops to low
+// afterwards. Defaults to 80% and 20% of the queue size (see
+// WithQueueSize).
+//
+// To be used as an option parameter in the NewClient() func.
+func WithQueueWatermarks(high, low int) func(*Client) {
+	return func(c *Client) {
+		c.highWatermark = high
+		c.lowWatermark = low
+	}
+}
+
+// Sets the watermarks not configured explicitly, relative to the queue size.
+func (c *Client) initWatermarks() {
+	if c.highWatermark <= 0 {
+		c.highWatermark = max(c.queueSize*defaultHighWatermarkPercent/100, 1)
+	}
+	if c.lowWatermark <= 0 || c.lowWatermark >= c.highWatermark {
+		c.lowWatermark = min(c.queueSize*defaultLowWatermarkPercent/100, c.highWatermark-1)
+	}
+}
+
+// Reports it to the metrics and the OnBackpressure hook if the queue length
+// n crossed a watermark. Crossings are reported at most once, even if the
+// queue length is reported concurrently.
+func (c *Client) checkWatermarks(n int) {
+	switch {
+	case n >= c.highWatermark && c.backpressure.CompareAndSwap(false, true):
+		c.watermarkCrossed(BackpressureEvent{High: true, Queued: n})
+	case n <= c.lowWatermark && c.backpressure.CompareAndSwap(true, false):
+		c.watermarkCrossed(BackpressureEvent{High: false, Queued: n})
+	}
+}
+
+// Reports the watermark crossing to the metrics and the OnBackpressure hook.
+func (c *Client) watermarkCrossed(event BackpressureEvent) {
+	if c.metrics != nil {
+		var value int64
+		if event.High {
+			value = 1
+		}
+		c.metrics.Set(MetricBackpressure, value)
+		c.metrics.Add(MetricWatermarkCrossings, 1)
+	}
+	if c.hooks.OnBackpressure != nil {
+		c.hooks.OnBackpressure(event)
+	}
+}
@@ -0,0 +1,89 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_Backpressure(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var events []BackpressureEvent
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithWorkers(1),
+		WithMaxBatchSize(1),
+		WithQueueWatermarks(5, 2),
+		WithHooks(Hooks{OnBackpressure: func(event BackpressureEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event)
+		}}),
+		deliverImmediately,
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// The first signal is taken by the blocked worker
+	for i := 0; i < 7; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.backpressureTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	mu.Lock()
+	if len(events) != 1 || !events[0].High {
+		t.Errorf("events before delivery = %+v, want one high watermark event", events)
+	}
+	mu.Unlock()
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []BackpressureEvent{{High: true, Queued: 5}, {High: false, Queued: 2}}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+}
+
+func TestClient_initWatermarks(t *testing.T) {
+	tests := []struct {
+		name      string
+		queueSize int
+		high, low int
+		wantHigh  int
+		wantLow   int
+	}{
+		{name: "defaults", queueSize: 1000, wantHigh: 800, wantLow: 200},
+		{name: "configured", queueSize: 1000, high: 500, low: 100, wantHigh: 500, wantLow: 100},
+		{name: "low above high", queueSize: 1000, high: 100, low: 500, wantHigh: 100, wantLow: 99},
+		{name: "tiny queue", queueSize: 1, wantHigh: 1, wantLow: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{queueSize: tt.queueSize, highWatermark: tt.high, lowWatermark: tt.low}
+			c.initWatermarks()
+			if c.highWatermark != tt.wantHigh || c.lowWatermark != tt.wantLow {
+				t.Errorf("initWatermarks() = %d, %d, want %d, %d", c.highWatermark, c.lowWatermark, tt.wantHigh, tt.wantLow)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package telemetrydeck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Token bucket limiting the number of request body bytes sent per second.
+// Safe for concurrent use.
+type bandwidthLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+// WithBandwidthLimit caps the telemetry throughput to the given number of
+// request body bytes per second, so that telemetry never competes
+// meaningfully with production traffic on constrained links. Deliveries
+// exceeding the budget are delayed. Bursts of up to one second worth of
+// bytes are allowed.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithBandwidthLimit(bytesPerSecond int) func(*Client) {
+	return func(c *Client) {
+		c.bandwidthLimit = bytesPerSecond
+	}
+}
+
+func newBandwidthLimiter(bytesPerSecond int, clock Clock) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		rate:   float64(bytesPerSecond),
+		burst:  float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   clock.Now(),
+		clock:  clock,
+	}
+}
+
+// Waits until n more bytes may be sent, or the context is done. Bodies
+// larger than the burst size are let through once the bucket has been
+// refilled, putting the bucket in debt.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := l.clock.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := l.clock.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
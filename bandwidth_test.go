@@ -0,0 +1,40 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_bandwidthLimiter(t *testing.T) {
+	l := newBandwidthLimiter(10000, systemClock{})
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := l.wait(ctx, 10000); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("wait() within burst took %s", elapsed)
+	}
+
+	start = time.Now()
+	if err := l.wait(ctx, 2000); err != nil {
+		t.Fatalf("wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("wait() beyond burst took %s, want about 200ms", elapsed)
+	}
+}
+
+func Test_bandwidthLimiter_ContextDone(t *testing.T) {
+	l := newBandwidthLimiter(100, systemClock{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.wait(ctx, 1000); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait() error = %v, want context.DeadlineExceeded", err)
+	}
+}
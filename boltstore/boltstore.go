@@ -0,0 +1,177 @@
+// Package boltstore provides a telemetrydeck.QueueStore backed by a bbolt
+// database, for agents that need durable, transactional buffering of
+// signals. It lives in its own package so that only users of it depend on
+// bbolt.
+package boltstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+// Name of the bucket holding the queued signals, keyed by their ID
+var bucketName = []byte("signals")
+
+// Store is a QueueStore keeping signals in a bbolt database. Every signal
+// is committed in its own transaction when enqueued, and deleted in the
+// transaction acknowledging it, so that signals dequeued but not
+// acknowledged are dequeued again after a restart.
+//
+// Bearer tokens are not persisted; signals are delivered with a token
+// requested from the client's token source instead.
+type Store struct {
+	db       *bolt.DB
+	capacity int
+
+	mu sync.Mutex
+	// ID of the next signal to dequeue
+	next uint64
+	// Number of signals not dequeued yet, and not acknowledged yet
+	pending int
+	stored  int
+}
+
+// Open opens the database at the given path, creating it if necessary. The
+// store rejects signals with telemetrydeck.ErrQueueFull once it holds
+// capacity signals that have not been acknowledged; a capacity of zero or
+// less means no limit.
+func Open(path string, capacity int) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening queue database: %w", err)
+	}
+
+	s := &Store{db: db, capacity: capacity}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketName)
+		if err != nil {
+			return err
+		}
+		s.stored = b.Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening queue database: %w", err)
+	}
+	s.pending = s.stored
+	return s, nil
+}
+
+// Enqueue implements telemetrydeck.QueueStore.
+func (s *Store) Enqueue(signal telemetrydeck.QueuedSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.capacity > 0 && s.stored >= s.capacity {
+		return telemetrydeck.ErrQueueFull
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		signal.ID = id
+		value, err := json.Marshal(signal)
+		if err != nil {
+			return err
+		}
+		return b.Put(key(id), value)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.pending++
+	s.stored++
+	return nil
+}
+
+// DequeueBatch implements telemetrydeck.QueueStore.
+func (s *Store) DequeueBatch(max int) ([]telemetrydeck.QueuedSignal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		return nil, nil
+	}
+
+	var batch []telemetrydeck.QueuedSignal
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketName).Cursor()
+		for k, v := c.Seek(key(s.next)); k != nil && len(batch) < max; k, v = c.Next() {
+			var signal telemetrydeck.QueuedSignal
+			if err := json.Unmarshal(v, &signal); err != nil {
+				return fmt.Errorf("decoding signal %d: %w", binary.BigEndian.Uint64(k), err)
+			}
+			batch = append(batch, signal)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(batch) > 0 {
+		s.next = batch[len(batch)-1].ID + 1
+		s.pending -= len(batch)
+	}
+	return batch, nil
+}
+
+// Ack implements telemetrydeck.QueueStore. The signals are deleted in a
+// single transaction.
+func (s *Store) Ack(signals []telemetrydeck.QueuedSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		for _, signal := range signals {
+			k := key(signal.ID)
+			if b.Get(k) == nil {
+				continue
+			}
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.stored -= deleted
+	return nil
+}
+
+// Len implements telemetrydeck.QueueStore.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Close closes the database. Signals that have not been acknowledged are
+// dequeued again by the next store opening it.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Returns the database key of the signal with the given ID. Keys are big
+// endian, so that signals are iterated in the order they were enqueued.
+func key(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
@@ -0,0 +1,103 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+	s, err := Open(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, typ := range []string{"first", "second", "third"} {
+		if err := s.Enqueue(telemetrydeck.QueuedSignal{Signal: telemetrydeck.SignalBody{Type: typ}, Token: "token"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := s.Enqueue(telemetrydeck.QueuedSignal{}); !errors.Is(err, telemetrydeck.ErrQueueFull) {
+		t.Errorf("Enqueue() to full store error = %v, want %v", err, telemetrydeck.ErrQueueFull)
+	}
+
+	batch, err := s.DequeueBatch(2)
+	if err != nil || len(batch) != 2 || batch[0].Signal.Type != "first" || batch[1].Signal.Type != "second" {
+		t.Fatalf("DequeueBatch() = %+v, %v, want first and second signal", batch, err)
+	}
+	if batch[0].Token != "" {
+		t.Errorf("DequeueBatch()[0].Token = %q, want tokens not to be persisted", batch[0].Token)
+	}
+	if got := s.Len(); got != 1 {
+		t.Errorf("Len() after DequeueBatch() = %d, want 1", got)
+	}
+
+	// Only acknowledged signals are gone after a restart
+	if err := s.Ack(batch[:1]); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.Len(); got != 2 {
+		t.Errorf("Len() after restart = %d, want 2", got)
+	}
+	batch, err = s.DequeueBatch(10)
+	if err != nil || len(batch) != 2 || batch[0].Signal.Type != "second" || batch[1].Signal.Type != "third" {
+		t.Fatalf("DequeueBatch() after restart = %+v, %v, want second and third signal", batch, err)
+	}
+	if batch, _ := s.DequeueBatch(10); len(batch) != 0 {
+		t.Errorf("DequeueBatch() = %+v, want dequeued signals not to be dequeued again", batch)
+	}
+}
+
+func TestStore_Client(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []telemetrydeck.SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		received.Add(int32(len(signals)))
+	}))
+	defer server.Close()
+
+	s, err := Open(filepath.Join(t.TempDir(), "queue.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c, err := telemetrydeck.NewClient("my-app-id", telemetrydeck.WithEndpoint(server.URL), telemetrydeck.WithQueueStore(s))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := c.SendStringSignal(context.Background(), "TestNamespace.boltTest", map[string]string{"key": "value"}); err != nil {
+			t.Fatalf("Client.SendStringSignal() error = %v", err)
+		}
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	if got := received.Load(); got != 10 {
+		t.Errorf("server received %d signals, want 10", got)
+	}
+	if got := s.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
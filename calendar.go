@@ -0,0 +1,40 @@
+package telemetrydeck
+
+import "time"
+
+// Payload keys of the calendar parameters, see WithCalendarParameters.
+const (
+	CalendarDayOfWeekKey   = "TelemetryDeck.Calendar.dayOfWeek"
+	CalendarWeekOfYearKey  = "TelemetryDeck.Calendar.weekOfYear"
+	CalendarMonthOfYearKey = "TelemetryDeck.Calendar.monthOfYear"
+	CalendarIsWeekendKey   = "TelemetryDeck.Calendar.isWeekend"
+)
+
+// WithCalendarParameters makes the client add parameters derived from the
+// local time a signal is sent at to its payload, following TelemetryDeck's
+// conventions: the day of the week from 1 (Monday) to 7 (Sunday), the ISO
+// 8601 week of the year, the month of the year from 1 to 12, and whether
+// it's a weekend day. This simplifies cohort queries, which would have to
+// derive them from the time otherwise.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithCalendarParameters() func(*Client) {
+	return func(c *Client) {
+		c.calendarParameters = true
+	}
+}
+
+// Adds the calendar parameters of the time to the payload.
+func addCalendarParameters[V any](payload map[string]V, t time.Time) {
+	weekday := t.Weekday()
+	dayOfWeek := int64(weekday)
+	if weekday == time.Sunday {
+		dayOfWeek = 7
+	}
+	_, week := t.ISOWeek()
+
+	payload[CalendarDayOfWeekKey] = parameterValue[V](dayOfWeek)
+	payload[CalendarWeekOfYearKey] = parameterValue[V](int64(week))
+	payload[CalendarMonthOfYearKey] = parameterValue[V](int64(t.Month()))
+	payload[CalendarIsWeekendKey] = parameterValue[V](weekday == time.Saturday || weekday == time.Sunday)
+}
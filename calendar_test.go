@@ -0,0 +1,39 @@
+package telemetrydeck
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClient_CalendarParameters(t *testing.T) {
+	// A Sunday in ISO week 52 of 2023
+	clock := &manualClock{now: time.Date(2023, 12, 31, 12, 0, 0, 0, time.Local)}
+	c, err := NewClient("my-app-id", WithClock(clock), WithCalendarParameters())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	payload := c.newSignal("TestNamespace.calendarTest", nil).Payload
+	want := map[string]interface{}{
+		CalendarDayOfWeekKey:   int64(7),
+		CalendarWeekOfYearKey:  int64(52),
+		CalendarMonthOfYearKey: int64(12),
+		CalendarIsWeekendKey:   true,
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("payload = %v, want %v", payload, want)
+	}
+
+	clock.advance(24 * time.Hour)
+	stringPayload := c.newStringSignal("TestNamespace.calendarTest", nil).stringPayload
+	wantString := map[string]string{
+		CalendarDayOfWeekKey:   "1",
+		CalendarWeekOfYearKey:  "1",
+		CalendarMonthOfYearKey: "1",
+		CalendarIsWeekendKey:   "false",
+	}
+	if !reflect.DeepEqual(stringPayload, wantString) {
+		t.Errorf("string payload = %v, want %v", stringPayload, wantString)
+	}
+}
@@ -0,0 +1,62 @@
+package telemetrydeck
+
+import "time"
+
+// Clock is the source of time for all time-dependent behavior of the
+// client, like batching by age, retry backoff and request durations.
+// Implementations must be safe for concurrent use.
+type Clock interface {
+	Now() time.Time
+
+	// NewTimer returns a timer sending the current time on its channel
+	// after the duration, like time.NewTimer.
+	NewTimer(d time.Duration) Timer
+
+	// AfterFunc calls f in its own goroutine after the duration, like
+	// time.AfterFunc. The returned timer has a nil channel.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer created by a Clock.
+type Timer interface {
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, like time.Timer.Stop.
+	Stop() bool
+}
+
+// WithClock replaces the system clock used by the client, e.g. with a
+// fake clock in tests (see telemetrydecktest.Clock), so that timing
+// behavior can be tested without sleeping.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithClock(clock Clock) func(*Client) {
+	return func(c *Client) {
+		if clock != nil {
+			c.clock = clock
+		}
+	}
+}
+
+// Clock using the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
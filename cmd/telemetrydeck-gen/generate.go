@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+// Go types of the parameter types
+var goTypes = map[telemetrydeck.ParameterType]string{
+	telemetrydeck.ParameterString: "string",
+	telemetrydeck.ParameterNumber: "float64",
+	telemetrydeck.ParameterBool:   "bool",
+	telemetrydeck.ParameterAny:    "interface{}",
+}
+
+// Data passed to the template for a signal type.
+type signal struct {
+	Name        string
+	Type        string
+	Description string
+	Fields      []field
+}
+
+// Data passed to the template for a payload key.
+type field struct {
+	Name        string
+	Key         string
+	GoType      string
+	Description string
+	Required    bool
+	// Whether the field is a pointer, to tell unset optional values apart
+	Pointer bool
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by telemetrydeck-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+{{range .Signals}}
+// {{.Name}} is a signal of type {{.Type}}.
+{{- with .Description}}
+//
+// {{.}}
+{{- end}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	// {{with .Description}}{{.}} {{end}}({{.Key}}{{if not .Required}}, optional{{end}})
+	{{.Name}} {{if .Pointer}}*{{end}}{{.GoType}}
+{{- end}}
+}
+
+// SignalType returns the type of the signal, {{.Type}}.
+func (s {{.Name}}) SignalType() string {
+	return {{printf "%q" .Type}}
+}
+
+// Payload returns the payload of the signal. Optional keys whose fields are
+// nil are omitted.
+func (s {{.Name}}) Payload() map[string]interface{} {
+	payload := make(map[string]interface{}, {{len .Fields}})
+{{- range .Fields}}
+{{- if .Required}}
+	payload[{{printf "%q" .Key}}] = s.{{.Name}}
+{{- else}}
+	if s.{{.Name}} != nil {
+		payload[{{printf "%q" .Key}}] = {{if .Pointer}}*{{end}}s.{{.Name}}
+	}
+{{- end}}
+{{- end}}
+	return payload
+}
+
+// Send sends the signal with the client, see telemetrydeck.Client.SendSignal.
+func (s {{.Name}}) Send(ctx context.Context, c *telemetrydeck.Client) error {
+	return c.SendSignal(ctx, s.SignalType(), s.Payload())
+}
+{{end -}}
+`))
+
+// Generates the Go source of typed structs for the signals in the
+// registry, in the given package. Source is the name of the schema file,
+// mentioned in the header of the generated file.
+func generate(registry *telemetrydeck.SchemaRegistry, pkg, source string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+
+	var signals []signal
+	names := map[string]string{}
+	for _, schema := range registry.Schemas() {
+		s := signal{
+			Name:        identifier(schema.Type),
+			Type:        schema.Type,
+			Description: comment(schema.Description),
+		}
+		if other, ok := names[s.Name]; ok || s.Name == "" {
+			return nil, fmt.Errorf("signal %s: struct name %q clashes with signal %s or is invalid", schema.Type, s.Name, other)
+		}
+		names[s.Name] = schema.Type
+
+		keys := make([]string, 0, len(schema.Parameters))
+		for key := range schema.Parameters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fieldNames := map[string]string{}
+		for _, key := range keys {
+			parameter := schema.Parameters[key]
+			goType, ok := goTypes[parameter.Type]
+			if !ok {
+				return nil, fmt.Errorf("signal %s: key %s has unknown type %q", schema.Type, key, parameter.Type)
+			}
+			f := field{
+				Name:        identifier(key[strings.LastIndex(key, ".")+1:]),
+				Key:         key,
+				GoType:      goType,
+				Description: comment(parameter.Description),
+				Required:    parameter.Required,
+				Pointer:     !parameter.Required && parameter.Type != telemetrydeck.ParameterAny,
+			}
+			if other, ok := fieldNames[f.Name]; ok || f.Name == "" {
+				return nil, fmt.Errorf("signal %s: field name %q of key %s clashes with key %s or is invalid", schema.Type, f.Name, key, other)
+			}
+			fieldNames[f.Name] = key
+			s.Fields = append(s.Fields, f)
+		}
+		signals = append(signals, s)
+	}
+
+	var b bytes.Buffer
+	err := fileTemplate.Execute(&b, map[string]interface{}{
+		"Source":  source,
+		"Package": pkg,
+		"Signals": signals,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
+
+// Returns the exported Go identifier of the signal type or key, made of its
+// letters and digits, capitalizing the first letter of every part
+// separated by other characters. Returns an empty string if there are no
+// letters.
+func identifier(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && !unicode.IsLetter(r) {
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Returns the description on a single line, so it can be used in a
+// comment.
+func comment(description string) string {
+	return strings.Join(strings.Fields(description), " ")
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/giantswarm/telemetrydeck-go"
+	"github.com/giantswarm/telemetrydeck-go/cmd/telemetrydeck-gen/internal/example"
+	"github.com/giantswarm/telemetrydeck-go/telemetrydecktest"
+)
+
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "signals_gen.go")
+	if err := run("internal/example/signals.json", output, "example"); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := "internal/example/signals_gen.go"
+	if os.Getenv(telemetrydecktest.UpdateGoldenEnv) != "" {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("generated code differs from %s (set %s=1 to update it):\n%s", path, telemetrydecktest.UpdateGoldenEnv, got)
+	}
+
+	if err := run("internal/example/signals.json", output, ""); err == nil {
+		t.Error("run() succeeded without package")
+	}
+}
+
+func TestGenerated(t *testing.T) {
+	data, err := os.ReadFile("internal/example/signals.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var registry telemetrydeck.SchemaRegistry
+	if err := registry.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+
+	gift := true
+	signals := []interface {
+		SignalType() string
+		Payload() map[string]interface{}
+	}{
+		example.ExampleAppLaunched{},
+		example.ExamplePurchaseCompleted{Product: "book", Amount: 12.5},
+		example.ExamplePurchaseCompleted{Product: "book", Amount: 12.5, Gift: &gift, Context: "checkout"},
+	}
+	for _, s := range signals {
+		if err := registry.Validate(s.SignalType(), s.Payload()); err != nil {
+			t.Errorf("payload %v doesn't match schema: %v", s.Payload(), err)
+		}
+	}
+
+	payload := signals[1].Payload()
+	if _, ok := payload["Example.gift"]; ok {
+		t.Errorf("payload %v contains unset optional key", payload)
+	}
+	if got := signals[2].Payload()["Example.gift"]; got != true {
+		t.Errorf("payload value of Example.gift = %v, want true", got)
+	}
+}
+
+func TestGenerate_clashes(t *testing.T) {
+	tests := []struct {
+		name    string
+		schemas []telemetrydeck.SignalSchema
+		wantErr string
+	}{
+		{
+			name:    "struct names",
+			schemas: []telemetrydeck.SignalSchema{{Type: "Example.app-launched"}, {Type: "Example.appLaunched"}},
+			wantErr: "clashes with signal Example.app-launched",
+		},
+		{
+			name: "field names",
+			schemas: []telemetrydeck.SignalSchema{{
+				Type: "Example.appLaunched",
+				Parameters: map[string]telemetrydeck.ParameterSchema{
+					"Example.source":       {Type: telemetrydeck.ParameterString},
+					"Example.Other.source": {Type: telemetrydeck.ParameterString},
+				},
+			}},
+			wantErr: "clashes with key Example.Other.source",
+		},
+		{
+			name:    "no letters",
+			schemas: []telemetrydeck.SignalSchema{{Type: "1.2"}},
+			wantErr: "invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := telemetrydeck.NewSchemaRegistry(tt.schemas...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = generate(registry, "example", "signals.json")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("generate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_identifier(t *testing.T) {
+	tests := map[string]string{
+		"MyApp.purchaseCompleted": "MyAppPurchaseCompleted",
+		"checkout_started":        "CheckoutStarted",
+		"2fa.enabled":             "FaEnabled",
+		"MyApp.step2":             "MyAppStep2",
+		"...":                     "",
+	}
+	for in, want := range tests {
+		if got := identifier(in); got != want {
+			t.Errorf("identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
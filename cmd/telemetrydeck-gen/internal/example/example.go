@@ -0,0 +1,7 @@
+// Package example holds code generated by telemetrydeck-gen from an example
+// schema file. It's compiled with the rest of the module, so that changes
+// to the generator breaking the generated code are caught, and serves as
+// the expected output in tests.
+package example
+
+//go:generate go run ../.. -schema signals.json
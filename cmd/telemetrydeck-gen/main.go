@@ -0,0 +1,82 @@
+// Command telemetrydeck-gen generates typed Go structs for the signal types
+// declared in a schema file, the JSON encoding of a
+// telemetrydeck.SchemaRegistry. For every signal type, a struct with a
+// field per payload key is generated, with methods returning the type and
+// the payload of the signal and sending it with a telemetrydeck.Client.
+// Keeping the declarations in a schema file makes them reviewable, and the
+// same file can be loaded into a SchemaRegistry to validate signals in
+// tests.
+//
+// The schema file looks like this:
+//
+//	{
+//	  "signals": [
+//	    {
+//	      "type": "MyApp.purchaseCompleted",
+//	      "description": "A purchase was completed.",
+//	      "parameters": {
+//	        "MyApp.product": {"type": "string", "required": true},
+//	        "MyApp.amount": {"type": "number", "required": true},
+//	        "MyApp.gift": {"type": "bool"}
+//	      }
+//	    }
+//	  ]
+//	}
+//
+// Parameter types are string, number, bool and any. Optional parameters
+// become pointer fields, omitted from the payload if nil.
+//
+// The command is meant to be run via go:generate:
+//
+//	//go:generate go run github.com/giantswarm/telemetrydeck-go/cmd/telemetrydeck-gen -schema signals.json
+//
+// By default, the code is written to a file named after the schema file
+// with the suffix _gen.go, in the package go:generate is run for.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "signals.json", "path of the schema file")
+	output := flag.String("o", "", "path of the generated file (default: schema file name with suffix _gen.go)")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of the generated file (default: $GOPACKAGE)")
+	flag.Parse()
+
+	if err := run(*schemaPath, *output, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "telemetrydeck-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaPath, output, pkg string) error {
+	if pkg == "" {
+		return fmt.Errorf("no package given, and $GOPACKAGE is not set")
+	}
+	if output == "" {
+		output = strings.TrimSuffix(schemaPath, filepath.Ext(schemaPath)) + "_gen.go"
+	}
+
+	data, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return err
+	}
+	var registry telemetrydeck.SchemaRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return fmt.Errorf("parsing %s: %w", schemaPath, err)
+	}
+
+	source, err := generate(&registry, pkg, filepath.Base(schemaPath))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output, source, 0o644)
+}
@@ -0,0 +1,71 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"compress/gzip"
+	"sync"
+)
+
+// Bodies smaller than this are not worth compressing.
+const minCompressionSize = 1024
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// WithCompression makes the client gzip-compress request bodies, which
+// reduces the telemetry traffic considerably for bodies containing many
+// signals. Compression operates on whole request bodies, using pooled
+// writers, to keep the CPU overhead low. Bodies smaller than 1 KiB are sent
+// uncompressed.
+//
+// Only enable this if the ingest endpoint supports gzip Content-Encoding.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithCompression() func(*Client) {
+	return func(c *Client) {
+		c.compression = true
+	}
+}
+
+// Returns a pooled buffer holding the gzip-compressed data.
+func compress(data []byte) (*bytes.Buffer, error) {
+	buf := getBuffer()
+
+	w := gzipWriterPool.Get().(*gzip.Writer)
+	defer gzipWriterPool.Put(w)
+	w.Reset(buf)
+
+	if _, err := w.Write(data); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// Compresses the body of the delivery, if compression is enabled and the
+// body is large enough.
+func (c *Client) compressDelivery(d *delivery) error {
+	if !c.compression || c.dryRun || len(d.body) < minCompressionSize {
+		return nil
+	}
+
+	buf, err := compress(d.body)
+	if err != nil {
+		return err
+	}
+
+	d.release()
+	d.buf = buf
+	d.body = buf.Bytes()
+	d.compressed = true
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestClient_newDelivery_Compression(t *testing.T) {
+	c, err := NewClient("my-app-id", WithCompression())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	small := []SignalBody{c.newSignal("TestNamespace.small", nil)}
+	d, err := c.newDelivery(small, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	if d.compressed {
+		t.Errorf("small body of %d bytes was compressed", len(d.body))
+	}
+	d.release()
+
+	large := []SignalBody{c.newSignal("TestNamespace.large", map[string]interface{}{
+		"TestNamespace.text": strings.Repeat("telemetry ", 500),
+	})}
+	d, err = c.newDelivery(large, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+	if !d.compressed {
+		t.Fatalf("large body was not compressed")
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(d.body))
+	if err != nil {
+		t.Fatalf("body is not gzip-compressed: %v", err)
+	}
+	uncompressed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("error decompressing body: %v", err)
+	}
+	var signals []SignalBody
+	if err := json.Unmarshal(uncompressed, &signals); err != nil || len(signals) != 1 {
+		t.Errorf("decompressed body is not a signal array: %v\n%s", err, uncompressed)
+	}
+	if len(d.body) >= len(uncompressed)/10 {
+		t.Errorf("compressed body has %d bytes, uncompressed %d bytes", len(d.body), len(uncompressed))
+	}
+}
@@ -0,0 +1,99 @@
+package telemetrydeck
+
+import "time"
+
+// Config is a snapshot of the effective configuration of a client, after
+// defaults and the environment have been applied, see Client.Config. It
+// doesn't contain secrets like the hash salt or auth tokens, so that it
+// can be printed, e.g. in the output of a doctor command.
+type Config struct {
+	// App signals are sent to, and the active environment, if any (see
+	// WithEnvironmentAppIDs).
+	AppID       string
+	Environment string
+
+	// Ingest endpoint, including the path of the API version, and the
+	// endpoints failed over to (see WithFallbackEndpoints).
+	Endpoint          string
+	FallbackEndpoints []string
+	APIVersion        APIVersion
+
+	// Whether signals are sent in test mode, or not sent at all (see
+	// WithDryRun).
+	TestMode bool
+	DryRun   bool
+
+	// Fraction of signals sent, see WithSampleRate.
+	SampleRate float64
+
+	// Limits of the queue and of requests, and when and how queued signals
+	// are delivered.
+	QueueSize       int
+	MaxQueueBytes   int64
+	MaxBatchSize    int
+	MaxRequestBytes int
+	MaxWorkers      int
+	FlushTriggers   FlushTriggers
+	RetryPolicy     RetryPolicy
+	Compression     bool
+
+	// Whether user IDs are hashed with a salt (see WithHashSalt), the salt
+	// itself is never included.
+	HashSaltSet bool
+
+	// Whether an auth token is sent, see WithAuthToken.
+	AuthTokenSet bool
+
+	// How payload values are sanitized, see WithPayloadNormalization and
+	// WithMaxValueLength.
+	PayloadNormalization bool
+	MaxValueLength       int
+
+	// Session renewal, see WithSessionMaxDuration and
+	// WithSessionIdleTimeout.
+	SessionMaxDuration time.Duration
+	SessionIdleTimeout time.Duration
+
+	// Where data is persisted, empty if not, and whether spooled signals
+	// are encrypted (see WithSpoolEncryptionKey).
+	SpoolDir       string
+	SpoolEncrypted bool
+	StateFile      string
+}
+
+// Config returns the effective configuration of the client, including
+// changes made via Reconfigure. The returned value is a copy, modifying
+// it doesn't affect the client.
+func (c *Client) Config() Config {
+	l := c.live()
+	config := Config{
+		AppID:                c.appID,
+		Environment:          c.environment,
+		Endpoint:             l.endpoint,
+		APIVersion:           c.apiVersion,
+		TestMode:             c.testMode,
+		DryRun:               c.dryRun,
+		SampleRate:           l.sampleRate,
+		QueueSize:            c.queueSize,
+		MaxQueueBytes:        c.maxQueueBytes,
+		MaxBatchSize:         l.maxBatchSize,
+		MaxRequestBytes:      l.maxRequestBytes,
+		MaxWorkers:           c.maxWorkers,
+		FlushTriggers:        c.flushTriggers,
+		RetryPolicy:          c.retryPolicy,
+		Compression:          c.compression,
+		HashSaltSet:          l.hashSalt != "",
+		AuthTokenSet:         c.authToken != "" || c.authTokenFunc != nil,
+		PayloadNormalization: c.normalizePayload,
+		MaxValueLength:       c.maxValueLength,
+		SessionMaxDuration:   c.sessionMaxDuration,
+		SessionIdleTimeout:   c.sessionIdleTimeout,
+		SpoolDir:             c.spoolDir,
+		SpoolEncrypted:       c.spoolKey != nil,
+		StateFile:            c.stateFile,
+	}
+	if len(c.fallbackEndpoints) > 0 {
+		config.FallbackEndpoints = append([]string(nil), c.fallbackEndpoints...)
+	}
+	return config
+}
@@ -0,0 +1,51 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClient_Config(t *testing.T) {
+	c, err := NewClient("app",
+		WithEndpoint("https://example.com"),
+		WithHashSalt("secret-salt"),
+		WithTestMode(),
+		WithFallbackEndpoints("https://fallback.example.com"),
+		WithSampleRate(0.5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+
+	config := c.Config()
+	if config.AppID != "app" || !config.TestMode || !config.HashSaltSet || config.SampleRate != 0.5 {
+		t.Errorf("Config() = %+v, want app ID, test mode, salt and sample rate set", config)
+	}
+	if config.Endpoint != c.live().endpoint {
+		t.Errorf("Config().Endpoint = %s, want %s", config.Endpoint, c.live().endpoint)
+	}
+	if config.MaxBatchSize != defaultMaxBatchSize || config.RetryPolicy != DefaultRetryPolicy {
+		t.Errorf("Config() = %+v, want defaults", config)
+	}
+	if strings.Contains(fmt.Sprintf("%#v", config), "secret-salt") {
+		t.Errorf("Config() = %#v contains the salt", config)
+	}
+
+	// The snapshot is a copy
+	config.FallbackEndpoints[0] = "https://other.example.com"
+	if got := c.Config(); reflect.DeepEqual(got, config) {
+		t.Error("modifying the snapshot changed the client")
+	}
+
+	// Reconfigured settings are reflected
+	if err := c.Reconfigure(WithSampleRate(0.1), WithHashSalt("")); err != nil {
+		t.Fatal(err)
+	}
+	if config := c.Config(); config.SampleRate != 0.1 || config.HashSaltSet {
+		t.Errorf("Config() after Reconfigure = %+v, want new sample rate and no salt", config)
+	}
+}
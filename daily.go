@@ -0,0 +1,65 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+)
+
+// Layout of the dates persisted by SendDaily.
+const dailyDateLayout = "2006-01-02"
+
+// SendDaily works like SendSignal, but sends a signal of the type at most
+// once per calendar day (in the local time zone), e.g. for inventory
+// signals describing the environment, which only need daily granularity.
+// The date a signal was last sent is persisted per type in the state file,
+// so that signals are suppressed across runs of the program. Signals
+// discarded because the client is disabled or the signal is sampled out
+// don't count as sent. Returns an error wrapping ErrNoStateFile if the
+// client has no state file (see WithStateFile).
+func (c *Client) SendDaily(ctx context.Context, signalType string, payload map[string]interface{}) error {
+	if c.stateFile == "" {
+		return fmt.Errorf("%w: SendDaily requires WithStateFile", ErrNoStateFile)
+	}
+	// Checked before the state is updated, not again by SendSignal, so
+	// that the day is only recorded if the signal is queued
+	if c.skipsSignal(signalType) {
+		return nil
+	}
+	sendType, payload, err := checkSignal(c, signalType, payload)
+	if err != nil {
+		return err
+	}
+
+	today := c.clock.Now().Format(dailyDateLayout)
+	var sentToday bool
+	var previous string
+	err = c.updateState(func(s *appState) {
+		previous = s.LastSent[signalType]
+		if sentToday = previous == today; sentToday {
+			return
+		}
+		if s.LastSent == nil {
+			s.LastSent = map[string]string{}
+		}
+		s.LastSent[signalType] = today
+	})
+	if err != nil {
+		return fmt.Errorf("error updating state file: %w", err)
+	}
+	if sentToday {
+		return nil
+	}
+
+	if err := c.sendSignal(ctx, c.newSignal(sendType, payload)); err != nil {
+		// Allows sending the signal again today
+		_ = c.updateState(func(s *appState) {
+			if previous == "" {
+				delete(s.LastSent, signalType)
+			} else if s.LastSent != nil {
+				s.LastSent[signalType] = previous
+			}
+		})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClient_SendDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)}
+	newClient := func() *Client {
+		t.Helper()
+		c, err := NewClient("my-app-id", WithStateFile(path), WithClock(clock), WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}))
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		return c
+	}
+	sendDaily := func(c *Client, signalType string) {
+		t.Helper()
+		if err := c.SendDaily(context.Background(), signalType, nil); err != nil {
+			t.Fatalf("Client.SendDaily() error = %v", err)
+		}
+	}
+
+	c := newClient()
+	sendDaily(c, "TestNamespace.inventory")
+	sendDaily(c, "TestNamespace.inventory")
+	sendDaily(c, "TestNamespace.other")
+	if n := c.store.Len(); n != 2 {
+		t.Errorf("queued signals = %d, want 2", n)
+	}
+
+	// A later run on the same day
+	clock.advance(11 * time.Hour)
+	c = newClient()
+	sendDaily(c, "TestNamespace.inventory")
+	if n := c.store.Len(); n != 0 {
+		t.Errorf("queued signals = %d, want none on the same day", n)
+	}
+
+	clock.advance(time.Hour)
+	sendDaily(c, "TestNamespace.inventory")
+	if n := c.store.Len(); n != 1 {
+		t.Errorf("queued signals = %d, want 1 on the next day", n)
+	}
+}
+
+func TestClient_SendDaily_noStateFile(t *testing.T) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendDaily(context.Background(), "TestNamespace.inventory", nil); !errors.Is(err, ErrNoStateFile) {
+		t.Errorf("Client.SendDaily() error = %v, want ErrNoStateFile", err)
+	}
+}
+
+func TestClient_SendDaily_discarded(t *testing.T) {
+	c, err := NewClient("my-app-id",
+		WithStateFile(filepath.Join(t.TempDir(), "state.json")),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+		WithSampleRate(0),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	sendDaily := func() {
+		t.Helper()
+		if err := c.SendDaily(context.Background(), "TestNamespace.inventory", nil); err != nil {
+			t.Fatalf("Client.SendDaily() error = %v", err)
+		}
+	}
+
+	// Neither a sampled out nor a discarded signal counts as sent today
+	sendDaily()
+	if err := c.Reconfigure(WithSampleRate(1)); err != nil {
+		t.Fatalf("Client.Reconfigure() error = %v", err)
+	}
+	c.Disable()
+	sendDaily()
+	c.Enable()
+	sendDaily()
+	if n := c.store.Len(); n != 1 {
+		t.Errorf("queued signals = %d, want 1", n)
+	}
+}
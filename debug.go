@@ -0,0 +1,66 @@
+package telemetrydeck
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+)
+
+// Writes dumps of ingest requests and responses. Safe for concurrent use.
+type debugDumper struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WithDebugDump makes the client write full dumps of every ingest request
+// and response to the given writer, e.g. os.Stderr. This is meant for
+// investigating why signals don't arrive, and should not be enabled in
+// production, as dumps include authorization headers. Payload keys are
+// sent in sorted order (see WithSortedPayloadKeys).
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDebugDump(w io.Writer) func(*Client) {
+	return func(c *Client) {
+		c.debugDump = &debugDumper{w: w}
+		c.sortPayloadKeys = true
+	}
+}
+
+// Writes a dump of the outgoing request, including its body.
+func (d *debugDumper) dumpRequest(request *http.Request) {
+	dump, err := httputil.DumpRequestOut(request, true)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		fmt.Fprintf(d.w, "--- error dumping request: %s\n", err)
+		return
+	}
+	fmt.Fprintf(d.w, "--- request\n%s\n", dump)
+}
+
+// Writes a dump of the response, with the given body (which has already
+// been read from the response).
+func (d *debugDumper) dumpResponse(requestID string, response *http.Response, body []byte) {
+	dump, err := httputil.DumpResponse(response, false)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err != nil {
+		fmt.Fprintf(d.w, "--- error dumping response to request %s: %s\n", requestID, err)
+		return
+	}
+	fmt.Fprintf(d.w, "--- response to request %s\n%s%s\n", requestID, dump, body)
+}
+
+// Writes the error that occurred instead of a response.
+func (d *debugDumper) dumpError(requestID string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	fmt.Fprintf(d.w, "--- no response to request %s: %s\n", requestID, err)
+}
@@ -0,0 +1,38 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_DebugDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("invalid signal"))
+	}))
+	defer server.Close()
+
+	var dump bytes.Buffer
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithDebugDump(&dump))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	_, _ = c.SendSignalSync(context.Background(), "TestNamespace.dumpTest", nil)
+
+	for _, want := range []string{
+		"POST / HTTP/1.1",
+		"X-Request-Id: ",
+		`"type":"TestNamespace.dumpTest"`,
+		"HTTP/1.1 400 Bad Request",
+		"invalid signal",
+	} {
+		if !strings.Contains(dump.String(), want) {
+			t.Errorf("dump does not contain %q:\n%s", want, dump.String())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package telemetrydeck
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Environment variables configuring the client returned by Default.
+const (
+	// App ID of the default client. If not set, the default client
+	// discards all signals.
+	EnvAppID = "TELEMETRY_APP_ID"
+
+	// Salt for hashing user IDs, see WithHashSalt.
+	EnvUserHashSalt = "TELEMETRY_USER_HASH_SALT"
+
+	// Ingest endpoint, see WithEndpoint.
+	EnvEndpoint = "TELEMETRY_ENDPOINT"
+
+	// Whether signals are sent in test mode, as parsed by
+	// strconv.ParseBool, see WithTestMode.
+	EnvTestMode = "TELEMETRY_TEST_MODE"
+)
+
+var (
+	defaultOnce   sync.Once
+	defaultClient atomic.Pointer[Client]
+)
+
+// Default returns the process-wide client, so that library code can send
+// signals without being passed a client. Unless set via SetDefault, it's
+// created on first use and configured via the environment variables
+// TELEMETRY_APP_ID, TELEMETRY_USER_HASH_SALT, TELEMETRY_ENDPOINT and
+// TELEMETRY_TEST_MODE. If no app ID is set, or the configuration is
+// invalid, the client discards all signals without returning errors, so
+// calling Default().SendSignal is always safe.
+func Default() *Client {
+	defaultOnce.Do(func() {
+		defaultClient.CompareAndSwap(nil, newDefaultClient())
+	})
+	return defaultClient.Load()
+}
+
+// SetDefault makes the client the one returned by Default, e.g. so that
+// applications can configure it explicitly. The previous default client
+// is not closed. Passing nil makes Default return a client discarding all
+// signals, e.g. to turn off telemetry sent by library code.
+func SetDefault(c *Client) {
+	if c == nil {
+		c = newNoopClient()
+	}
+	defaultClient.Store(c)
+}
+
+// Returns a client configured via the environment variables described for
+// Default, or a client discarding all signals.
+func newDefaultClient() *Client {
+	appID := os.Getenv(EnvAppID)
+	if appID == "" {
+		return newNoopClient()
+	}
+
+	options := []func(*Client){WithHashSalt(os.Getenv(EnvUserHashSalt))}
+	if endpoint := os.Getenv(EnvEndpoint); endpoint != "" {
+		options = append(options, WithEndpoint(endpoint))
+	}
+	if testMode, _ := strconv.ParseBool(os.Getenv(EnvTestMode)); testMode {
+		options = append(options, WithTestMode())
+	}
+
+	client, err := NewClient(appID, options...)
+	if err != nil {
+		return newNoopClient()
+	}
+	return client
+}
+
+// Returns a client discarding all signals. It doesn't identify the
+// machine, as it never sends anything.
+func newNoopClient() *Client {
+	client, _ := NewClient("noop", WithUserID("noop"), func(c *Client) { c.noop = true })
+	return client
+}
@@ -0,0 +1,76 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	previous := Default()
+	t.Cleanup(func() { SetDefault(previous) })
+
+	if Default() != previous {
+		t.Error("Default() returned different clients")
+	}
+
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	SetDefault(c)
+	if Default() != c {
+		t.Error("Default() didn't return the client passed to SetDefault()")
+	}
+
+	SetDefault(nil)
+	if c := Default(); c == nil || !c.noop {
+		t.Error("Default() after SetDefault(nil) didn't return a no-op client")
+	}
+}
+
+func Test_newDefaultClient(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvAppID, "")
+	c := newDefaultClient()
+	if !c.noop {
+		t.Error("newDefaultClient() without app ID returned a client sending signals")
+	}
+	if err := c.SendSignal(context.Background(), "", nil); err != nil {
+		t.Errorf("no-op Client.SendSignal() error = %v", err)
+	}
+	if err := c.SendStringSignal(context.Background(), "TestNamespace.defaultTest", nil); err != nil {
+		t.Errorf("no-op Client.SendStringSignal() error = %v", err)
+	}
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.defaultTest", nil); err != nil {
+		t.Errorf("no-op Client.SendSignalSync() error = %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Errorf("no-op Client.Close() error = %v", err)
+	}
+
+	t.Setenv(EnvAppID, "my-app-id")
+	t.Setenv(EnvUserHashSalt, "salt")
+	t.Setenv(EnvEndpoint, server.URL)
+	t.Setenv(EnvTestMode, "true")
+	c = newDefaultClient()
+	if c.noop || c.appID != "my-app-id" || c.hashSalt != "salt" || c.endpoint != server.URL || !c.testMode {
+		t.Errorf("newDefaultClient() didn't apply the environment variables")
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.defaultTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
@@ -0,0 +1,84 @@
+package telemetrydeck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Prefix of payload keys reserved for the standard fields of TelemetryDeck
+// dashboards.
+const reservedKeyPrefix = "TelemetryDeck."
+
+// WithDefaultKeyOverrides makes payload fields take precedence over the
+// standard fields the client adds to every signal, like
+// TelemetryDeck.Device.operatingSystem. By default, the standard fields
+// win. Either way, collisions are logged and reported to the
+// OnDefaultKeyCollision hook.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDefaultKeyOverrides() func(*Client) {
+	return func(c *Client) {
+		c.overrideDefaultKeys = true
+	}
+}
+
+// WithStrictPayloadKeys makes SendSignal and SendStringSignal reject
+// signals with payload keys starting with "TelemetryDeck.", which are
+// reserved for the standard dimensions of TelemetryDeck dashboards, by
+// returning an error wrapping ErrReservedKey.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithStrictPayloadKeys() func(*Client) {
+	return func(c *Client) {
+		c.strictPayloadKeys = true
+	}
+}
+
+// Returns an error wrapping ErrReservedKey if the payload contains a
+// reserved key.
+func checkReservedKeys[V any](payload map[string]V) error {
+	for key := range payload {
+		if strings.HasPrefix(key, reservedKeyPrefix) {
+			return fmt.Errorf("%w: %s", ErrReservedKey, key)
+		}
+	}
+	return nil
+}
+
+// Reports payload fields of the signal colliding with standard fields.
+func (c *Client) checkDefaultKeys(signal *SignalBody) {
+	if !c.logEnabled(LogSubsystemQueue, LogLevelWarn) && c.hooks.OnDefaultKeyCollision == nil {
+		return
+	}
+
+	for _, key := range defaultPayloadKeys {
+		var collides bool
+		if signal.stringPayload != nil {
+			_, collides = signal.stringPayload[key]
+		} else {
+			_, collides = signal.Payload[key]
+		}
+		if !collides {
+			continue
+		}
+
+		if c.overrideDefaultKeys {
+			c.log(LogSubsystemQueue, LogLevelWarn, "payload key overrides the standard field", "key", key, "type", signal.Type)
+		} else {
+			c.log(LogSubsystemQueue, LogLevelWarn, "payload key is overwritten by the standard field", "key", key, "type", signal.Type)
+		}
+		if c.hooks.OnDefaultKeyCollision != nil {
+			c.hooks.OnDefaultKeyCollision(signal.Type, key)
+		}
+	}
+}
+
+// Reports whether the payload contains any of the standard fields.
+func hasDefaultKey[V any](payload map[string]V) bool {
+	for _, key := range defaultPayloadKeys {
+		if _, ok := payload[key]; ok {
+			return true
+		}
+	}
+	return false
+}
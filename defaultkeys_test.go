@@ -0,0 +1,110 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestClient_DefaultKeyCollision(t *testing.T) {
+	const key = "TelemetryDeck.Device.operatingSystem"
+
+	tests := []struct {
+		name    string
+		options []func(*Client)
+		want    string
+	}{
+		{name: "standard field wins", want: runtime.GOOS},
+		{name: "standard field wins, sorted", options: []func(*Client){WithSortedPayloadKeys()}, want: runtime.GOOS},
+		{name: "payload wins", options: []func(*Client){WithDefaultKeyOverrides()}, want: "custom"},
+		{name: "payload wins, sorted", options: []func(*Client){WithDefaultKeyOverrides(), WithSortedPayloadKeys()}, want: "custom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var collisions []string
+			hooks := Hooks{OnDefaultKeyCollision: func(signalType, key string) {
+				collisions = append(collisions, signalType+" "+key)
+			}}
+			c, err := NewClient("my-app-id", append(tt.options, WithHooks(hooks))...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			signal := c.newSignal("TestNamespace.collisionTest", map[string]interface{}{key: "custom", "other": 1})
+			c.checkDefaultKeys(&signal)
+			if len(collisions) != 1 || collisions[0] != "TestNamespace.collisionTest "+key {
+				t.Errorf("OnDefaultKeyCollision calls = %q, want one for %s", collisions, key)
+			}
+
+			var buf bytes.Buffer
+			if err := c.appendSignal(&buf, &signal); err != nil {
+				t.Fatalf("Client.appendSignal() error = %v", err)
+			}
+			var decoded SignalBody
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+				t.Fatalf("invalid JSON %s: %v", buf.Bytes(), err)
+			}
+			if got := decoded.Payload[key]; got != tt.want {
+				t.Errorf("payload %s = %v, want %v", key, got, tt.want)
+			}
+			if len(decoded.Payload) != len(defaultPayload)+1 {
+				t.Errorf("payload = %v, want standard fields and other", decoded.Payload)
+			}
+		})
+	}
+}
+
+func Test_hasDefaultKey(t *testing.T) {
+	if hasDefaultKey(map[string]string{"key": "value"}) {
+		t.Error("hasDefaultKey() = true for payload without standard fields")
+	}
+	if !hasDefaultKey(map[string]string{"TelemetryDeck.SDK.nameAndVersion": "value"}) {
+		t.Error("hasDefaultKey() = false for payload with standard field")
+	}
+}
+
+func TestClient_StrictPayloadKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		strict  bool
+		payload map[string]string
+		wantErr error
+	}{
+		{name: "reserved key", strict: true, payload: map[string]string{"TelemetryDeck.Device.modelName": "custom"}, wantErr: ErrReservedKey},
+		{name: "other key", strict: true, payload: map[string]string{"TestNamespace.key": "value"}},
+		{name: "not strict", payload: map[string]string{"TelemetryDeck.Device.modelName": "custom"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []func(*Client){WithEndpoint(server.URL)}
+			if tt.strict {
+				options = append(options, WithStrictPayloadKeys())
+			}
+			c, err := NewClient("my-app-id", options...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			if err := c.SendStringSignal(context.Background(), "TestNamespace.strictTest", tt.payload); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Client.SendStringSignal() error = %v, want %v", err, tt.wantErr)
+			}
+			payload := map[string]interface{}{}
+			for key, value := range tt.payload {
+				payload[key] = value
+			}
+			if err := c.SendSignal(context.Background(), "TestNamespace.strictTest", payload); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Client.SendSignal() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package telemetrydeck
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Size of an entry in the acknowledgement log of a disk store segment: the
+// ID of the acknowledged signal.
+const diskStoreAckSize = 8
+
+// DiskQueueStore is a QueueStore keeping signals in append-only segment
+// files in a directory, so that signals that were not delivered survive
+// restarts. It uses the same checksummed record format as the spool.
+//
+// Bearer tokens are not persisted; signals are delivered with a token
+// requested from the client's token source instead.
+type DiskQueueStore struct {
+	dir          string
+	capacity     int
+	segmentBytes int64
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending []diskStoreEntry
+
+	// Errors of signals skipped since the last call of takeSkipped,
+	// because they could not be read or decoded.
+	skipped []error
+
+	// Number of signals per segment that have not been acknowledged yet,
+	// dequeued or not, and the segments of dequeued signals by ID.
+	unacked  map[string]int
+	inflight map[uint64]string
+
+	// Segment signals are appended to
+	active     *os.File
+	activeName string
+	activeSize int64
+}
+
+// Location of a signal that has not been dequeued yet.
+type diskStoreEntry struct {
+	id      uint64
+	segment string
+	offset  int64
+	end     int64
+
+	// Set if the signal could not be decoded when loading the segment
+	err error
+}
+
+// NewDiskQueueStore opens the disk store in the given directory, creating
+// the directory if necessary. Signals left by a previous store that were
+// not acknowledged are dequeued again, oldest first. Signals that can't be
+// read or decoded are skipped by DequeueBatch, and reported as dropped by
+// the client; they are removed along with their segment. The store rejects
+// signals with ErrQueueFull once it holds capacity signals that have not
+// been acknowledged; a capacity of zero or less means no limit.
+func NewDiskQueueStore(dir string, capacity int) (*DiskQueueStore, error) {
+	s := &DiskQueueStore{
+		dir:          dir,
+		capacity:     capacity,
+		segmentBytes: defaultSpoolSegmentBytes,
+		unacked:      make(map[string]int),
+		inflight:     make(map[uint64]string),
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating queue store directory: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("loading queue store: %w", err)
+	}
+	return s, nil
+}
+
+// Indexes the signals of the existing segments that have not been
+// acknowledged, truncating segments torn by a crash and removing segments
+// without such signals.
+func (s *DiskQueueStore) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		if isSpoolSegmentName(entry.Name()) && entry.Type().IsRegular() {
+			segments = append(segments, entry.Name())
+		}
+	}
+	sort.Strings(segments)
+
+	exists := make(map[string]bool, len(segments))
+	for _, name := range segments {
+		exists[name] = true
+
+		path := filepath.Join(s.dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		records, valid := decodeSpoolRecords(data)
+		if valid < int64(len(data)) {
+			if err := os.Truncate(path, valid); err != nil {
+				return err
+			}
+		}
+
+		acked := s.acked(name)
+		var offset int64
+		for _, record := range records {
+			entry := diskStoreEntry{segment: name, offset: offset, end: record.end}
+			offset = record.end
+
+			var stored QueuedSignal
+			if err := json.Unmarshal(record.body, &stored); err != nil {
+				entry.err = fmt.Errorf("decoding signal in %s: %w", name, err)
+			} else if acked[stored.ID] {
+				continue
+			}
+			entry.id = stored.ID
+			s.nextID = max(s.nextID, stored.ID+1)
+			s.pending = append(s.pending, entry)
+			s.unacked[name]++
+		}
+
+		if s.unacked[name] == 0 {
+			s.removeSegment(name)
+		}
+	}
+
+	// Acknowledgement logs of removed segments
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), spoolAckExt)
+		if ok && !exists[name] {
+			os.Remove(filepath.Join(s.dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// Returns the IDs in the acknowledgement log of the segment. A torn entry
+// at the end of the log is ignored.
+func (s *DiskQueueStore) acked(name string) map[uint64]bool {
+	data, _ := os.ReadFile(filepath.Join(s.dir, name+spoolAckExt))
+	acked := make(map[uint64]bool, len(data)/diskStoreAckSize)
+	for len(data) >= diskStoreAckSize {
+		acked[binary.LittleEndian.Uint64(data)] = true
+		data = data[diskStoreAckSize:]
+	}
+	return acked
+}
+
+// Enqueue implements QueueStore. The signal is synced to disk before
+// returning.
+func (s *DiskQueueStore) Enqueue(signal QueuedSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.capacity > 0 && s.unackedLocked() >= s.capacity {
+		return ErrQueueFull
+	}
+
+	signal.ID = s.nextID
+	body, err := json.Marshal(signal)
+	if err != nil {
+		return err
+	}
+	record := encodeSpoolRecord(time.Now(), 1, false, nil, body)
+
+	if s.active == nil || s.activeSize+int64(len(record)) > s.segmentBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.active.Write(record); err != nil {
+		// Don't append to a segment that may end with a partial record
+		s.closeActive()
+		return err
+	}
+	if err := s.active.Sync(); err != nil {
+		s.closeActive()
+		return err
+	}
+
+	s.pending = append(s.pending, diskStoreEntry{
+		id:      s.nextID,
+		segment: s.activeName,
+		offset:  s.activeSize,
+		end:     s.activeSize + int64(len(record)),
+	})
+	s.unacked[s.activeName]++
+	s.activeSize += int64(len(record))
+	s.nextID++
+	return nil
+}
+
+// Starts a new active segment, named after the ID of its first signal.
+// Must be called with s.mu held.
+func (s *DiskQueueStore) rotate() error {
+	s.closeActive()
+
+	name := fmt.Sprintf("%020d%s", s.nextID, spoolSegmentExt)
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	s.active = f
+	s.activeName = name
+	s.activeSize = 0
+	return nil
+}
+
+// Closes the active segment, removing it if all its signals have been
+// acknowledged. Must be called with s.mu held.
+func (s *DiskQueueStore) closeActive() {
+	if s.active == nil {
+		return
+	}
+	s.active.Close()
+	name := s.activeName
+	s.active = nil
+	s.activeName = ""
+	if s.unacked[name] == 0 {
+		s.removeSegment(name)
+	}
+}
+
+// DequeueBatch implements QueueStore. The signals are read from disk.
+// Signals that can't be read or decoded are skipped, so that they don't
+// block the signals after them.
+func (s *DiskQueueStore) DequeueBatch(max int) ([]QueuedSignal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(max, len(s.pending))
+	if n == 0 {
+		return nil, nil
+	}
+
+	batch := make([]QueuedSignal, 0, n)
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	var dequeued []diskStoreEntry
+	for len(batch) < max && len(s.pending) > 0 {
+		entry := s.pending[0]
+		f, ok := files[entry.segment]
+		if !ok {
+			var err error
+			if f, err = os.Open(filepath.Join(s.dir, entry.segment)); err != nil {
+				// Signals read so far are dequeued with the next batch
+				for _, entry := range dequeued {
+					delete(s.inflight, entry.id)
+				}
+				s.pending = append(dequeued, s.pending...)
+				return nil, err
+			}
+			files[entry.segment] = f
+		}
+		s.pending = s.pending[1:]
+
+		stored, err := s.read(f, entry)
+		if err != nil {
+			s.skip(entry, err)
+			continue
+		}
+		s.inflight[entry.id] = entry.segment
+		dequeued = append(dequeued, entry)
+		batch = append(batch, stored)
+	}
+	return batch, nil
+}
+
+// Reads the signal of the entry from its segment.
+func (s *DiskQueueStore) read(f *os.File, entry diskStoreEntry) (QueuedSignal, error) {
+	var stored QueuedSignal
+	if entry.err != nil {
+		return stored, entry.err
+	}
+
+	data := make([]byte, entry.end-entry.offset)
+	if _, err := f.ReadAt(data, entry.offset); err != nil {
+		return stored, fmt.Errorf("reading signal %d in %s: %w", entry.id, entry.segment, err)
+	}
+	records, _ := decodeSpoolRecords(data)
+	if len(records) != 1 {
+		return stored, fmt.Errorf("signal %d in %s: %w", entry.id, entry.segment, errSpoolSegmentCorrupt)
+	}
+	if err := json.Unmarshal(records[0].body, &stored); err != nil {
+		return stored, fmt.Errorf("decoding signal %d in %s: %w", entry.id, entry.segment, err)
+	}
+	return stored, nil
+}
+
+// Records the entry as skipped, removing its segment once all other
+// signals in it have been acknowledged. Must be called with s.mu held.
+func (s *DiskQueueStore) skip(entry diskStoreEntry, err error) {
+	s.skipped = append(s.skipped, err)
+	s.unacked[entry.segment]--
+	if s.unacked[entry.segment] <= 0 && entry.segment != s.activeName {
+		s.removeSegment(entry.segment)
+	}
+}
+
+// Returns the errors of the signals skipped by DequeueBatch since the
+// last call.
+func (s *DiskQueueStore) takeSkipped() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	skipped := s.skipped
+	s.skipped = nil
+	return skipped
+}
+
+// Ack implements QueueStore. Segments are removed once all their signals
+// have been acknowledged.
+func (s *DiskQueueStore) Ack(signals []QueuedSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bySegment := make(map[string][]byte)
+	for _, signal := range signals {
+		name, ok := s.inflight[signal.ID]
+		if !ok {
+			continue
+		}
+		delete(s.inflight, signal.ID)
+		bySegment[name] = binary.LittleEndian.AppendUint64(bySegment[name], signal.ID)
+	}
+
+	for name, ids := range bySegment {
+		s.unacked[name] -= len(ids) / diskStoreAckSize
+		if s.unacked[name] <= 0 && name != s.activeName {
+			s.removeSegment(name)
+			continue
+		}
+		if err := appendFile(filepath.Join(s.dir, name+spoolAckExt), ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Len implements QueueStore.
+func (s *DiskQueueStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Returns the number of signals that have not been acknowledged yet.
+// Must be called with s.mu held.
+func (s *DiskQueueStore) unackedLocked() int {
+	var n int
+	for _, count := range s.unacked {
+		n += count
+	}
+	return n
+}
+
+// Removes the segment and its acknowledgement log. Must be called with
+// s.mu held.
+func (s *DiskQueueStore) removeSegment(name string) {
+	os.Remove(filepath.Join(s.dir, name))
+	os.Remove(filepath.Join(s.dir, name+spoolAckExt))
+	delete(s.unacked, name)
+}
+
+// Close closes the active segment. Signals that have not been acknowledged
+// are dequeued again by the next store opened in the same directory.
+func (s *DiskQueueStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeActive()
+	return nil
+}
+
+// Appends the data to the file, creating it if necessary, and syncs it.
+func appendFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,212 @@
+package telemetrydeck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskQueueStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskQueueStore(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, typ := range []string{"first", "second", "third"} {
+		signal := SignalBody{Type: typ}
+		if i == 0 {
+			signal.stringPayload = map[string]string{"key": "value"}
+		}
+		if err := s.Enqueue(QueuedSignal{Signal: signal, Token: "token"}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: "fourth"}}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Enqueue() to full store error = %v, want %v", err, ErrQueueFull)
+	}
+	if got := s.Len(); got != 3 {
+		t.Errorf("Len() = %d, want 3", got)
+	}
+
+	batch, err := s.DequeueBatch(2)
+	if err != nil || len(batch) != 2 {
+		t.Fatalf("DequeueBatch() = %v, %v, want 2 signals", batch, err)
+	}
+	if batch[0].Signal.Type != "first" || batch[0].Signal.stringPayload["key"] != "value" || batch[1].Signal.Type != "second" {
+		t.Errorf("DequeueBatch() = %+v, want first and second signal", batch)
+	}
+	if batch[0].Token != "" {
+		t.Errorf("DequeueBatch()[0].Token = %q, want tokens not to be persisted", batch[0].Token)
+	}
+	if got := s.Len(); got != 1 {
+		t.Errorf("Len() after DequeueBatch() = %d, want 1", got)
+	}
+
+	// Only acknowledged signals are gone after a restart
+	if err := s.Ack(batch[:1]); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	s.Close()
+
+	s, err = NewDiskQueueStore(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err = s.DequeueBatch(10)
+	if err != nil || len(batch) != 2 || batch[0].Signal.Type != "second" || batch[1].Signal.Type != "third" {
+		t.Fatalf("DequeueBatch() after restart = %+v, %v, want second and third signal", batch, err)
+	}
+	if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: "fourth"}}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if next, _ := s.DequeueBatch(10); len(next) != 1 || next[0].ID <= batch[1].ID {
+		t.Errorf("DequeueBatch() = %+v, want new signal with a new ID", next)
+	}
+
+	// Fully acknowledged segments are removed
+	if err := s.Ack(batch); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != s.activeName && entry.Name() != s.activeName+spoolAckExt {
+			t.Errorf("file %s left after acknowledging all its signals", entry.Name())
+		}
+	}
+}
+
+func TestDiskQueueStore_TornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: "signal"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, s.activeName)
+	s.Close()
+
+	// Simulate a crash in the middle of writing the second signal
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Len(); got != 1 {
+		t.Errorf("Len() after recovery = %d, want 1", got)
+	}
+}
+
+func TestDiskQueueStore_CorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range []string{"first", "second"} {
+		if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: typ}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, s.activeName)
+	s.Close()
+
+	// A record with a valid checksum that can't be decoded
+	if err := appendFile(path, encodeSpoolRecord(time.Now(), 1, false, nil, []byte("{"))); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatalf("NewDiskQueueStore() with undecodable record error = %v", err)
+	}
+	if got := s.Len(); got != 3 {
+		t.Errorf("Len() = %d, want 3", got)
+	}
+
+	// Damage the first record after it has been loaded
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[s.pending[0].end-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	batch, err := s.DequeueBatch(10)
+	if err != nil || len(batch) != 1 || batch[0].Signal.Type != "second" {
+		t.Fatalf("DequeueBatch() = %+v, %v, want second signal only", batch, err)
+	}
+	if skipped := s.takeSkipped(); len(skipped) != 2 || !errors.Is(skipped[0], errSpoolSegmentCorrupt) {
+		t.Errorf("takeSkipped() = %v, want corrupt and undecodable record", skipped)
+	}
+	if got := s.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+
+	// Skipped records are removed along with the segment
+	if err := s.Ack(batch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("segment with skipped records not removed, error = %v", err)
+	}
+}
+
+func TestDiskQueueStore_WriteError(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: "first"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writing the second signal fails, leaving a partial record
+	path := filepath.Join(dir, s.activeName)
+	readOnly, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.active.Close()
+	s.active = readOnly
+	if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: "second"}}); err == nil {
+		t.Fatal("Enqueue() error = nil, want write error")
+	}
+	record := encodeSpoolRecord(time.Now(), 1, false, nil, []byte("{}"))
+	if err := appendFile(path, record[:len(record)/2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Enqueue(QueuedSignal{Signal: SignalBody{Type: "third"}}); err != nil {
+		t.Fatalf("Enqueue() after write error = %v", err)
+	}
+	s.Close()
+
+	s, err = NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := s.DequeueBatch(10)
+	if err != nil || len(batch) != 2 || batch[0].Signal.Type != "first" || batch[1].Signal.Type != "third" {
+		t.Errorf("DequeueBatch() after restart = %+v, %v, want first and third signal", batch, err)
+	}
+}
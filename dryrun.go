@@ -0,0 +1,43 @@
+package telemetrydeck
+
+import (
+	"log"
+	"net/http"
+)
+
+// WithDryRun makes the client log the JSON request bodies it would send,
+// instead of sending them. Signals are enriched, hashed and encoded as
+// usual, so this can be used to verify an integration before enabling
+// telemetry. Unlike test mode, which marks signals as test signals but
+// still sends them, nothing leaves the machine. Bodies are logged
+// uncompressed.
+//
+// Bodies are logged regardless of the log level, to the logger given via
+// WithSlogLogger or WithLogger, or the standard logger if none is given.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDryRun() func(*Client) {
+	return func(c *Client) {
+		c.dryRun = true
+	}
+}
+
+// Logs the delivery instead of submitting it, and returns the result of
+// a successful submission.
+func (c *Client) dryRunSubmit(d delivery) IngestResult {
+	switch {
+	case c.slogLogger != nil:
+		c.slogLogger.Info("dry run, not sending signals", "count", d.count, "endpoint", c.activeEndpoint(), "body", string(d.body))
+	case c.logger != nil:
+		c.logger.Printf("dry run, not sending %d signals to %s: %s", d.count, c.activeEndpoint(), d.body)
+	default:
+		log.Printf("dry run, not sending %d signals to %s: %s", d.count, c.activeEndpoint(), d.body)
+	}
+
+	return IngestResult{
+		RequestID:  c.newID(),
+		StatusCode: http.StatusOK,
+		Sent:       d.count,
+		Accepted:   d.count,
+	}
+}
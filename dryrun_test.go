@@ -0,0 +1,62 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestClient_DryRun(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithDryRun(),
+		WithCompression(),
+		WithUserID("user"),
+		WithLogger(log.New(&logs, "", 0)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	payload := map[string]interface{}{"TestNamespace.padding": strings.Repeat("x", minCompressionSize)}
+	if err := c.SendSignal(context.Background(), "TestNamespace.dryRunTest", payload); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	result, err := c.SendSignalSync(context.Background(), "TestNamespace.dryRunSyncTest", nil)
+	if err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+	if result.StatusCode != http.StatusOK || result.Accepted != 1 {
+		t.Errorf("Client.SendSignalSync() result = %+v, want 1 accepted", result)
+	}
+
+	if got := requests.Load(); got != 0 {
+		t.Errorf("server received %d requests, want 0", got)
+	}
+	output := logs.String()
+	for _, want := range []string{
+		`"type":"TestNamespace.dryRunTest"`,
+		`"type":"TestNamespace.dryRunSyncTest"`,
+		`"clientUser":"` + c.userIDHash + `"`,
+		server.URL,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log output doesn't contain %s:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,534 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// Standard fields added to the payload of every signal. They never change
+// during the lifetime of a process, so they are encoded only once. They take
+// precedence over payload fields of the same name, unless configured
+// otherwise via WithDefaultKeyOverrides.
+var defaultPayload = map[string]interface{}{
+	"TelemetryDeck.Device.operatingSystem": runtime.GOOS,
+	"TelemetryDeck.Device.architecture":    runtime.GOARCH,
+	"TelemetryDeck.SDK.nameAndVersion":     version,
+}
+
+// Sorted keys of defaultPayload, and its pre-encoded key-value pairs
+// (without the surrounding braces).
+var defaultPayloadKeys, defaultPayloadFragment = encodeDefaultPayload()
+
+func encodeDefaultPayload() ([]string, []byte) {
+	keys := make([]string, 0, len(defaultPayload))
+	for key := range defaultPayload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(&buf, key)
+		buf.WriteByte(':')
+		_ = writeJSONValue(&buf, defaultPayload[key])
+	}
+
+	return keys, buf.Bytes()
+}
+
+// Buffers larger than this are not returned to the pool, so that a single
+// huge signal doesn't keep its memory allocated forever.
+const maxPooledBufferSize = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buf)
+	}
+}
+
+// The pre-encoded beginning of a signal, containing the fields which are
+// the same for all signals sent by a client, up to the "type" key.
+type signalPrefix struct {
+	appID      string
+	clientUser string
+	sessionID  string
+	testMode   bool
+
+	encoded []byte
+}
+
+// Returns the prefix for signals with the given static fields.
+func newSignalPrefix(appID, clientUser, sessionID string, testMode bool) *signalPrefix {
+	var buf bytes.Buffer
+	writeSignalPrefix(&buf, appID, clientUser, sessionID, testMode)
+	return &signalPrefix{
+		appID:      appID,
+		clientUser: clientUser,
+		sessionID:  sessionID,
+		testMode:   testMode,
+		encoded:    buf.Bytes(),
+	}
+}
+
+// Returns whether the prefix can be used to encode the signal.
+func (p *signalPrefix) matches(s *SignalBody) bool {
+	return p != nil &&
+		s.AppID == p.appID &&
+		s.ClientUser == p.clientUser &&
+		s.SessionID == p.sessionID &&
+		s.IsTestMode == p.testMode
+}
+
+func writeSignalPrefix(buf *bytes.Buffer, appID, clientUser, sessionID string, testMode bool) {
+	buf.WriteString(`{"appID":`)
+	writeJSONString(buf, appID)
+	buf.WriteString(`,"clientUser":`)
+	writeJSONString(buf, clientUser)
+	buf.WriteString(`,"sessionID":`)
+	writeJSONString(buf, sessionID)
+	buf.WriteString(`,"isTestMode":`)
+	buf.WriteString(strconv.FormatBool(testMode))
+	buf.WriteString(`,"type":`)
+}
+
+// Encodes the signals as a JSON array in the client's ingest format into
+// a pooled buffer, and returns a delivery for it, compressed if enabled.
+// The delivery must be released once it is no longer used.
+//
+// The signals are encoded directly into the buffer the request body is read
+// from. For multiple signals, the buffer is pre-sized from an estimate of
+// the encoded size, so that large batches aren't copied repeatedly while
+// the buffer grows.
+func (c *Client) newDelivery(signals []SignalBody, token string) (delivery, error) {
+	buf := getBuffer()
+	if len(signals) > 1 {
+		size := 2
+		for i := range signals {
+			size += c.estimateSignalSize(&signals[i]) + 1
+		}
+		buf.Grow(size)
+	}
+
+	buf.WriteByte('[')
+	for i := range signals {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := c.appendSignal(buf, &signals[i]); err != nil {
+			putBuffer(buf)
+			return delivery{}, err
+		}
+	}
+	buf.WriteByte(']')
+	if c.testMode && c.logEnabled(LogSubsystemTransport, LogLevelInfo) {
+		c.log(LogSubsystemTransport, LogLevelInfo, "delivering signals in test mode", "count", len(signals), "body", buf.String())
+	}
+
+	d := delivery{body: buf.Bytes(), count: len(signals), token: token, buf: buf}
+	if err := c.compressDelivery(&d); err != nil {
+		d.release()
+		return delivery{}, err
+	}
+
+	return d, nil
+}
+
+// Size assumed for encoded payload values of types other than string.
+const estimatedValueSize = 16
+
+// Returns the approximate size of the encoding of the signal in the
+// client's ingest format.
+func (c *Client) estimateSignalSize(s *SignalBody) int {
+	return c.live().format.estimateSignalSize(*s)
+}
+
+// Appends the encoding of the signal in the client's ingest format to the
+// buffer.
+func (c *Client) appendSignal(buf *bytes.Buffer, s *SignalBody) error {
+	return c.live().format.appendSignal(buf, s)
+}
+
+// The format of Ingest API v2, encoding signals as JSON objects with the
+// payload as an object.
+type v2Format struct {
+	// Pre-encoded static fields of the signals sent by the client.
+	prefix *signalPrefix
+
+	// Whether payload keys are encoded in sorted order, and whether
+	// payload fields take precedence over standard fields.
+	sortPayloadKeys     bool
+	overrideDefaultKeys bool
+
+	// Writes values of Payload, see WithPayloadTypePolicy
+	writeValue func(*bytes.Buffer, interface{}) error
+}
+
+func newV2Format(c *Client, l *liveConfig) ingestFormat {
+	f := &v2Format{
+		prefix:              newSignalPrefix(c.appID, l.userIDHash, l.sessionID, c.testMode),
+		sortPayloadKeys:     c.sortPayloadKeys,
+		overrideDefaultKeys: c.overrideDefaultKeys,
+		writeValue:          writeJSONValue,
+	}
+	if c.payloadTypePolicy == PayloadTypesStringify {
+		f.writeValue = writeJSONStringifiedValue
+	}
+	return f
+}
+
+func (f *v2Format) endpoint(configured string) string {
+	return configured
+}
+
+// Returns the approximate size of the JSON encoding of the signal, assuming
+// that no escaping is needed.
+func (f *v2Format) estimateSignalSize(s SignalBody) int {
+	var size int
+	if f.prefix.matches(&s) {
+		size = len(f.prefix.encoded)
+	} else {
+		size = len(`{"appID":"","clientUser":"","sessionID":"","isTestMode":false,"type":`) +
+			len(s.AppID) + len(s.ClientUser) + len(s.SessionID)
+	}
+	size += len(s.Type) + len(`"","payload":{}}`) + len(defaultPayloadFragment)
+
+	for key, value := range s.Payload {
+		size += len(key) + len(`"":,`)
+		if v, ok := value.(string); ok {
+			size += len(v) + 2
+		} else {
+			size += estimatedValueSize
+		}
+	}
+	for key, value := range s.stringPayload {
+		size += len(key) + len(value) + len(`"":"",`)
+	}
+	if s.FloatValue != nil {
+		size += len(`,"floatValue":`) + estimatedValueSize
+	}
+
+	return size
+}
+
+// Appends the JSON encoding of the signal to the buffer. The result is
+// equivalent to json.Marshal, except for the order of payload keys unless
+// sorted payload keys have been enabled.
+func (f *v2Format) appendSignal(buf *bytes.Buffer, s *SignalBody) error {
+	if f.prefix.matches(s) {
+		buf.Write(f.prefix.encoded)
+	} else {
+		writeSignalPrefix(buf, s.AppID, s.ClientUser, s.SessionID, s.IsTestMode)
+	}
+	internedStrings.writeJSONString(buf, s.Type)
+
+	buf.WriteString(`,"payload":`)
+	var err error
+	if s.stringPayload != nil {
+		err = appendPayload(buf, s.stringPayload, f.sortPayloadKeys, f.overrideDefaultKeys, writeJSONStringValue)
+	} else {
+		err = appendPayload(buf, s.Payload, f.sortPayloadKeys, f.overrideDefaultKeys, f.writeValue)
+	}
+	if err != nil {
+		return err
+	}
+	if s.FloatValue != nil {
+		buf.WriteString(`,"floatValue":`)
+		if err := writeJSONFloat(buf, *s.FloatValue, 64); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// Appends the JSON encoding of the payload object, including the standard
+// fields, to the buffer. Payload values are written using writeValue. If
+// sorted is true, keys are written in sorted order, like json.Marshal does.
+// If overrideDefaults is true, payload fields take precedence over standard
+// fields of the same name.
+func appendPayload[V any](buf *bytes.Buffer, payload map[string]V, sorted, overrideDefaults bool, writeValue func(*bytes.Buffer, V) error) error {
+	buf.WriteByte('{')
+
+	writeEntry := func(i int, key string, write func() error) error {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		internedStrings.writeJSONString(buf, key)
+		buf.WriteByte(':')
+		if err := write(); err != nil {
+			return fmt.Errorf("error encoding payload key %q: %w", key, err)
+		}
+		return nil
+	}
+
+	// Whether a payload field replaces a standard field
+	overridden := overrideDefaults && hasDefaultKey(payload)
+
+	if sorted {
+		keys := make([]string, 0, len(payload)+len(defaultPayloadKeys))
+		keys = append(keys, defaultPayloadKeys...)
+		for key := range payload {
+			if _, isDefault := defaultPayload[key]; !isDefault {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for i, key := range keys {
+			write := func() error {
+				value, inPayload := payload[key]
+				if defaultValue, isDefault := defaultPayload[key]; isDefault && !(overridden && inPayload) {
+					return writeJSONValue(buf, defaultValue)
+				}
+				return writeValue(buf, value)
+			}
+			if err := writeEntry(i, key, write); err != nil {
+				return err
+			}
+		}
+	} else if overridden {
+		var i int
+		for _, key := range defaultPayloadKeys {
+			if _, inPayload := payload[key]; inPayload {
+				continue
+			}
+			if err := writeEntry(i, key, func() error { return writeJSONValue(buf, defaultPayload[key]) }); err != nil {
+				return err
+			}
+			i++
+		}
+		for key, value := range payload {
+			if err := writeEntry(i, key, func() error { return writeValue(buf, value) }); err != nil {
+				return err
+			}
+			i++
+		}
+	} else {
+		buf.Write(defaultPayloadFragment)
+		i := len(defaultPayloadKeys)
+		for key, value := range payload {
+			if _, isDefault := defaultPayload[key]; isDefault {
+				continue
+			}
+			if err := writeEntry(i, key, func() error { return writeValue(buf, value) }); err != nil {
+				return err
+			}
+			i++
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// Writes the JSON encoding of a payload value. Common types are encoded
+// directly, errors as described for encodeErrorValue, everything else via
+// json.Marshal.
+func writeJSONValue(buf *bytes.Buffer, value interface{}) error {
+	var scratch [64]byte
+
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJSONString(buf, v)
+	case bool:
+		buf.Write(strconv.AppendBool(scratch[:0], v))
+	case int:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int32:
+		buf.Write(strconv.AppendInt(scratch[:0], int64(v), 10))
+	case int64:
+		buf.Write(strconv.AppendInt(scratch[:0], v, 10))
+	case uint:
+		buf.Write(strconv.AppendUint(scratch[:0], uint64(v), 10))
+	case uint64:
+		buf.Write(strconv.AppendUint(scratch[:0], v, 10))
+	case float64:
+		return writeJSONFloat(buf, v, 64)
+	case float32:
+		return writeJSONFloat(buf, float64(v), 32)
+	case error:
+		encoded, err := safeEncodeErrorValue(v)
+		if err != nil {
+			return err
+		}
+		writeJSONString(buf, encoded)
+	default:
+		encoded, err := safeMarshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+
+	return nil
+}
+
+// Maximum nesting depth of maps and slices in payload values. Deeper
+// values could exhaust the stack while being encoded.
+const maxPayloadNesting = 64
+
+// Works like json.Marshal, but returns an error instead of panicking if a
+// MarshalJSON or String method panics, and for values nested too deeply.
+func safeMarshal(v interface{}) (encoded []byte, err error) {
+	if err := checkNesting(v, 0); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic encoding value of type %T: %v", v, r)
+		}
+	}()
+	return json.Marshal(v)
+}
+
+// Returns an error if maps or slices in the value are nested deeper than
+// maxPayloadNesting. Only the generic types produced by decoding JSON are
+// checked.
+func checkNesting(v interface{}, depth int) error {
+	if depth > maxPayloadNesting {
+		return fmt.Errorf("value nested deeper than %d levels", maxPayloadNesting)
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, value := range v {
+			if err := checkNesting(value, depth+1); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if err := checkNesting(value, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Works like encodeErrorValue, but returns an error instead of panicking
+// if an Error or Unwrap method panics, e.g. for nil pointer receivers.
+func safeEncodeErrorValue(v error) (encoded string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic encoding error of type %T: %v", v, r)
+		}
+	}()
+	return encodeErrorValue(v), nil
+}
+
+// Writes a string payload value. Never fails.
+func writeJSONStringValue(buf *bytes.Buffer, value string) error {
+	writeJSONString(buf, value)
+	return nil
+}
+
+// Writes a float the same way encoding/json does.
+func writeJSONFloat(buf *bytes.Buffer, f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("unsupported float value %s", strconv.FormatFloat(f, 'g', -1, bits))
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+
+	var scratch [64]byte
+	b := strconv.AppendFloat(scratch[:0], f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	buf.Write(b)
+
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// Writes a JSON string literal, escaped the same way encoding/json does,
+// including HTML-safe escaping and replacement of invalid UTF-8.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '\\', '"':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+
+	buf.WriteByte('"')
+}
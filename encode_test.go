@@ -0,0 +1,439 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_writeJSONString(t *testing.T) {
+	tests := []string{
+		"",
+		"plain",
+		"string with space",
+		`quote " and backslash \`,
+		"control \n \r \t \x00 \x1f \b \f",
+		"html <script>&</script>",
+		"unicode äöü 日本語 🚀",
+		"line separators \u2028 \u2029",
+		"invalid utf-8 \xff\xfe",
+	}
+
+	for _, s := range tests {
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+
+		want, _ := json.Marshal(s)
+		var got, wantDecoded string
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Errorf("writeJSONString(%q) produced invalid JSON %s: %v", s, buf.String(), err)
+			continue
+		}
+		_ = json.Unmarshal(want, &wantDecoded)
+		if got != wantDecoded {
+			t.Errorf("writeJSONString(%q) decodes to %q, want %q", s, got, wantDecoded)
+		}
+		if strings.ContainsAny(buf.String(), "<>&\u2028\u2029") {
+			t.Errorf("writeJSONString(%q) = %s, want HTML-safe escaping", s, buf.String())
+		}
+	}
+}
+
+func Test_writeJSONValue(t *testing.T) {
+	values := []interface{}{
+		nil, "s", true, false, 42, int32(-7), int64(1 << 40), uint(3), uint64(1 << 63),
+		3.14, 0.0, -1e-7, 1e21, 123456789.0, float32(2.5), float32(1e-8),
+		[]string{"a", "b"}, map[string]int{"x": 1}, struct{ A int }{A: 1},
+	}
+
+	for _, value := range values {
+		var buf bytes.Buffer
+		if err := writeJSONValue(&buf, value); err != nil {
+			t.Errorf("writeJSONValue(%#v) error = %v", value, err)
+			continue
+		}
+		want, _ := json.Marshal(value)
+		if buf.String() != string(want) {
+			t.Errorf("writeJSONValue(%#v) = %s, want %s", value, buf.String(), want)
+		}
+	}
+
+	for _, value := range []interface{}{math.NaN(), math.Inf(1), make(chan int)} {
+		var buf bytes.Buffer
+		if err := writeJSONValue(&buf, value); err == nil {
+			t.Errorf("writeJSONValue(%#v) returned no error", value)
+		}
+	}
+}
+
+func TestClient_newDelivery(t *testing.T) {
+	c, err := NewClient("my-app-id", WithUserID("somebody@example.com"), WithTestMode())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	signals := []SignalBody{
+		c.newSignal("TestNamespace.first", map[string]interface{}{
+			"TestNamespace.someString": "some <string>",
+			"TestNamespace.someInt":    42,
+			"TestNamespace.someFloat":  3.14,
+			"TestNamespace.someBool":   true,
+			"TestNamespace.someSlice":  []int{1, 2},
+		}),
+		c.newSignal("TestNamespace.second", nil),
+	}
+	// Different static fields, which can't use the pre-encoded prefix
+	signals[1].IsTestMode = false
+
+	d, err := c.newDelivery(signals, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+
+	if d.count != 2 {
+		t.Errorf("delivery count = %d, want 2", d.count)
+	}
+
+	want, _ := json.Marshal(withDefaultPayload(signals))
+	var gotDecoded, wantDecoded interface{}
+	if err := json.Unmarshal(d.body, &gotDecoded); err != nil {
+		t.Fatalf("delivery body is invalid JSON: %v\n%s", err, d.body)
+	}
+	_ = json.Unmarshal(want, &wantDecoded)
+	if !reflect.DeepEqual(gotDecoded, wantDecoded) {
+		t.Errorf("delivery body = %s, want equivalent of %s", d.body, want)
+	}
+}
+
+func TestClient_newDelivery_SortedPayloadKeys(t *testing.T) {
+	c, err := NewClient("my-app-id", WithSortedPayloadKeys())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	signals := []SignalBody{c.newSignal("TestNamespace.sorted", benchmarkPayload())}
+	want, _ := json.Marshal(withDefaultPayload(signals))
+
+	// Several runs, as map iteration order is random
+	for i := 0; i < 10; i++ {
+		d, err := c.newDelivery(signals, "")
+		if err != nil {
+			t.Fatalf("Client.newDelivery() error = %v", err)
+		}
+		if string(d.body) != string(want) {
+			t.Errorf("delivery body = %s, want %s", d.body, want)
+		}
+		d.release()
+	}
+}
+
+func TestClient_newDelivery_DefaultPayload(t *testing.T) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	payload := map[string]interface{}{
+		"TestNamespace.key":                    "value",
+		"TelemetryDeck.Device.operatingSystem": "overridden",
+	}
+	d, err := c.newDelivery([]SignalBody{c.newSignal("TestNamespace.defaults", payload)}, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+
+	var got []SignalBody
+	if err := json.Unmarshal(d.body, &got); err != nil {
+		t.Fatalf("delivery body is invalid JSON: %v\n%s", err, d.body)
+	}
+	want := map[string]interface{}{
+		"TestNamespace.key":                    "value",
+		"TelemetryDeck.Device.operatingSystem": runtime.GOOS,
+		"TelemetryDeck.Device.architecture":    runtime.GOARCH,
+		"TelemetryDeck.SDK.nameAndVersion":     version,
+	}
+	if !reflect.DeepEqual(got[0].Payload, want) {
+		t.Errorf("payload = %v, want %v", got[0].Payload, want)
+	}
+	if len(payload) != 2 || payload["TelemetryDeck.Device.operatingSystem"] != "overridden" {
+		t.Errorf("caller's payload was modified: %v", payload)
+	}
+}
+
+func TestClient_newDelivery_StringPayload(t *testing.T) {
+	c, err := NewClient("my-app-id", WithSortedPayloadKeys())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	signal := c.newSignal("TestNamespace.strings", nil)
+	signal.stringPayload = map[string]string{
+		"TestNamespace.command": "create cluster",
+		"TestNamespace.quoted":  `"<quoted>"`,
+	}
+
+	d, err := c.newDelivery([]SignalBody{signal}, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+
+	expected := signal
+	expected.Payload = map[string]interface{}{}
+	for key, value := range signal.stringPayload {
+		expected.Payload[key] = value
+	}
+	want, _ := json.Marshal(withDefaultPayload([]SignalBody{expected}))
+	if string(d.body) != string(want) {
+		t.Errorf("delivery body = %s, want %s", d.body, want)
+	}
+}
+
+func TestWithTestMode_SortsPayloadKeys(t *testing.T) {
+	c, err := NewClient("my-app-id", WithTestMode())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if !c.sortPayloadKeys {
+		t.Errorf("WithTestMode() did not enable sorted payload keys")
+	}
+}
+
+func TestClient_newDelivery_Error(t *testing.T) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	signals := []SignalBody{c.newSignal("TestNamespace.invalid", map[string]interface{}{"value": math.NaN()})}
+	if _, err := c.newDelivery(signals, ""); err == nil {
+		t.Errorf("Client.newDelivery() with NaN value returned no error")
+	}
+}
+
+func TestClient_estimateSignalSize(t *testing.T) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for _, signal := range []SignalBody{
+		c.newSignal("TestNamespace.estimate", benchmarkPayload()),
+		{AppID: "other-app-id", Type: "TestNamespace.estimate", Payload: map[string]interface{}{"key": "value"}},
+	} {
+		var buf bytes.Buffer
+		if err := c.appendSignal(&buf, &signal); err != nil {
+			t.Fatalf("Client.appendSignal() error = %v", err)
+		}
+
+		estimate := c.estimateSignalSize(&signal)
+		if actual := buf.Len(); estimate < actual*8/10 || estimate > actual*12/10 {
+			t.Errorf("Client.estimateSignalSize() = %d, actual size %d", estimate, actual)
+		}
+	}
+}
+
+// Returns copies of the signals with the standard fields added to the
+// payload, as expected in the encoded signals.
+func withDefaultPayload(signals []SignalBody) []SignalBody {
+	result := make([]SignalBody, len(signals))
+	for i, signal := range signals {
+		payload := map[string]interface{}{}
+		for key, value := range signal.Payload {
+			payload[key] = value
+		}
+		for key, value := range defaultPayload {
+			payload[key] = value
+		}
+		signal.Payload = payload
+		result[i] = signal
+	}
+	return result
+}
+
+func benchmarkPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"TestNamespace.command":  "create cluster",
+		"TestNamespace.provider": "capa",
+		"TestNamespace.count":    3,
+		"TestNamespace.dryRun":   false,
+		"TestNamespace.duration": 1.234,
+	}
+}
+
+func BenchmarkClient_newDelivery(b *testing.B) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		b.Fatal(err)
+	}
+	signals := []SignalBody{c.newSignal("TestNamespace.benchmark", benchmarkPayload())}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d, err := c.newDelivery(signals, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		d.release()
+	}
+}
+
+// Baseline for BenchmarkClient_newDelivery.
+func BenchmarkJSONMarshal(b *testing.B) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		b.Fatal(err)
+	}
+	signals := []SignalBody{c.newSignal("TestNamespace.benchmark", benchmarkPayload())}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(signals); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_newDelivery_LargeBatch(b *testing.B) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		b.Fatal(err)
+	}
+	signals := make([]SignalBody, 10000)
+	for i := range signals {
+		signals[i] = c.newSignal("TestNamespace.benchmark", benchmarkPayload())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d, err := c.newDelivery(signals, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		d.release()
+	}
+}
+
+func BenchmarkClient_newDelivery_StringPayload(b *testing.B) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		b.Fatal(err)
+	}
+	signal := c.newSignal("TestNamespace.benchmark", nil)
+	signal.stringPayload = map[string]string{
+		"TestNamespace.command":  "create cluster",
+		"TestNamespace.provider": "capa",
+		"TestNamespace.count":    "3",
+		"TestNamespace.dryRun":   "false",
+		"TestNamespace.duration": "1.234",
+	}
+	signals := []SignalBody{signal}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d, err := c.newDelivery(signals, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		d.release()
+	}
+}
+
+// Guards against allocations creeping into the encoding hot path.
+func Test_newDeliveryAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations differ with the race detector")
+	}
+
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sorted, err := NewClient("my-app-id", WithSortedPayloadKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stringSignal := c.newSignal("TestNamespace.allocs", nil)
+	stringSignal.stringPayload = map[string]string{"TestNamespace.command": "create cluster"}
+	batch := make([]SignalBody, 100)
+	for i := range batch {
+		batch[i] = c.newSignal("TestNamespace.allocs", benchmarkPayload())
+	}
+
+	tests := []struct {
+		name    string
+		client  *Client
+		signals []SignalBody
+		want    float64
+	}{
+		{name: "single signal", client: c, signals: []SignalBody{c.newSignal("TestNamespace.allocs", benchmarkPayload())}, want: 0},
+		{name: "string payload", client: c, signals: []SignalBody{stringSignal}, want: 0},
+		{name: "sorted keys", client: sorted, signals: []SignalBody{c.newSignal("TestNamespace.allocs", benchmarkPayload())}, want: 1},
+		{name: "batch", client: c, signals: batch, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocs := testing.AllocsPerRun(100, func() {
+				d, err := tt.client.newDelivery(tt.signals, "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				d.release()
+			})
+			if allocs > tt.want {
+				t.Errorf("newDelivery() allocates %.1f times, want at most %.1f", allocs, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkClient_newDelivery_Batch(b *testing.B) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		b.Fatal(err)
+	}
+	signals := make([]SignalBody, defaultMaxBatchSize)
+	for i := range signals {
+		signals[i] = c.newSignal("TestNamespace.benchmark", benchmarkPayload())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d, err := c.newDelivery(signals, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		d.release()
+	}
+}
+
+func BenchmarkClient_newDelivery_Sorted(b *testing.B) {
+	c, err := NewClient("my-app-id", WithSortedPayloadKeys())
+	if err != nil {
+		b.Fatal(err)
+	}
+	signals := []SignalBody{c.newSignal("TestNamespace.benchmark", benchmarkPayload())}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d, err := c.newDelivery(signals, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		d.release()
+	}
+}
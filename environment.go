@@ -0,0 +1,57 @@
+package telemetrydeck
+
+import (
+	"fmt"
+	"os"
+)
+
+// Environment variable naming the active environment, if not given via
+// WithEnvironment, see WithEnvironmentAppIDs.
+const EnvEnvironment = "TELEMETRY_ENVIRONMENT"
+
+// WithEnvironmentAppIDs maps environments, e.g. "staging" and
+// "production", to the TelemetryDeck apps their signals are sent to, so
+// that traffic of different environments is kept apart without branching
+// in the application. The active environment is given via WithEnvironment
+// or the environment variable TELEMETRY_ENVIRONMENT. The app ID passed to
+// NewClient, which may be empty then, is used if no environment is active.
+// NewClient returns an error wrapping ErrUnknownEnvironment if the active
+// environment isn't mapped.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithEnvironmentAppIDs(appIDs map[string]string) func(*Client) {
+	return func(c *Client) {
+		c.environmentAppIDs = appIDs
+	}
+}
+
+// WithEnvironment specifies the active environment, see
+// WithEnvironmentAppIDs. Takes precedence over the environment variable
+// TELEMETRY_ENVIRONMENT.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithEnvironment(name string) func(*Client) {
+	return func(c *Client) {
+		c.environment = name
+	}
+}
+
+// Replaces the app ID by the one of the active environment, if any.
+func (c *Client) resolveEnvironmentAppID() error {
+	if c.environmentAppIDs == nil {
+		return nil
+	}
+	if c.environment == "" {
+		c.environment = os.Getenv(EnvEnvironment)
+	}
+	if c.environment == "" {
+		return nil
+	}
+
+	appID, ok := c.environmentAppIDs[c.environment]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEnvironment, c.environment)
+	}
+	c.appID = appID
+	return nil
+}
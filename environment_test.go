@@ -0,0 +1,43 @@
+package telemetrydeck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithEnvironmentAppIDs(t *testing.T) {
+	appIDs := map[string]string{"staging": "staging-app-id", "production": "production-app-id"}
+	tests := []struct {
+		name      string
+		appID     string
+		options   []func(*Client)
+		env       string
+		wantAppID string
+		wantErr   error
+	}{
+		{name: "option", options: []func(*Client){WithEnvironment("staging")}, env: "production", wantAppID: "staging-app-id"},
+		{name: "environment variable", env: "production", wantAppID: "production-app-id"},
+		{name: "no environment", appID: "fallback-app-id", wantAppID: "fallback-app-id"},
+		{name: "no environment nor app ID", wantErr: ErrNoAppID},
+		{name: "unknown environment", appID: "fallback-app-id", env: "development", wantErr: ErrUnknownEnvironment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvEnvironment, tt.env)
+			options := append([]func(*Client){WithEnvironmentAppIDs(appIDs)}, tt.options...)
+			c, err := NewClient(tt.appID, options...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("NewClient() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if c.appID != tt.wantAppID {
+				t.Errorf("app ID = %s, want %s", c.appID, tt.wantAppID)
+			}
+		})
+	}
+}
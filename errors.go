@@ -0,0 +1,143 @@
+package telemetrydeck
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Maximum number of errors of a chain whose types are included in encoded
+// error values, to bound the size of deeply wrapped errors.
+const maxErrorChainLength = 16
+
+// Calls fn for the error and the errors it wraps, depth-first, following
+// both Unwrap() error and Unwrap() []error (see errors.Join). Stops after
+// maxErrorChainLength errors.
+func walkErrorChain(err error, fn func(err error)) {
+	var visited int
+	var walk func(err error)
+	walk = func(err error) {
+		if err == nil || visited >= maxErrorChainLength {
+			return
+		}
+		visited++
+		fn(err)
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, wrapped := range e.Unwrap() {
+				walk(wrapped)
+			}
+		}
+	}
+	walk(err)
+}
+
+// Returns the type names of the error and the errors it wraps, see
+// walkErrorChain.
+func errorChainTypes(err error) []string {
+	var types []string
+	walkErrorChain(err, func(err error) {
+		types = append(types, fmt.Sprintf("%T", err))
+	})
+	return types
+}
+
+// Returns the canonical encoding of an error payload value: its message,
+// followed by the type names of its chain, e.g.
+// "open x: no such file or directory (*fs.PathError > syscall.Errno)".
+// The default JSON encoding of most errors is "{}".
+func encodeErrorValue(err error) string {
+	return err.Error() + " (" + strings.Join(errorChainTypes(err), " > ") + ")"
+}
+
+// Keys of the payload returned by ErrorContext.
+const (
+	ErrorKeyMessage     = "Error.message"
+	ErrorKeyTypes       = "Error.types"
+	ErrorKeyCause       = "Error.cause"
+	ErrorKeyTopFrame    = "Error.topFrame"
+	ErrorKeyFingerprint = "Error.fingerprint"
+)
+
+// ErrorContext flattens the error and the errors it wraps, including those
+// combined via errors.Join, into a payload for SendStringSignal, so that
+// error telemetry looks the same across teams:
+//
+//   - Error.message: the message of the error
+//   - Error.types: the type names of the chain, e.g. "*fmt.wrapError > *fs.PathError"
+//   - Error.cause: the message of the innermost error, if it's wrapped
+//   - Error.topFrame and Error.fingerprint: the innermost frame and the
+//     fingerprint of the stack trace of the first error in the chain
+//     carrying one, if any (see StackTrace)
+//
+// Errors carry a stack trace if they have a StackTrace method returning a
+// StackTrace, or a slice of program counters like the errors of
+// github.com/pkg/errors. Returns nil if err is nil.
+func ErrorContext(err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+
+	payload := map[string]string{
+		ErrorKeyMessage: err.Error(),
+		ErrorKeyTypes:   strings.Join(errorChainTypes(err), " > "),
+	}
+
+	var cause error
+	var stack StackTrace
+	walkErrorChain(err, func(e error) {
+		if cause == nil && !wrapsErrors(e) {
+			cause = e
+		}
+		if stack == nil {
+			stack = errorStackTrace(e)
+		}
+	})
+	if cause != nil && cause != err {
+		payload[ErrorKeyCause] = cause.Error()
+	}
+	if len(stack) > 0 {
+		payload[ErrorKeyTopFrame] = stack.TopFrame()
+		payload[ErrorKeyFingerprint] = stack.Fingerprint()
+	}
+
+	return payload
+}
+
+// Reports whether the error wraps other errors.
+func wrapsErrors(err error) bool {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap() != nil
+	case interface{ Unwrap() []error }:
+		return len(e.Unwrap()) > 0
+	}
+	return false
+}
+
+// Returns the stack trace carried by the error, if any: either a
+// StackTrace, or program counters as returned by runtime.Callers, like the
+// errors.StackTrace of github.com/pkg/errors.
+func errorStackTrace(err error) StackTrace {
+	if e, ok := err.(interface{ StackTrace() StackTrace }); ok {
+		return e.StackTrace()
+	}
+
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+	out := method.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+
+	value := method.Call(nil)[0]
+	pcs := make([]uintptr, value.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(value.Index(i).Uint())
+	}
+	return stackTraceFromPCs(pcs)
+}
@@ -0,0 +1,127 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_encodeErrorValue(t *testing.T) {
+	_, pathErr := os.Open("/does/not/exist")
+	sentinel := errors.New("sentinel")
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "simple",
+			err:  sentinel,
+			want: "sentinel (*errors.errorString)",
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("loading config: %w", pathErr),
+			want: "loading config: open /does/not/exist: no such file or directory (*fmt.wrapError > *fs.PathError > syscall.Errno)",
+		},
+		{
+			name: "joined",
+			err:  errors.Join(sentinel, fs.ErrNotExist),
+			want: "sentinel\nfile does not exist (*errors.joinError > *errors.errorString > *errors.errorString)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeErrorValue(tt.err); got != tt.want {
+				t.Errorf("encodeErrorValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Error wrapping itself, to check that chains are bounded
+type cyclicError struct{}
+
+func (e *cyclicError) Error() string { return "cyclic" }
+func (e *cyclicError) Unwrap() error { return e }
+
+func Test_errorChainTypes_Bounded(t *testing.T) {
+	if got := len(errorChainTypes(&cyclicError{})); got != maxErrorChainLength {
+		t.Errorf("len(errorChainTypes()) = %d, want %d", got, maxErrorChainLength)
+	}
+}
+
+func Test_writeJSONValue_Error(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, errors.New("failed")); err != nil {
+		t.Fatalf("writeJSONValue() error = %v", err)
+	}
+	if want := `"failed (*errors.errorString)"`; buf.String() != want {
+		t.Errorf("writeJSONValue() = %s, want %s", buf.String(), want)
+	}
+}
+
+// Error carrying a stack trace as program counters, like the errors of
+// github.com/pkg/errors.
+type pcStackError struct {
+	pcs []uintptr
+}
+
+func (e *pcStackError) Error() string { return "with stack" }
+
+func (e *pcStackError) StackTrace() []uintptr { return e.pcs }
+
+//go:noinline
+func newPCStackError() error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	return &pcStackError{pcs: pcs[:n]}
+}
+
+func TestErrorContext(t *testing.T) {
+	if got := ErrorContext(nil); got != nil {
+		t.Errorf("ErrorContext(nil) = %v, want nil", got)
+	}
+
+	_, pathErr := os.Open("/does/not/exist")
+	got := ErrorContext(fmt.Errorf("loading config: %w", pathErr))
+	want := map[string]string{
+		ErrorKeyMessage: "loading config: open /does/not/exist: no such file or directory",
+		ErrorKeyTypes:   "*fmt.wrapError > *fs.PathError > syscall.Errno",
+		ErrorKeyCause:   "no such file or directory",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ErrorContext() = %v, want %v", got, want)
+	}
+
+	// Stack traces of wrapped errors
+	for name, err := range map[string]error{
+		"program counters": fmt.Errorf("wrapped: %w", newPCStackError()),
+		"StackTrace":       errors.Join(errors.New("other"), &stackError{stack: captureStackHelper()}),
+	} {
+		got := ErrorContext(err)
+		if !strings.Contains(got[ErrorKeyTopFrame], "errors_test.go") && !strings.Contains(got[ErrorKeyTopFrame], "stack_test.go") {
+			t.Errorf("%s: ErrorContext()[%s] = %q, want frame of the test", name, ErrorKeyTopFrame, got[ErrorKeyTopFrame])
+		}
+		if len(got[ErrorKeyFingerprint]) != 16 {
+			t.Errorf("%s: ErrorContext()[%s] = %q, want fingerprint", name, ErrorKeyFingerprint, got[ErrorKeyFingerprint])
+		}
+	}
+}
+
+// Error carrying a StackTrace.
+type stackError struct {
+	stack StackTrace
+}
+
+func (e *stackError) Error() string { return "with stack" }
+
+func (e *stackError) StackTrace() StackTrace { return e.stack }
@@ -0,0 +1,89 @@
+package telemetrydeck
+
+import "sync"
+
+// Number of consecutive failed deliveries after which we fail over to the
+// next endpoint, unless configured otherwise.
+const defaultFailoverThreshold = 3
+
+// Tracks which of the configured endpoints is currently in use and how
+// many deliveries to it have failed in a row.
+type failoverState struct {
+	mu       sync.Mutex
+	current  int
+	failures int
+}
+
+// WithFallbackEndpoints specifies endpoints to fail over to when the
+// primary endpoint (see WithEndpoint) is unreachable.
+//
+// After a number of consecutive failed deliveries (see
+// WithFailoverThreshold), the client switches to the next endpoint in the
+// list. After the last fallback endpoint, the primary endpoint is tried
+// again.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithFallbackEndpoints(endpoints ...string) func(*Client) {
+	return func(c *Client) {
+		c.fallbackEndpoints = endpoints
+	}
+}
+
+// WithFailoverThreshold specifies after how many consecutive failed
+// deliveries the client switches to the next endpoint. Defaults to 3.
+// Only has an effect in combination with WithFallbackEndpoints.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithFailoverThreshold(n int) func(*Client) {
+	return func(c *Client) {
+		if n > 0 {
+			c.failoverThreshold = n
+		}
+	}
+}
+
+// Returns the primary endpoint followed by all fallback endpoints.
+func (c *Client) endpoints() []string {
+	return append([]string{c.live().endpoint}, c.fallbackEndpoints...)
+}
+
+// Returns the endpoint deliveries should currently be sent to.
+func (c *Client) activeEndpoint() string {
+	if len(c.fallbackEndpoints) == 0 {
+		return c.live().endpoint
+	}
+
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+	return c.endpoints()[c.failover.current]
+}
+
+// Records whether a delivery to the given endpoint reached it, and
+// switches to the next endpoint once the failover threshold is reached.
+func (c *Client) reportEndpointResult(endpoint string, reachable bool) {
+	if len(c.fallbackEndpoints) == 0 {
+		return
+	}
+
+	c.failover.mu.Lock()
+	defer c.failover.mu.Unlock()
+
+	endpoints := c.endpoints()
+	if endpoints[c.failover.current] != endpoint {
+		// Result for an endpoint we already failed over from
+		return
+	}
+	if reachable {
+		c.failover.failures = 0
+		return
+	}
+
+	c.failover.failures++
+	if c.failover.failures < c.failoverThreshold {
+		return
+	}
+
+	c.failover.current = (c.failover.current + 1) % len(endpoints)
+	c.failover.failures = 0
+	c.log(LogSubsystemTransport, LogLevelWarn, "endpoint unreachable, failing over", "endpoint", endpoint, "fallback", endpoints[c.failover.current])
+}
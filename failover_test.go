@@ -0,0 +1,110 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Failover(t *testing.T) {
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	var fallbackHits int
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fallbackHits++
+	}))
+	defer fallback.Close()
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(unreachable.URL),
+		WithFallbackEndpoints(fallback.URL),
+		WithFailoverThreshold(2),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	d := delivery{body: []byte("[]")}
+
+	c.deliver(d)
+	if got := c.activeEndpoint(); got != unreachable.URL {
+		t.Errorf("after 1 failure: activeEndpoint() = %q, want %q", got, unreachable.URL)
+	}
+
+	c.deliver(d)
+	if got := c.activeEndpoint(); got != fallback.URL {
+		t.Errorf("after 2 failures: activeEndpoint() = %q, want %q", got, fallback.URL)
+	}
+
+	c.deliver(d)
+	if fallbackHits != 1 {
+		t.Errorf("fallback endpoint got %d requests, want 1", fallbackHits)
+	}
+	if got := c.activeEndpoint(); got != fallback.URL {
+		t.Errorf("after successful delivery: activeEndpoint() = %q, want %q", got, fallback.URL)
+	}
+}
+
+func TestClient_NoFallbackEndpoints(t *testing.T) {
+	c, err := NewClient("my-app-id", WithEndpoint("http://primary.example"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		c.reportEndpointResult("http://primary.example", false)
+	}
+	if got := c.activeEndpoint(); got != "http://primary.example" {
+		t.Errorf("activeEndpoint() = %q, want primary endpoint", got)
+	}
+}
+
+func TestClient_FailoverIgnoresCanceledContext(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(300 * time.Millisecond):
+		}
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fallback.Close()
+
+	var hookErrors int
+	c, err := NewClient("my-app-id",
+		WithEndpoint(primary.URL),
+		WithFallbackEndpoints(fallback.URL),
+		WithFailoverThreshold(1),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		WithHooks(Hooks{OnError: func(error) { hookErrors++ }}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		c.deliverChunk([]QueuedSignal{{Signal: c.newSignal("TestNamespace.test", nil), ctx: ctx}})
+		cancel()
+	}
+
+	if got := c.activeEndpoint(); got != primary.URL {
+		t.Errorf("activeEndpoint() = %q, want primary endpoint %q", got, primary.URL)
+	}
+	if !c.Healthy() {
+		t.Errorf("Healthy() = false, want true, CheckHealth() = %v", c.CheckHealth(context.Background()))
+	}
+	if _, err := c.LastError(); err != nil {
+		t.Errorf("LastError() = %v, want nil", err)
+	}
+	if hookErrors != 0 {
+		t.Errorf("OnError called %d times, want 0", hookErrors)
+	}
+	if stats := c.Stats(); stats.Dropped != 2 || stats.Failures != 0 {
+		t.Errorf("Stats() = %+v, want 2 dropped and no failures", stats)
+	}
+}
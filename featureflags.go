@@ -0,0 +1,101 @@
+package telemetrydeck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Type of the signals sent by TrackFlagEvaluation.
+const FlagEvaluationSignalType = "TelemetryDeck.FeatureFlag.evaluated"
+
+// Keys of the payload of signals sent by TrackFlagEvaluation.
+const (
+	// Name of the feature flag
+	FlagEvaluationKeyFlag = "TelemetryDeck.FeatureFlag.name"
+
+	// Variant the flag evaluated to
+	FlagEvaluationKeyVariant = "TelemetryDeck.FeatureFlag.variant"
+)
+
+// Default time within which evaluations of a feature flag to the same
+// variant are only tracked once, see WithFlagEvaluationInterval.
+const DefaultFlagEvaluationInterval = time.Hour
+
+// WithFlagEvaluationInterval specifies the time within which evaluations
+// of a feature flag are only tracked once by TrackFlagEvaluation, unless
+// the flag evaluates to a different variant. Defaults to
+// DefaultFlagEvaluationInterval, zero tracks every evaluation.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithFlagEvaluationInterval(interval time.Duration) func(*Client) {
+	return func(c *Client) {
+		if interval >= 0 {
+			c.flagEvaluations.interval = interval
+		}
+	}
+}
+
+// Throttles the signals sent by TrackFlagEvaluation.
+type flagEvaluations struct {
+	interval time.Duration
+
+	mu sync.Mutex
+	// Most recently tracked evaluation, by flag
+	last map[string]flagEvaluation
+}
+
+// An evaluation of a feature flag that was tracked.
+type flagEvaluation struct {
+	variant string
+	time    time.Time
+}
+
+// TrackFlagEvaluation sends a signal of type FlagEvaluationSignalType
+// telling that the feature flag evaluated to the variant, so that the
+// exposure of users to the variants can be analyzed. It's meant to be
+// called on every evaluation, e.g. from an OpenFeature hook: evaluations
+// of a flag to the same variant as the previous one are only tracked once
+// per interval (see WithFlagEvaluationInterval), so that hot code paths
+// don't flood the ingest API. Like other signals sent by the client
+// itself, they aren't subject to the signal policy, reserved payload keys
+// and sampling. Returns errors like SendStringSignal.
+func (c *Client) TrackFlagEvaluation(flag, variant string) error {
+	if c.discardsSignals() {
+		return nil
+	}
+
+	f := &c.flagEvaluations
+	now := c.clock.Now()
+	f.mu.Lock()
+	previous, tracked := f.last[flag]
+	if tracked && previous.variant == variant && now.Sub(previous.time) < f.interval {
+		f.mu.Unlock()
+		return nil
+	}
+	if f.last == nil {
+		f.last = map[string]flagEvaluation{}
+	}
+	f.last[flag] = flagEvaluation{variant: variant, time: now}
+	f.mu.Unlock()
+
+	payload := map[string]string{
+		FlagEvaluationKeyFlag:    flag,
+		FlagEvaluationKeyVariant: variant,
+	}
+	signal := c.newStringSignal(FlagEvaluationSignalType, payload)
+	if err := c.sendSignal(context.Background(), signal); err != nil {
+		// Allows tracking the evaluation again
+		f.mu.Lock()
+		if f.last[flag] == (flagEvaluation{variant: variant, time: now}) {
+			if tracked {
+				f.last[flag] = previous
+			} else {
+				delete(f.last, flag)
+			}
+		}
+		f.mu.Unlock()
+		return err
+	}
+	return nil
+}
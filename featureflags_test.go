@@ -0,0 +1,80 @@
+package telemetrydeck
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClient_TrackFlagEvaluation(t *testing.T) {
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id", WithClock(clock), WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	track := func(flag, variant string) {
+		t.Helper()
+		if err := c.TrackFlagEvaluation(flag, variant); err != nil {
+			t.Fatalf("Client.TrackFlagEvaluation() error = %v", err)
+		}
+	}
+
+	track("new-checkout", "on")
+	track("new-checkout", "on")
+	track("dark-mode", "off")
+	if n := c.store.Len(); n != 2 {
+		t.Errorf("queued signals = %d, want 2", n)
+	}
+
+	// A different variant is tracked right away
+	track("new-checkout", "off")
+	if n := c.store.Len(); n != 3 {
+		t.Errorf("queued signals = %d, want 3 after variant change", n)
+	}
+
+	// The same variant is tracked again after the interval
+	clock.advance(DefaultFlagEvaluationInterval)
+	track("new-checkout", "off")
+	if n := c.store.Len(); n != 4 {
+		t.Errorf("queued signals = %d, want 4 after interval", n)
+	}
+
+	batch, err := c.store.DequeueBatch(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signal := batch[0].Signal
+	if signal.Type != FlagEvaluationSignalType {
+		t.Errorf("signal type = %s, want %s", signal.Type, FlagEvaluationSignalType)
+	}
+	if signal.stringPayload[FlagEvaluationKeyFlag] != "new-checkout" || signal.stringPayload[FlagEvaluationKeyVariant] != "on" {
+		t.Errorf("payload = %v, want flag new-checkout and variant on", signal.stringPayload)
+	}
+}
+
+func TestWithFlagEvaluationInterval(t *testing.T) {
+	c, err := NewClient("my-app-id", WithFlagEvaluationInterval(0), WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.TrackFlagEvaluation("new-checkout", "on"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := c.store.Len(); n != 3 {
+		t.Errorf("queued signals = %d, want every evaluation tracked", n)
+	}
+}
+
+func TestClient_TrackFlagEvaluation_strictPayloadKeys(t *testing.T) {
+	c, err := NewClient("my-app-id", WithStrictPayloadKeys(), WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.TrackFlagEvaluation("new-checkout", "on"); err != nil {
+		t.Errorf("Client.TrackFlagEvaluation() error = %v, want reserved keys accepted", err)
+	}
+	if n := c.store.Len(); n != 1 {
+		t.Errorf("queued signals = %d, want 1", n)
+	}
+}
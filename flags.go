@@ -0,0 +1,64 @@
+package telemetrydeck
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+// Keys of the payload returned by FlagUsage.
+const (
+	// Comma-separated, sorted names of the flags set explicitly
+	FlagKeySet = "Flags.set"
+
+	// Prefix of the keys holding the hashed values of the flags set
+	// explicitly, followed by the flag name
+	FlagKeyValuePrefix = "Flags.value."
+)
+
+// FlagValues determines whether FlagUsage includes flag values.
+type FlagValues int
+
+const (
+	// Only flag names are included. The default.
+	FlagValuesOmit FlagValues = iota
+
+	// Flag values are included as SHA-256 hashes, so that signals can be
+	// grouped by value without transmitting it. Values with few possible
+	// choices can still be recovered from their hashes.
+	FlagValuesHash
+)
+
+// FlagUsage returns a payload for SendStringSignal describing which flags
+// of the flag set have been set explicitly on the command line, e.g. for a
+// command signal. Values are never included as given, so that user input
+// isn't leaked. Must be called after fs.Parse.
+func FlagUsage(fs *flag.FlagSet, values FlagValues) map[string]string {
+	return FlagUsageFunc(func(record func(name, value string)) {
+		fs.Visit(func(f *flag.Flag) {
+			record(f.Name, f.Value.String())
+		})
+	}, values)
+}
+
+// FlagUsageFunc works like FlagUsage for other flag packages. The visit
+// function is called once, and must call record for every flag set
+// explicitly. With github.com/spf13/pflag:
+//
+//	telemetrydeck.FlagUsageFunc(func(record func(name, value string)) {
+//		fs.Visit(func(f *pflag.Flag) { record(f.Name, f.Value.String()) })
+//	}, telemetrydeck.FlagValuesOmit)
+func FlagUsageFunc(visit func(record func(name, value string)), values FlagValues) map[string]string {
+	payload := map[string]string{}
+	var names []string
+	visit(func(name, value string) {
+		names = append(names, name)
+		if values == FlagValuesHash {
+			payload[FlagKeyValuePrefix+name] = hashUserId(value, "")
+		}
+	})
+
+	sort.Strings(names)
+	payload[FlagKeySet] = strings.Join(names, ",")
+	return payload
+}
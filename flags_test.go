@@ -0,0 +1,63 @@
+package telemetrydeck
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestFlagUsage(t *testing.T) {
+	newFlagSet := func() *flag.FlagSet {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.String("token", "", "")
+		fs.Bool("verbose", false, "")
+		fs.Int("count", 1, "")
+		if err := fs.Parse([]string{"--verbose", "--token", "secret"}); err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+
+	tests := []struct {
+		name   string
+		values FlagValues
+		want   map[string]string
+	}{
+		{
+			name:   "omit values",
+			values: FlagValuesOmit,
+			want:   map[string]string{FlagKeySet: "token,verbose"},
+		},
+		{
+			name:   "hash values",
+			values: FlagValuesHash,
+			want: map[string]string{
+				FlagKeySet:                     "token,verbose",
+				FlagKeyValuePrefix + "token":   hashUserId("secret", ""),
+				FlagKeyValuePrefix + "verbose": hashUserId("true", ""),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FlagUsage(newFlagSet(), tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FlagUsage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagUsage_NoFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("token", "", "")
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{FlagKeySet: ""}
+	if got := FlagUsage(fs, FlagValuesHash); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlagUsage() = %v, want %v", got, want)
+	}
+}
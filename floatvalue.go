@@ -0,0 +1,66 @@
+package telemetrydeck
+
+import (
+	"math"
+	"strconv"
+)
+
+// WithFloatValueKey makes the client copy the numeric value of the payload
+// key, e.g. "TestNamespace.durationInSeconds", to the floatValue field of
+// signals, which TelemetryDeck can aggregate (e.g. averages or sums), so
+// that existing call sites provide metrics without changes. Values of
+// SendStringSignal are parsed as floats. Signals without a numeric value
+// for the key are sent without floatValue. The key stays in the payload.
+// Ingest API v1 doesn't support floatValue.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithFloatValueKey(key string) func(*Client) {
+	return func(c *Client) {
+		c.floatValueKey = key
+	}
+}
+
+// Returns the value as a float, if it's a finite number or a string
+// containing one.
+func floatValueOf(value interface{}) (float64, bool) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int:
+		f = float64(v)
+	case int32:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case uint:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(v, 64); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// Sets the floatValue of the signal from the payload value of the key
+// given via WithFloatValueKey, if any.
+func setFloatValue[V any](c *Client, signal *SignalBody, payload map[string]V) {
+	value, ok := payload[c.floatValueKey]
+	if !ok {
+		return
+	}
+	if f, ok := floatValueOf(value); ok {
+		signal.FloatValue = &f
+	}
+}
@@ -0,0 +1,44 @@
+package telemetrydeck
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClient_FloatValueKey(t *testing.T) {
+	c, err := NewClient("my-app-id", WithFloatValueKey("TestNamespace.duration"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	signals := []SignalBody{
+		c.newSignal("TestNamespace.floatTest", map[string]interface{}{"TestNamespace.duration": 1.5}),
+		c.newSignal("TestNamespace.floatTest", map[string]interface{}{"TestNamespace.duration": 3}),
+		c.newStringSignal("TestNamespace.floatTest", map[string]string{"TestNamespace.duration": "4.25"}),
+		c.newStringSignal("TestNamespace.floatTest", map[string]string{"TestNamespace.duration": "slow"}),
+		c.newSignal("TestNamespace.floatTest", nil),
+	}
+	d, err := c.newDelivery(signals, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+
+	var body []SignalBody
+	if err := json.Unmarshal(d.body, &body); err != nil {
+		t.Fatalf("invalid request body %s: %v", d.body, err)
+	}
+	want := []interface{}{1.5, 3.0, 4.25, nil, nil}
+	for i, signal := range body {
+		var got interface{}
+		if signal.FloatValue != nil {
+			got = *signal.FloatValue
+		}
+		if got != want[i] {
+			t.Errorf("floatValue of signal %d = %v, want %v", i, got, want[i])
+		}
+	}
+	if body[0].Payload["TestNamespace.duration"] != 1.5 {
+		t.Errorf("payload = %v, want key kept", body[0].Payload)
+	}
+}
@@ -0,0 +1,126 @@
+package telemetrydeck
+
+import (
+	"context"
+	"time"
+)
+
+// FlushTriggers determine when queued signals are delivered: as soon as
+// any of the conditions is met, all queued signals are delivered. A zero
+// value field disables the condition. If all are zero, signals are
+// delivered immediately.
+type FlushTriggers struct {
+	// Number of queued signals
+	Count int
+
+	// Estimated total size of the signals waiting for delivery
+	Bytes int64
+
+	// Time since the oldest queued signal was enqueued
+	MaxAge time.Duration
+}
+
+// DefaultFlushTriggers are the flush triggers used unless configured
+// otherwise via WithFlushTriggers.
+var DefaultFlushTriggers = FlushTriggers{
+	Count:  defaultMaxBatchSize,
+	Bytes:  256 << 10,
+	MaxAge: time.Second,
+}
+
+// WithFlushTriggers specifies when queued signals are delivered. Signals
+// are also delivered when the queue is full, and on demand via Flush.
+// Defaults to DefaultFlushTriggers.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithFlushTriggers(triggers FlushTriggers) func(*Client) {
+	return func(c *Client) {
+		c.flushTriggers = triggers
+	}
+}
+
+// Flush waits until all signals passed to SendSignal so far have been
+// delivered, or their delivery has failed permanently or after all
+// retries. Queued signals are delivered in batches by all workers in
+// parallel (see WithWorkers and WithMaxBatchSize).
+//
+// Returns the context's error if the context is done before all signals
+// have been delivered, e.g. because its deadline passed. Delivery errors
+// are not returned, but passed to the OnError hook.
+func (c *Client) Flush(ctx context.Context) error {
+	for {
+		// Register for notification before checking, so that finishing the
+		// last signal in between is not missed.
+		idle := c.idleNotify()
+		if c.unfinished.Load() == 0 {
+			return nil
+		}
+
+		c.startWorkers()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-idle:
+		}
+	}
+}
+
+// Starts delivering the queued signals if a flush trigger condition is
+// met. Otherwise, makes sure they are delivered once the oldest is too old.
+func (c *Client) checkFlushTriggers() {
+	t := c.flushTriggers
+	if (t.Count <= 0 && t.Bytes <= 0 && t.MaxAge <= 0) ||
+		(t.Count > 0 && c.store.Len() >= t.Count) ||
+		(t.Bytes > 0 && c.pendingBytes.Load() >= t.Bytes) {
+		c.startWorkers()
+		return
+	}
+	if t.MaxAge <= 0 {
+		return
+	}
+
+	c.flushTimerMu.Lock()
+	defer c.flushTimerMu.Unlock()
+
+	// An armed timer fires no later than the age limit of all signals
+	// enqueued since it was armed.
+	if !c.flushTimerArmed {
+		c.flushTimerArmed = true
+		c.clock.AfterFunc(t.MaxAge, func() {
+			c.flushTimerMu.Lock()
+			c.flushTimerArmed = false
+			c.flushTimerMu.Unlock()
+
+			c.startWorkers()
+		})
+	}
+}
+
+// Returns a channel that is closed when no enqueued signals are
+// unfinished anymore.
+func (c *Client) idleNotify() <-chan struct{} {
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+
+	if c.idle == nil {
+		c.idle = make(chan struct{})
+	}
+	return c.idle
+}
+
+// Records that n enqueued signals have been delivered, or failed or been
+// dropped, and notifies waiting flushes if none are unfinished anymore.
+func (c *Client) finish(n int) {
+	if c.unfinished.Add(-int64(n)) != 0 {
+		return
+	}
+
+	c.idleMu.Lock()
+	defer c.idleMu.Unlock()
+
+	if c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
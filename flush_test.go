@@ -0,0 +1,179 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Delivers signals as soon as they are enqueued, so that tests don't have
+// to wait for the flush triggers.
+var deliverImmediately = WithFlushTriggers(FlushTriggers{})
+
+func TestClient_Flush(t *testing.T) {
+	var requests, received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		if len(signals) > 10 {
+			t.Errorf("request with %d signals, want at most 10", len(signals))
+		}
+		requests.Add(1)
+		received.Add(int32(len(signals)))
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithWorkers(4), WithMaxBatchSize(10))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for i := 0; i < 200; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.flushTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	if got := received.Load(); got != 200 {
+		t.Errorf("server received %d signals, want 200", got)
+	}
+	if got := requests.Load(); got < 20 || got >= 200 {
+		t.Errorf("server received %d requests, want batches of up to 10 signals", got)
+	}
+	if stats := c.Stats(); stats.Queued != 0 || stats.PendingBytes != 0 {
+		t.Errorf("Stats() = %+v, want empty queue", stats)
+	}
+
+	// Nothing to flush
+	if err := c.Flush(ctx); err != nil {
+		t.Errorf("Client.Flush() without signals error = %v", err)
+	}
+}
+
+func TestClient_FlushDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.flushTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Client.Flush() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClient_deliverBatch(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// One request per run of signals with the same token
+	signal := c.newSignal("TestNamespace.batchTest", nil)
+	c.deliverBatch([]QueuedSignal{
+		{Signal: signal, Token: "a"},
+		{Signal: signal, Token: "a"},
+		{Signal: signal, Token: "b"},
+		{Signal: signal, Token: "a"},
+	})
+
+	want := []string{"Bearer a", "Bearer b", "Bearer a"}
+	if len(tokens) != len(want) {
+		t.Fatalf("%d requests, want %d", len(tokens), len(want))
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("request %d Authorization = %q, want %q", i, tokens[i], want[i])
+		}
+	}
+}
+
+func TestClient_FlushTriggers(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers FlushTriggers
+		sends    int
+		want     int32 // signals delivered without Flush
+	}{
+		{name: "count not reached", triggers: FlushTriggers{Count: 3, MaxAge: time.Hour}, sends: 2, want: 0},
+		{name: "count reached", triggers: FlushTriggers{Count: 3, MaxAge: time.Hour}, sends: 3, want: 3},
+		{name: "bytes reached", triggers: FlushTriggers{Bytes: 1, MaxAge: time.Hour}, sends: 1, want: 1},
+		{name: "max age", triggers: FlushTriggers{Count: 100, MaxAge: 20 * time.Millisecond}, sends: 1, want: 1},
+		{name: "immediately", triggers: FlushTriggers{}, sends: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var signals []SignalBody
+				if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+					t.Errorf("decoding body: %v", err)
+				}
+				received.Add(int32(len(signals)))
+			}))
+			defer server.Close()
+
+			c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithFlushTriggers(tt.triggers))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			for i := 0; i < tt.sends; i++ {
+				if err := c.SendSignal(context.Background(), "TestNamespace.triggerTest", nil); err != nil {
+					t.Fatalf("Client.SendSignal() error = %v", err)
+				}
+			}
+
+			deadline := time.Now().Add(200 * time.Millisecond)
+			for received.Load() < tt.want && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if tt.want == 0 {
+				time.Sleep(50 * time.Millisecond)
+			}
+			if got := received.Load(); got != tt.want {
+				t.Errorf("server received %d signals, want %d", got, tt.want)
+			}
+
+			// Flushing on demand delivers the rest
+			if err := c.Flush(context.Background()); err != nil {
+				t.Fatalf("Client.Flush() error = %v", err)
+			}
+			if got := received.Load(); got != int32(tt.sends) {
+				t.Errorf("server received %d signals after Flush, want %d", got, tt.sends)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzWriteJSONString(f *testing.F) {
+	for _, seed := range []string{"", "plain", "quote\" and \\", "<html>&", "\x00\x1f\n\t", "  ", "\xff\xfe", "日本語", "a\xc3"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+
+		// Compared after decoding, as encoding/json writes replacement
+		// characters for invalid UTF-8 unescaped
+		want := string([]rune(s))
+		var got string
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("writeJSONString(%q) = %s, invalid JSON: %v", s, buf.Bytes(), err)
+		}
+		if got != want {
+			t.Errorf("writeJSONString(%q) decodes to %q, want %q", s, got, want)
+		}
+	})
+}
+
+func FuzzClient_appendSignal(f *testing.F) {
+	f.Add("TestNamespace.fuzz", "key", "value", 1.5, 0, false)
+	f.Add("", "TelemetryDeck.Device.operatingSystem", "\xff", math.Inf(1), 10, true)
+	f.Add("Type ", "", "<>&", -0.0, 1000, false)
+
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		f.Fatal(err)
+	}
+	sorted, err := NewClient("my-app-id", WithSortedPayloadKeys(), WithDefaultKeyOverrides())
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, signalType, key, value string, number float64, nesting int, stringPayload bool) {
+		// Nested value, bounded to keep the fuzzer fast
+		var nested interface{} = value
+		for i := 0; i < nesting%200; i++ {
+			nested = map[string]interface{}{key: nested, "list": []interface{}{number}}
+		}
+
+		var signal SignalBody
+		if stringPayload {
+			signal = c.newSignal(signalType, nil)
+			signal.stringPayload = map[string]string{key: value, value: key}
+		} else {
+			signal = c.newSignal(signalType, map[string]interface{}{key: value, "number": number, "nested": nested})
+		}
+
+		for _, client := range []*Client{c, sorted} {
+			var buf bytes.Buffer
+			if err := client.appendSignal(&buf, &signal); err != nil {
+				continue
+			}
+			if !json.Valid(buf.Bytes()) {
+				t.Fatalf("Client.appendSignal() = %s, invalid JSON", buf.Bytes())
+			}
+
+			var decoded SignalBody
+			if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if utf8.ValidString(signalType) && decoded.Type != signalType {
+				t.Errorf("decoded type = %q, want %q", decoded.Type, signalType)
+			}
+			if !strings.HasPrefix(key, reservedKeyPrefix) && utf8.ValidString(key) && utf8.ValidString(value) && !stringPayload &&
+				key != "number" && key != "nested" && decoded.Payload[key] != value {
+				t.Errorf("decoded payload %q = %v, want %q", key, decoded.Payload[key], value)
+			}
+		}
+	})
+}
+
+// Error panicking for nil receivers, like many error types.
+type nilPanicError struct {
+	msg string
+}
+
+func (e *nilPanicError) Error() string { return e.msg }
+
+// Value whose MarshalJSON method panics.
+type panickingMarshaler struct{}
+
+func (panickingMarshaler) MarshalJSON() ([]byte, error) { panic("boom") }
+
+func Test_writeJSONValue_Hardened(t *testing.T) {
+	var deep interface{} = "leaf"
+	for i := 0; i < maxPayloadNesting+1; i++ {
+		deep = []interface{}{deep}
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{name: "nil error pointer", value: (*nilPanicError)(nil)},
+		{name: "panicking marshaler", value: panickingMarshaler{}},
+		{name: "too deeply nested", value: deep},
+		{name: "NaN", value: math.NaN()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeJSONValue(&buf, tt.value); err == nil {
+				t.Errorf("writeJSONValue() = %s, want error", buf.Bytes())
+			}
+		})
+	}
+}
@@ -2,4 +2,11 @@ module github.com/giantswarm/telemetrydeck-go
 
 go 1.21
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/go-logr/logr v1.4.2
+	github.com/google/uuid v1.6.0
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/text v0.14.0
+)
+
+require golang.org/x/sys v0.5.0 // indirect
@@ -0,0 +1,106 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HealthError is returned by CheckHealth if the delivery pipeline is not
+// operational, listing the problems found.
+type HealthError struct {
+	Problems []string
+}
+
+func (e *HealthError) Error() string {
+	return "telemetry delivery unhealthy: " + strings.Join(e.Problems, "; ")
+}
+
+// CheckHealth reports whether the delivery pipeline is operational: the
+// client has not been closed, the primary endpoint has not been failed over
+// from (see WithFallbackEndpoints), the queue is below the high watermark
+// (see WithQueueWatermarks), and the most recent delivery succeeded.
+// Returns a *HealthError describing the problems otherwise, or
+// ErrClientClosed.
+//
+// Telemetry is rarely critical to a service, so the result is meant to be
+// wired into readiness endpoints as a non-fatal check, e.g. for logging or
+// alerting.
+func (c *Client) CheckHealth(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+
+	var problems []string
+	if endpoint := c.activeEndpoint(); endpoint != c.live().endpoint {
+		problems = append(problems, fmt.Sprintf("failed over to %s", endpoint))
+	}
+	if n := c.store.Len(); n >= c.highWatermark {
+		problems = append(problems, fmt.Sprintf("%d signals queued, high watermark is %d", n, c.highWatermark))
+	}
+	if err := c.lastDeliveryError(); err != nil {
+		problems = append(problems, fmt.Sprintf("last delivery failed: %s", err))
+	}
+
+	if len(problems) > 0 {
+		return &HealthError{Problems: problems}
+	}
+	return nil
+}
+
+// Healthy reports whether the delivery pipeline is operational, see
+// CheckHealth.
+func (c *Client) Healthy() bool {
+	return c.CheckHealth(context.Background()) == nil
+}
+
+// LastError returns when the most recent delivery error occurred and the
+// error, even if later deliveries succeeded, or the zero time and nil if
+// no delivery failed yet. Together with LastSuccess, it's meant for
+// diagnostic commands reporting the telemetry connectivity.
+func (c *Client) LastError() (time.Time, error) {
+	c.lastDeliveryMu.Lock()
+	defer c.lastDeliveryMu.Unlock()
+	return c.lastErrorTime, c.lastDeliveryErr
+}
+
+// LastSuccess returns when the most recent successful delivery completed,
+// or the zero time if no delivery succeeded yet.
+func (c *Client) LastSuccess() time.Time {
+	c.lastDeliveryMu.Lock()
+	defer c.lastDeliveryMu.Unlock()
+	return c.lastSuccessTime
+}
+
+// Records the outcome of a delivery of the given number of signals.
+func (c *Client) recordDeliveryResult(err error, signals int) {
+	if err == nil {
+		c.stats.recordDelivered(signals)
+	}
+	now := c.clock.Now()
+
+	c.lastDeliveryMu.Lock()
+	defer c.lastDeliveryMu.Unlock()
+	if err != nil {
+		c.lastDeliveryErr = err
+		c.lastErrorTime = now
+	} else {
+		c.lastSuccessTime = now
+	}
+	c.lastDeliveryFailed = err != nil
+}
+
+// Returns the error of the most recent delivery, nil if it succeeded or
+// there was none.
+func (c *Client) lastDeliveryError() error {
+	c.lastDeliveryMu.Lock()
+	defer c.lastDeliveryMu.Unlock()
+	if !c.lastDeliveryFailed {
+		return nil
+	}
+	return c.lastDeliveryErr
+}
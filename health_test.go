@@ -0,0 +1,172 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_CheckHealth(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	unreachable.Close()
+
+	tests := []struct {
+		name         string
+		options      []func(*Client)
+		sends        int
+		noFlush      bool
+		close        bool
+		wantProblems int
+		wantErr      error
+	}{
+		{
+			name:    "healthy",
+			options: []func(*Client){WithEndpoint(ok.URL)},
+			sends:   1,
+		},
+		{
+			name:         "delivery failed",
+			options:      []func(*Client){WithEndpoint(rejecting.URL)},
+			sends:        1,
+			wantProblems: 1,
+		},
+		{
+			name: "failed over",
+			options: []func(*Client){
+				WithEndpoint(unreachable.URL),
+				WithFallbackEndpoints(ok.URL),
+				WithFailoverThreshold(1),
+				WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+				WithMaxBatchSize(1),
+				WithWorkers(1),
+			},
+			sends:        2,
+			wantProblems: 1,
+		},
+		{
+			name: "queue above watermark",
+			options: []func(*Client){
+				WithEndpoint(ok.URL),
+				WithQueueWatermarks(2, 1),
+				WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+			},
+			sends:        2,
+			noFlush:      true,
+			wantProblems: 1,
+		},
+		{
+			name:    "closed",
+			options: []func(*Client){WithEndpoint(ok.URL)},
+			close:   true,
+			wantErr: ErrClientClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient("my-app-id", tt.options...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if !c.Healthy() {
+				t.Errorf("Healthy() of new client = false, want true")
+			}
+
+			for i := 0; i < tt.sends; i++ {
+				if err := c.SendSignal(context.Background(), "TestNamespace.healthTest", nil); err != nil {
+					t.Fatalf("Client.SendSignal() error = %v", err)
+				}
+			}
+			if !tt.noFlush {
+				if err := c.Flush(context.Background()); err != nil {
+					t.Fatalf("Client.Flush() error = %v", err)
+				}
+			}
+			if tt.close {
+				c.Close(context.Background())
+			}
+
+			err = c.CheckHealth(context.Background())
+			var healthErr *HealthError
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("CheckHealth() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantProblems == 0:
+				if err != nil {
+					t.Errorf("CheckHealth() error = %v, want nil", err)
+				}
+			case !errors.As(err, &healthErr):
+				t.Errorf("CheckHealth() error = %v, want *HealthError", err)
+			case len(healthErr.Problems) != tt.wantProblems:
+				t.Errorf("CheckHealth() problems = %q, want %d", healthErr.Problems, tt.wantProblems)
+			}
+			if got, want := c.Healthy(), err == nil; got != want {
+				t.Errorf("Healthy() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestClient_LastErrorAndSuccess(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if at, err := c.LastError(); err != nil || !at.IsZero() || !c.LastSuccess().IsZero() {
+		t.Errorf("LastError() = %s, %v, LastSuccess() = %s of new client, want zero values", at, err, c.LastSuccess())
+	}
+
+	send := func() {
+		t.Helper()
+		if _, err := c.SendSignalSync(context.Background(), "TestNamespace.lastTest", nil); err != nil && !fail.Load() {
+			t.Fatalf("Client.SendSignalSync() error = %v", err)
+		}
+	}
+
+	send()
+	success := c.LastSuccess()
+	if success.IsZero() {
+		t.Fatal("LastSuccess() is zero after successful delivery")
+	}
+
+	fail.Store(true)
+	send()
+	at, err := c.LastError()
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || at.Before(success) {
+		t.Errorf("LastError() = %s, %v, want 400 response error after %s", at, err, success)
+	}
+	if c.LastSuccess() != success {
+		t.Errorf("LastSuccess() = %s after failure, want %s", c.LastSuccess(), success)
+	}
+
+	// The error is kept, while the client is healthy again
+	fail.Store(false)
+	send()
+	if _, got := c.LastError(); got != err {
+		t.Errorf("LastError() after success = %v, want %v", got, err)
+	}
+	if !c.LastSuccess().After(at) || !c.Healthy() {
+		t.Errorf("LastSuccess() = %s, Healthy() = %v after success, want after %s and healthy", c.LastSuccess(), c.Healthy(), at)
+	}
+}
@@ -0,0 +1,88 @@
+package telemetrydeck
+
+// Hooks are optional callbacks invoked by the client during signal
+// delivery. All hooks may be called concurrently from several goroutines
+// and should return quickly, as they block delivery.
+type Hooks struct {
+	// OnResult is called for every request that received a response from
+	// the ingest endpoint, including error responses.
+	OnResult func(result IngestResult)
+
+	// OnDelivery is called for every batch of signals once its delivery
+	// succeeded or failed, after all retries, e.g. to collect metrics
+	// about the delivery of telemetry.
+	OnDelivery func(result DeliveryResult)
+
+	// OnError is called when a signal sent via SendSignal could not be
+	// delivered, either because of a permanent failure (e.g. the app ID was
+	// not accepted) or because all retries have been used up.
+	OnError func(err error)
+
+	// OnTrace is called for connection-level events of every request, like
+	// DNS resolution, connection establishment and TLS handshake, to
+	// attribute delivery performance problems to the network layer.
+	OnTrace func(event TraceEvent)
+
+	// OnBackpressure is called when the number of queued signals reaches
+	// the high watermark, e.g. because of sustained delivery problems, and
+	// again when it drops to the low watermark (see WithQueueWatermarks).
+	// Applications may use it to reduce the number of signals they send,
+	// e.g. by disabling verbose telemetry, until the pressure is relieved.
+	OnBackpressure func(event BackpressureEvent)
+
+	// OnStart is called when the delivery pipeline has been set up by
+	// NewClient, before any signals are delivered.
+	OnStart func()
+
+	// OnStop is called when the delivery pipeline has been shut down by
+	// Close, with the final delivery statistics. Signals still queued
+	// because the context passed to Close was done are counted as Queued.
+	OnStop func(stats Stats)
+
+	// OnDefaultKeyCollision is called when the payload of a signal contains
+	// one of the standard fields added by the client, like
+	// TelemetryDeck.Device.operatingSystem (see WithDefaultKeyOverrides).
+	// It's called from the goroutine sending the signal.
+	OnDefaultKeyCollision func(signalType, key string)
+
+	// OnDrop is called for every signal dropped without being delivered,
+	// with the reason: ErrQueueFull if the queue was full, an error
+	// wrapping ErrSignalRejected if the endpoint rejected the signal while
+	// accepting the rest of its batch, ErrClientDisabled if the client was
+	// disabled while the signal was queued (see Disable), or the error of
+	// the queue store.
+	// Signals dropped from the spool (see WithSpoolDir) are not reported.
+	OnDrop func(signal SignalBody, err error)
+}
+
+// WithHooks specifies callbacks to be invoked during signal delivery.
+// Hooks not set in the given struct are not called.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithHooks(hooks Hooks) func(*Client) {
+	return func(c *Client) {
+		c.hooks = hooks
+	}
+}
+
+// WithErrorChannel specifies a channel to which delivery errors are sent,
+// like those passed to the OnError hook, so that applications can observe
+// them without a logger. Sends never block: errors are dropped if the
+// channel is full, so it should be buffered. The channel is never closed
+// by the client.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithErrorChannel(ch chan<- error) func(*Client) {
+	return func(c *Client) {
+		c.errorChannel = ch
+	}
+}
+
+// Records a signal dropped without being delivered, and passes it to the
+// OnDrop hook.
+func (c *Client) drop(signal SignalBody, err error) {
+	c.stats.recordDrop()
+	if c.hooks.OnDrop != nil {
+		c.hooks.OnDrop(signal, err)
+	}
+}
@@ -0,0 +1,40 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithErrorChannel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	errs := make(chan error, 1)
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithErrorChannel(errs), WithMaxBatchSize(1))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// The second error is dropped, as the channel is full
+	for i := 0; i < 2; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.errorChannelTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	if got := len(errs); got != 1 {
+		t.Fatalf("channel holds %d errors, want 1", got)
+	}
+	var responseErr *ResponseError
+	if err := <-errs; !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("received error %v, want 400 response error", err)
+	}
+}
@@ -0,0 +1,150 @@
+package telemetrydeck
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IngestResult describes how the ingest endpoint handled one request.
+type IngestResult struct {
+	// Value of the X-Request-ID header sent with the request, to correlate
+	// it with proxy logs or TelemetryDeck support requests.
+	RequestID string
+
+	// Time it took to send the request and receive the response.
+	Duration time.Duration
+
+	// HTTP status code of the response.
+	StatusCode int
+
+	// Number of signals sent in the request.
+	Sent int
+
+	// Number of signals accepted and rejected by the endpoint. If the
+	// endpoint doesn't report these numbers, all signals are counted as
+	// accepted for successful responses, and as rejected otherwise.
+	Accepted int
+	Rejected int
+
+	// Signals of the request rejected individually, if the endpoint
+	// reports them for a successful response. The other signals of the
+	// request have been accepted.
+	RejectedSignals []RejectedSignal
+
+	// Error details reported by the endpoint, if any.
+	Errors []string
+}
+
+// RejectedSignal identifies a signal the ingest endpoint rejected, while
+// accepting the other signals of the request.
+type RejectedSignal struct {
+	// Position of the signal in the request.
+	Index int `json:"index"`
+
+	// Why the signal was rejected, if reported.
+	Reason string `json:"reason"`
+
+	// Whether sending the signal again may succeed, e.g. because it was
+	// rejected due to a temporary problem.
+	Retryable bool `json:"retryable"`
+}
+
+// DeliveryResult describes the delivery of a batch of signals, including
+// all retries, as passed to the OnDelivery hook.
+type DeliveryResult struct {
+	// Number of signals in the batch.
+	Signals int
+
+	// Size of the request body, which is sent with every attempt, and
+	// whether it is gzip-compressed.
+	Bytes      int
+	Compressed bool
+
+	// Number of requests made, including retries. Zero if the delivery
+	// failed before the first request was made.
+	Attempts int
+
+	// Time from the first attempt until the last one completed, including
+	// the time waiting between retries.
+	Duration time.Duration
+
+	// Endpoint, request ID and response status of the last attempt. The
+	// status is zero if no response was received.
+	Endpoint   string
+	RequestID  string
+	StatusCode int
+
+	// Error of the last attempt, nil if the delivery succeeded.
+	Err error
+}
+
+// The ingest API response body, as far as we evaluate it.
+type ingestResponse struct {
+	Accepted        *int             `json:"accepted"`
+	Rejected        *int             `json:"rejected"`
+	RejectedSignals []RejectedSignal `json:"rejectedSignals"`
+	Errors          []string         `json:"errors"`
+	Reason          string           `json:"reason"`
+}
+
+// Builds an IngestResult from the response to a request containing the
+// given number of signals.
+func parseIngestResponse(statusCode int, body []byte, sent int) IngestResult {
+	result := IngestResult{
+		StatusCode: statusCode,
+		Sent:       sent,
+	}
+
+	var response ingestResponse
+	if len(body) > 0 && json.Unmarshal(body, &response) == nil {
+		result.Errors = response.Errors
+		if response.Reason != "" {
+			result.Errors = append(result.Errors, response.Reason)
+		}
+		if statusCode < 300 {
+			result.RejectedSignals = validRejectedSignals(response.RejectedSignals, sent)
+			if response.Accepted == nil && response.Rejected == nil && len(result.RejectedSignals) > 0 {
+				result.Rejected = len(result.RejectedSignals)
+				result.Accepted = sent - result.Rejected
+				return result
+			}
+		}
+		if response.Accepted != nil || response.Rejected != nil {
+			if response.Accepted != nil {
+				result.Accepted = *response.Accepted
+			}
+			if response.Rejected != nil {
+				result.Rejected = *response.Rejected
+			} else {
+				result.Rejected = sent - result.Accepted
+			}
+			if response.Accepted == nil {
+				result.Accepted = sent - result.Rejected
+			}
+			return result
+		}
+	}
+
+	if statusCode >= 300 {
+		result.Rejected = sent
+	} else {
+		result.Accepted = sent
+	}
+
+	return result
+}
+
+// Returns the rejected signals with indexes within the request, without
+// duplicates.
+func validRejectedSignals(rejected []RejectedSignal, sent int) []RejectedSignal {
+	var valid []RejectedSignal
+	seen := map[int]bool{}
+	for _, r := range rejected {
+		if r.Index < 0 || r.Index >= sent || seen[r.Index] {
+			continue
+		}
+		seen[r.Index] = true
+		valid = append(valid, r)
+	}
+	return valid
+}
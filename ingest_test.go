@@ -0,0 +1,162 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_parseIngestResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		sent       int
+		want       IngestResult
+	}{
+		{
+			name:       "empty success response",
+			statusCode: http.StatusOK,
+			sent:       3,
+			want:       IngestResult{StatusCode: http.StatusOK, Sent: 3, Accepted: 3},
+		},
+		{
+			name:       "empty error response",
+			statusCode: http.StatusBadRequest,
+			sent:       3,
+			want:       IngestResult{StatusCode: http.StatusBadRequest, Sent: 3, Rejected: 3},
+		},
+		{
+			name:       "non-JSON error response",
+			statusCode: http.StatusInternalServerError,
+			body:       "internal error",
+			sent:       1,
+			want:       IngestResult{StatusCode: http.StatusInternalServerError, Sent: 1, Rejected: 1},
+		},
+		{
+			name:       "partial success",
+			statusCode: http.StatusOK,
+			body:       `{"accepted": 2, "rejected": 1, "errors": ["signal 2: invalid type"]}`,
+			sent:       3,
+			want:       IngestResult{StatusCode: http.StatusOK, Sent: 3, Accepted: 2, Rejected: 1, Errors: []string{"signal 2: invalid type"}},
+		},
+		{
+			name:       "only accepted count",
+			statusCode: http.StatusOK,
+			body:       `{"accepted": 1}`,
+			sent:       3,
+			want:       IngestResult{StatusCode: http.StatusOK, Sent: 3, Accepted: 1, Rejected: 2},
+		},
+		{
+			name:       "rejected signals",
+			statusCode: http.StatusOK,
+			body:       `{"rejectedSignals": [{"index": 2, "reason": "busy", "retryable": true}, {"index": 0}, {"index": 2}, {"index": 3}]}`,
+			sent:       3,
+			want: IngestResult{StatusCode: http.StatusOK, Sent: 3, Accepted: 1, Rejected: 2, RejectedSignals: []RejectedSignal{
+				{Index: 2, Reason: "busy", Retryable: true},
+				{Index: 0},
+			}},
+		},
+		{
+			name:       "rejected signals of error response",
+			statusCode: http.StatusBadRequest,
+			body:       `{"rejectedSignals": [{"index": 0}]}`,
+			sent:       2,
+			want:       IngestResult{StatusCode: http.StatusBadRequest, Sent: 2, Rejected: 2},
+		},
+		{
+			name:       "error reason",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"reason": "unknown app ID"}`,
+			sent:       1,
+			want:       IngestResult{StatusCode: http.StatusUnauthorized, Sent: 1, Rejected: 1, Errors: []string{"unknown app ID"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseIngestResponse(tt.statusCode, []byte(tt.body), tt.sent)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIngestResponse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_SendSignalSync(t *testing.T) {
+	var requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get("X-Request-ID")
+		_, _ = w.Write([]byte(`{"accepted": 1, "rejected": 0}`))
+	}))
+	defer server.Close()
+
+	var hookResult IngestResult
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithHooks(Hooks{OnResult: func(result IngestResult) { hookResult = result }}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	result, err := c.SendSignalSync(context.Background(), "TestNamespace.syncTest", nil)
+	if err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+
+	if requestID == "" {
+		t.Fatalf("request sent without X-Request-ID header")
+	}
+
+	if result.Duration <= 0 {
+		t.Errorf("Client.SendSignalSync() returned no duration")
+	}
+	result.Duration = 0
+	hookResult.Duration = 0
+
+	want := IngestResult{RequestID: requestID, StatusCode: http.StatusOK, Sent: 1, Accepted: 1}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Client.SendSignalSync() = %+v, want %+v", result, want)
+	}
+	if !reflect.DeepEqual(hookResult, want) {
+		t.Errorf("OnResult hook got %+v, want %+v", hookResult, want)
+	}
+}
+
+func TestClient_SendSignalSync_preconditions(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		_, _ = w.Write([]byte(`{"accepted": 1, "rejected": 0}`))
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithSampleRate(0))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	result, err := c.SendSignalSync(context.Background(), "TestNamespace.sampledOut", nil)
+	if err != nil || result.Sent != 0 {
+		t.Errorf("Client.SendSignalSync() = %+v, %v, want sampled out signal to be skipped", result, err)
+	}
+
+	if err := c.Reconfigure(WithSampleRate(1)); err != nil {
+		t.Fatalf("Client.Reconfigure() error = %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.closed", nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Client.SendSignalSync() error = %v, want %v", err, ErrClientClosed)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("server got %d requests, want 0", n)
+	}
+}
@@ -0,0 +1,122 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The format of the legacy Ingest API v1, encoding signals as JSON objects
+// with the payload as an array of "key:value" strings, and the test mode
+// flag as a string. The app ID is part of the endpoint URL.
+type v1Format struct {
+	appID string
+
+	// Whether payload fields take precedence over standard fields.
+	overrideDefaultKeys bool
+}
+
+func newV1Format(c *Client, l *liveConfig) ingestFormat {
+	return &v1Format{appID: c.appID, overrideDefaultKeys: c.overrideDefaultKeys}
+}
+
+// Replaces TelemetryDeck's v2 endpoints by the v1 endpoint of the app.
+func (f *v1Format) endpoint(configured string) string {
+	if configured == DefaultEndpoint {
+		return strings.TrimSuffix(configured, "v2/") + "v1/apps/" + url.PathEscape(f.appID) + "/signals/multiple/"
+	}
+	return configured
+}
+
+func (f *v1Format) estimateSignalSize(s SignalBody) int {
+	size := len(`{"appID":"","clientUser":"","sessionID":"","isTestMode":"false","type":"","payload":[]}`) +
+		len(s.AppID) + len(s.ClientUser) + len(s.SessionID) + len(s.Type) + len(defaultPayloadFragment)
+
+	for key, value := range s.Payload {
+		size += len(key) + len(`"",:`)
+		if v, ok := value.(string); ok {
+			size += len(v)
+		} else {
+			size += estimatedValueSize
+		}
+	}
+	for key, value := range s.stringPayload {
+		size += len(key) + len(value) + len(`"",:`)
+	}
+
+	return size
+}
+
+// Appends the JSON encoding of the signal to the buffer. Payload entries
+// are written in sorted order.
+func (f *v1Format) appendSignal(buf *bytes.Buffer, s *SignalBody) error {
+	entries := make([]string, 0, len(s.Payload)+len(s.stringPayload)+len(defaultPayload))
+	add := func(key, value string) {
+		if _, isDefault := defaultPayload[key]; isDefault && !f.overrideDefaultKeys {
+			return
+		}
+		entries = append(entries, key+":"+value)
+	}
+	for key, value := range s.stringPayload {
+		add(key, value)
+	}
+	for key, value := range s.Payload {
+		v, err := v1PayloadValue(value)
+		if err != nil {
+			return fmt.Errorf("error encoding payload key %q: %w", key, err)
+		}
+		add(key, v)
+	}
+	for _, key := range defaultPayloadKeys {
+		_, inPayload := s.Payload[key]
+		_, inStringPayload := s.stringPayload[key]
+		if f.overrideDefaultKeys && (inPayload || inStringPayload) {
+			continue
+		}
+		v, _ := v1PayloadValue(defaultPayload[key])
+		entries = append(entries, key+":"+v)
+	}
+	sort.Strings(entries)
+
+	buf.WriteString(`{"appID":`)
+	writeJSONString(buf, s.AppID)
+	buf.WriteString(`,"clientUser":`)
+	writeJSONString(buf, s.ClientUser)
+	buf.WriteString(`,"sessionID":`)
+	writeJSONString(buf, s.SessionID)
+	buf.WriteString(`,"isTestMode":"`)
+	buf.WriteString(strconv.FormatBool(s.IsTestMode))
+	buf.WriteString(`","type":`)
+	writeJSONString(buf, s.Type)
+	buf.WriteString(`,"payload":[`)
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(buf, entry)
+	}
+	buf.WriteString(`]}`)
+
+	return nil
+}
+
+// Returns the payload value as a string: strings as they are, errors as
+// described for encodeErrorValue, and everything else JSON-encoded.
+func v1PayloadValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case error:
+		return safeEncodeErrorValue(v)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := writeJSONValue(buf, value); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
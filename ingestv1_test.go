@@ -0,0 +1,138 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestClient_APIVersion1(t *testing.T) {
+	type request struct {
+		path string
+		body []map[string]interface{}
+	}
+	requests := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		req.path = r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &req.body); err != nil {
+			t.Errorf("invalid request body %s: %v", data, err)
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL+"/proxy/"), WithAPIVersion(APIVersion1), WithSessionID("my-session"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	payload := map[string]interface{}{
+		"TestNamespace.string": "a:b",
+		"TestNamespace.number": 1.5,
+		"TestNamespace.bool":   true,
+		"TestNamespace.error":  errors.New("failed"),
+	}
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.v1Test", payload); err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+
+	req := <-requests
+	if req.path != "/proxy/" {
+		t.Errorf("request path = %s, want custom endpoint unchanged", req.path)
+	}
+	want := []map[string]interface{}{{
+		"appID":      "my-app-id",
+		"clientUser": c.userIDHash,
+		"sessionID":  "my-session",
+		"isTestMode": "false",
+		"type":       "TestNamespace.v1Test",
+		"payload": []interface{}{
+			"TelemetryDeck.Device.architecture:" + runtime.GOARCH,
+			"TelemetryDeck.Device.operatingSystem:" + runtime.GOOS,
+			"TelemetryDeck.SDK.nameAndVersion:" + version,
+			"TestNamespace.bool:true",
+			"TestNamespace.error:failed (*errors.errorString)",
+			"TestNamespace.number:1.5",
+			"TestNamespace.string:a:b",
+		},
+	}}
+	if !reflect.DeepEqual(req.body, want) {
+		t.Errorf("request body = %v, want %v", req.body, want)
+	}
+}
+
+func TestClient_APIVersion1_stringSignal(t *testing.T) {
+	c, err := NewClient("my-app-id", WithAPIVersion(APIVersion1), WithDefaultKeyOverrides())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	signal := c.newSignal("TestNamespace.v1Test", nil)
+	signal.stringPayload = map[string]string{"TelemetryDeck.SDK.nameAndVersion": "wrapper/1.0"}
+	d, err := c.newDelivery([]SignalBody{signal}, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+
+	var body []struct {
+		Payload []string `json:"payload"`
+	}
+	if err := json.Unmarshal(d.body, &body); err != nil {
+		t.Fatalf("invalid request body %s: %v", d.body, err)
+	}
+	if got := body[0].Payload[2]; got != "TelemetryDeck.SDK.nameAndVersion:wrapper/1.0" {
+		t.Errorf("payload entry = %s, want overridden SDK version", got)
+	}
+	if len(body[0].Payload) != len(defaultPayload) {
+		t.Errorf("payload = %v, want only the standard fields", body[0].Payload)
+	}
+
+	signal = c.newSignal("TestNamespace.v1Test", map[string]interface{}{"TestNamespace.nan": math.NaN()})
+	if _, err := c.newDelivery([]SignalBody{signal}, ""); err == nil {
+		t.Error("Client.newDelivery() with NaN value returned no error")
+	}
+}
+
+func TestV1Format_endpoint(t *testing.T) {
+	f := &v1Format{appID: "my-app-id"}
+	tests := map[string]string{
+		DefaultEndpoint:                "https://nom.telemetrydeck.com/v1/apps/my-app-id/signals/multiple/",
+		"https://proxy.example.com/v1": "https://proxy.example.com/v1",
+	}
+	for configured, want := range tests {
+		if got := f.endpoint(configured); got != want {
+			t.Errorf("v1Format.endpoint(%s) = %s, want %s", configured, got, want)
+		}
+	}
+}
+
+func TestV1Format_estimateSignalSize(t *testing.T) {
+	c, err := NewClient("my-app-id", WithAPIVersion(APIVersion1))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.endpoint != "https://nom.telemetrydeck.com/v1/apps/my-app-id/signals/multiple/" {
+		t.Errorf("endpoint = %s, want v1 endpoint", c.endpoint)
+	}
+
+	signal := c.newSignal("TestNamespace.estimate", benchmarkPayload())
+	d, err := c.newDelivery([]SignalBody{signal}, "")
+	if err != nil {
+		t.Fatalf("Client.newDelivery() error = %v", err)
+	}
+	defer d.release()
+
+	actual := len(d.body) - 2
+	if estimate := c.estimateSignalSize(&signal); estimate < actual*8/10 || estimate > actual*12/10 {
+		t.Errorf("Client.estimateSignalSize() = %d, actual size %d", estimate, actual)
+	}
+}
@@ -0,0 +1,76 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Maximum number of distinct strings interned, so that high-cardinality
+// keys don't make the table grow without bounds.
+const maxInternedStrings = 4096
+
+// Signal types and payload keys interned by all clients.
+var internedStrings = stringTable{m: make(map[string]*internedString)}
+
+// Table of strings repeated across many signals, like signal types and
+// payload keys, along with their JSON encoding. Interning them means
+// queued signals share a single copy of each string, and the encoder
+// doesn't escape them over and over again. Entries are never removed.
+type stringTable struct {
+	mu sync.RWMutex
+	m  map[string]*internedString
+}
+
+// An interned string and its JSON encoding.
+type internedString struct {
+	s    string
+	json []byte
+}
+
+// Returns the table entry for the string, adding it if the table isn't
+// full. Returns nil if the string isn't interned.
+func (t *stringTable) lookup(s string) *internedString {
+	t.mu.RLock()
+	e, ok := t.m[s]
+	full := len(t.m) >= maxInternedStrings
+	t.mu.RUnlock()
+	if ok || full {
+		return e
+	}
+
+	var buf bytes.Buffer
+	writeJSONString(&buf, s)
+	// Cloned, so that the entry doesn't retain a larger string s is part of
+	e = &internedString{s: strings.Clone(s), json: buf.Bytes()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if existing, ok := t.m[s]; ok {
+		return existing
+	}
+	if len(t.m) >= maxInternedStrings {
+		return nil
+	}
+	t.m[e.s] = e
+	return e
+}
+
+// Returns the interned copy of the string, or the string itself if the
+// table is full.
+func (t *stringTable) intern(s string) string {
+	if e := t.lookup(s); e != nil {
+		return e.s
+	}
+	return s
+}
+
+// Writes the JSON string literal of an interned string to the buffer,
+// interning it if necessary.
+func (t *stringTable) writeJSONString(buf *bytes.Buffer, s string) {
+	if e := t.lookup(s); e != nil {
+		buf.Write(e.json)
+		return
+	}
+	writeJSONString(buf, s)
+}
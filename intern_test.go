@@ -0,0 +1,49 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func Test_stringTable(t *testing.T) {
+	table := stringTable{m: make(map[string]*internedString)}
+
+	// Built at runtime, so that the copies don't share memory
+	a := strings.Repeat("TestNamespace.intern", 1)
+	b := strings.Repeat("TestNamespace.intern", 1)
+	if unsafe.StringData(table.intern(a)) != unsafe.StringData(table.intern(b)) {
+		t.Error("intern() returned different copies of the same string")
+	}
+
+	var buf bytes.Buffer
+	table.writeJSONString(&buf, `<key "quoted">`)
+	if got, want := buf.String(), `"\u003ckey \"quoted\"\u003e"`; got != want {
+		t.Errorf("writeJSONString() = %s, want %s", got, want)
+	}
+}
+
+func Test_stringTable_full(t *testing.T) {
+	table := stringTable{m: make(map[string]*internedString)}
+	for i := 0; i < maxInternedStrings; i++ {
+		table.intern(fmt.Sprintf("key%d", i))
+	}
+
+	s := "not interned"
+	if table.lookup(s) != nil {
+		t.Errorf("lookup() of a string beyond the limit = non-nil, want nil")
+	}
+	if got := table.intern(s); got != s {
+		t.Errorf("intern() = %q, want %q", got, s)
+	}
+	if len(table.m) != maxInternedStrings {
+		t.Errorf("table has %d entries, want %d", len(table.m), maxInternedStrings)
+	}
+
+	// Strings interned before are still found
+	if table.lookup("key0") == nil {
+		t.Error("lookup() of an interned string = nil")
+	}
+}
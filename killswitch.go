@@ -0,0 +1,85 @@
+package telemetrydeck
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Environment variable which, if set to a true value as parsed by
+// strconv.ParseBool (e.g. 1), disables all clients of the process, see
+// WithKillSwitchInterval.
+const EnvDisabled = "TELEMETRYDECK_DISABLED"
+
+// State of the kill switch.
+type killSwitch struct {
+	// How often the environment variable is checked again, zero if only
+	// on creation
+	interval time.Duration
+
+	mu sync.Mutex
+	// Whether the kill switch disabled the client
+	engaged bool
+	timer   Timer
+}
+
+// WithKillSwitchInterval makes the client check the environment variable
+// TELEMETRYDECK_DISABLED again at the interval, disabling the client (see
+// Disable) while it's set and enabling it again once it's unset, e.g. to
+// silence fleet-deployed agents in an emergency without restarting them.
+// Without this option, the variable is only checked by NewClient. Clients
+// disabled via Disable aren't enabled by the kill switch, while clients
+// enabled via Enable are disabled again at the next check.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithKillSwitchInterval(interval time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.killSwitch.interval = interval
+	}
+}
+
+// Returns whether TELEMETRYDECK_DISABLED is set to a true value.
+func killSwitchSet() bool {
+	disabled, _ := strconv.ParseBool(os.Getenv(EnvDisabled))
+	return disabled
+}
+
+// Disables or enables the client according to the kill switch, and
+// schedules the next check if configured.
+func (c *Client) checkKillSwitch() {
+	k := &c.killSwitch
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if c.closed.Load() {
+		return
+	}
+
+	switch set := killSwitchSet(); {
+	case set && c.Enabled():
+		k.engaged = true
+		c.log(LogSubsystemConfig, LogLevelWarn, "telemetry disabled via "+EnvDisabled)
+		c.Disable()
+	case !set && k.engaged:
+		k.engaged = false
+		c.log(LogSubsystemConfig, LogLevelInfo, "telemetry enabled again, "+EnvDisabled+" unset")
+		c.Enable()
+	}
+
+	if k.interval > 0 {
+		if k.timer != nil {
+			k.timer.Stop()
+		}
+		k.timer = c.clock.AfterFunc(k.interval, c.checkKillSwitch)
+	}
+}
+
+// Stops checking the kill switch.
+func (c *Client) stopKillSwitch() {
+	k := &c.killSwitch
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.timer != nil {
+		k.timer.Stop()
+	}
+}
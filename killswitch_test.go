@@ -0,0 +1,79 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_killSwitch(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvDisabled, "1")
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), deliverImmediately)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.Enabled() {
+		t.Errorf("Client.Enabled() = true with %s set", EnvDisabled)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.killSwitchTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("server received %d requests, want 0", got)
+	}
+}
+
+func TestWithKillSwitchInterval(t *testing.T) {
+	t.Setenv(EnvDisabled, "")
+	c, err := NewClient("my-app-id", WithKillSwitchInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.killSwitch.timer == nil {
+		t.Fatal("NewClient() didn't schedule the next kill switch check")
+	}
+
+	// Checked directly rather than waiting for the timer
+	check := func(want bool) {
+		t.Helper()
+		c.checkKillSwitch()
+		if got := c.Enabled(); got != want {
+			t.Errorf("Client.Enabled() = %v, want %v", got, want)
+		}
+	}
+
+	check(true)
+	t.Setenv(EnvDisabled, "true")
+	check(false)
+
+	// The kill switch wins over Enable
+	c.Enable()
+	check(false)
+
+	t.Setenv(EnvDisabled, "0")
+	check(true)
+
+	// Clients disabled by the application stay disabled
+	c.Disable()
+	check(false)
+	t.Setenv(EnvDisabled, "1")
+	check(false)
+	t.Setenv(EnvDisabled, "")
+	check(false)
+
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UndeliveredError is returned by Close if signals could not be delivered,
+// either because delivering them failed or they were dropped while
+// closing, or because the context was done before they were delivered.
+type UndeliveredError struct {
+	// Number of signals that were not delivered.
+	Count int
+
+	// Error of the context, if it was done before all signals were
+	// delivered.
+	Err error
+}
+
+func (e *UndeliveredError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%d signals could not be delivered", e.Count)
+	}
+	return fmt.Sprintf("%d signals could not be delivered: %s", e.Count, e.Err)
+}
+
+func (e *UndeliveredError) Unwrap() error {
+	return e.Err
+}
+
+// Close delivers all queued signals, waiting no longer than the context
+// allows, and releases the spool. Returns an *UndeliveredError with the
+// number of signals that could not be delivered, if any, so that callers
+// can decide whether to log or persist them.
+//
+// Signals that are spooled because the endpoint is unreachable count as
+// delivered, as the next client using the spool delivers them.
+//
+// Signals sent after Close has been called are rejected with
+// ErrClientClosed. Closing the client again does nothing and returns nil.
+func (c *Client) Close(ctx context.Context) error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	c.stopKillSwitch()
+	c.stopRemoteConfig()
+	c.requeueRejectedNow()
+
+	failed := c.failedSignals.Load()
+	dropped := c.Stats().Dropped
+
+	err := c.Flush(ctx)
+	if err == nil && c.deliveryReports != nil {
+		// Reports the final counters
+		c.checkDeliveryReport(ctx, false, true)
+		err = c.Flush(ctx)
+	}
+
+	if c.spool != nil {
+		c.spool.sealActive()
+	}
+	if c.defaultPaths.store != nil {
+		c.defaultPaths.store.Close()
+	}
+	c.flushRepeatedLogs()
+	if c.hooks.OnStop != nil {
+		c.hooks.OnStop(c.Stats())
+	}
+
+	undelivered := int(c.unfinished.Load()) +
+		int(c.failedSignals.Load()-failed) +
+		c.Stats().Dropped - dropped
+	if err == nil && undelivered == 0 {
+		return nil
+	}
+	return &UndeliveredError{Count: undelivered, Err: err}
+}
+
+// Time the cleanup function returned by Provide waits for queued signals
+// to be delivered.
+const provideCloseTimeout = 10 * time.Second
+
+// Start implements the start half of a lifecycle hook, e.g. an uber/fx
+// OnStart hook. The delivery pipeline is already started by NewClient, so
+// Start only returns ErrClientClosed if the client has been closed.
+func (c *Client) Start(ctx context.Context) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	return nil
+}
+
+// Stop implements the stop half of a lifecycle hook, e.g. an uber/fx
+// OnStop hook. It closes the client, see Close.
+func (c *Client) Stop(ctx context.Context) error {
+	return c.Close(ctx)
+}
+
+// NewClientWithLifecycle works like NewClient, and passes the client's
+// Start and Stop methods to the given function, to register them with the
+// lifecycle of a dependency injection framework. With uber/fx:
+//
+//	fx.Provide(func(lc fx.Lifecycle) (*telemetrydeck.Client, error) {
+//		return telemetrydeck.NewClientWithLifecycle(appID, func(start, stop func(context.Context) error) {
+//			lc.Append(fx.Hook{OnStart: start, OnStop: stop})
+//		})
+//	})
+func NewClientWithLifecycle(appID string, appendHook func(start, stop func(context.Context) error), options ...func(*Client)) (*Client, error) {
+	client, err := NewClient(appID, options...)
+	if err != nil {
+		return nil, err
+	}
+	appendHook(client.Start, client.Stop)
+	return client, nil
+}
+
+// AppID is the TelemetryDeck app ID, as a distinct type for dependency
+// injection (see Provide).
+type AppID string
+
+// Options are options passed to NewClient, as a distinct type for
+// dependency injection (see Provide).
+type Options []func(*Client)
+
+// Provide creates a client for dependency injection frameworks like
+// google/wire, e.g. via wire.NewSet(telemetrydeck.Provide). The returned
+// cleanup function closes the client, waiting up to 10 seconds for queued
+// signals to be delivered.
+func Provide(appID AppID, options Options) (*Client, func(), error) {
+	client, err := NewClient(string(appID), options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), provideCloseTimeout)
+		defer cancel()
+		client.Close(ctx)
+	}
+	return client, cleanup, nil
+}
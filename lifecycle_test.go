@@ -0,0 +1,210 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Close(t *testing.T) {
+	// Blocks requests until the subtest is done
+	var release chan struct{}
+
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		timeout   time.Duration
+		wantCount int
+		wantErr   error
+	}{
+		{
+			name:    "delivered",
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			timeout: 5 * time.Second,
+		},
+		{
+			name: "rejected",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			timeout:   5 * time.Second,
+			wantCount: 3,
+		},
+		{
+			name: "deadline",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			},
+			timeout:   50 * time.Millisecond,
+			wantCount: 3,
+			wantErr:   context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release = make(chan struct{})
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			defer close(release)
+
+			c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			for i := 0; i < 3; i++ {
+				if err := c.SendSignal(context.Background(), "TestNamespace.closeTest", nil); err != nil {
+					t.Fatalf("Client.SendSignal() error = %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			err = c.Close(ctx)
+
+			if tt.wantCount == 0 {
+				if err != nil {
+					t.Errorf("Client.Close() error = %v, want nil", err)
+				}
+				return
+			}
+			var undelivered *UndeliveredError
+			if !errors.As(err, &undelivered) {
+				t.Fatalf("Client.Close() error = %v, want *UndeliveredError", err)
+			}
+			if undelivered.Count != tt.wantCount {
+				t.Errorf("UndeliveredError.Count = %d, want %d", undelivered.Count, tt.wantCount)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Client.Close() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_AfterClose(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+
+	if err := c.SendSignal(context.Background(), "TestNamespace.closedTest", nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Client.SendSignal() error = %v, want %v", err, ErrClientClosed)
+	}
+	if err := c.SendStringSignal(context.Background(), "TestNamespace.closedTest", nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Client.SendStringSignal() error = %v, want %v", err, ErrClientClosed)
+	}
+	if got := c.Stats().Queued; got != 0 {
+		t.Errorf("Stats().Queued = %d, want 0", got)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Errorf("second Client.Close() error = %v, want nil", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := requests.Load(); got != 0 {
+		t.Errorf("server received %d requests, want 0", got)
+	}
+}
+
+func TestClient_LifecycleHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var started, stopped int
+	var final Stats
+	hooks := Hooks{
+		OnStart: func() { started++ },
+		OnStop: func(stats Stats) {
+			stopped++
+			final = stats
+		},
+	}
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithHooks(hooks))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if started != 1 || stopped != 0 {
+		t.Errorf("OnStart called %d times, OnStop %d times after NewClient(), want 1 and 0", started, stopped)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.hookTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.Close(context.Background()); err != nil {
+			t.Fatalf("Client.Close() error = %v", err)
+		}
+	}
+	if started != 1 || stopped != 1 {
+		t.Errorf("OnStart called %d times, OnStop %d times after Close(), want 1 and 1", started, stopped)
+	}
+	if final.Requests != 1 || final.Queued != 0 {
+		t.Errorf("OnStop() stats = %+v, want 1 request and empty queue", final)
+	}
+}
+
+func TestNewClientWithLifecycle(t *testing.T) {
+	var start, stop func(context.Context) error
+	c, err := NewClientWithLifecycle("my-app-id", func(onStart, onStop func(context.Context) error) {
+		start, stop = onStart, onStop
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithLifecycle() error = %v", err)
+	}
+	if start == nil || stop == nil {
+		t.Fatal("NewClientWithLifecycle() didn't append hooks")
+	}
+
+	if err := start(context.Background()); err != nil {
+		t.Errorf("start() error = %v", err)
+	}
+	if err := stop(context.Background()); err != nil {
+		t.Errorf("stop() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.lifecycleTest", nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Client.SendSignal() after stop() error = %v, want %v", err, ErrClientClosed)
+	}
+	if err := start(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("start() after stop() error = %v, want %v", err, ErrClientClosed)
+	}
+
+	if _, err := NewClientWithLifecycle("", func(start, stop func(context.Context) error) {
+		t.Error("hooks appended for invalid client")
+	}); !errors.Is(err, ErrNoAppID) {
+		t.Errorf("NewClientWithLifecycle() error = %v, want %v", err, ErrNoAppID)
+	}
+}
+
+func TestProvide(t *testing.T) {
+	var stopped bool
+	c, cleanup, err := Provide("my-app-id", Options{WithHooks(Hooks{OnStop: func(Stats) { stopped = true }})})
+	if err != nil {
+		t.Fatalf("Provide() error = %v", err)
+	}
+	if c.appID != "my-app-id" {
+		t.Errorf("Provide() app ID = %q, want %q", c.appID, "my-app-id")
+	}
+	cleanup()
+	if !stopped {
+		t.Error("cleanup() didn't close the client")
+	}
+
+	if _, _, err := Provide("", nil); !errors.Is(err, ErrNoAppID) {
+		t.Errorf("Provide() error = %v, want %v", err, ErrNoAppID)
+	}
+}
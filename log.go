@@ -0,0 +1,200 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogLevel is the severity of a log message. The values match those of
+// the log/slog levels.
+type LogLevel int
+
+const (
+	// Every enqueued signal and every delivery attempt.
+	LogLevelDebug LogLevel = -4
+
+	// Results of delivered batches, and other events of normal operation.
+	LogLevelInfo LogLevel = 0
+
+	// Degraded operation, like dropped signals or failing over to a
+	// fallback endpoint.
+	LogLevelWarn LogLevel = 4
+
+	// Signals that could not be delivered, and other failures.
+	LogLevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// WithLogLevel specifies the minimum level of messages to log. Defaults to
+// LogLevelWarn for loggers given via WithLogger. Loggers given via
+// WithSlogLogger receive all messages their handler is enabled for, unless
+// a level is given.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithLogLevel(level LogLevel) func(*Client) {
+	return func(c *Client) {
+		c.logLevel = level
+		c.logLevelSet = true
+	}
+}
+
+// WithSlogLogger specifies a structured logger to use instead of a logger
+// given via WithLogger. Messages carry attributes like the number of
+// signals, the response status and the delivery attempt.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSlogLogger(logger *slog.Logger) func(*Client) {
+	return func(c *Client) {
+		c.slogLogger = logger
+	}
+}
+
+// Sets up the loggers messages of the subsystems are passed to, if any.
+func (c *Client) initLogging() {
+	if c.slogLogger == nil && c.logger == nil {
+		return
+	}
+	c.logSinks = make(map[LogSubsystem]*slog.Logger, len(logSubsystems))
+	for _, subsystem := range logSubsystems {
+		logger := slog.New(c.subsystemLogHandler(subsystem))
+		c.logSinks[subsystem] = logger.With("subsystem", string(subsystem))
+	}
+}
+
+// Returns whether messages of the subsystem of the given level are logged.
+func (c *Client) logEnabled(subsystem LogSubsystem, level LogLevel) bool {
+	sink := c.logSinks[subsystem]
+	return sink != nil && sink.Enabled(context.Background(), slog.Level(level))
+}
+
+// Logs the message of the subsystem with the attributes, given as
+// alternating keys and values, if the level is enabled and the message
+// isn't a repetition (see WithLogDeduplication).
+func (c *Client) log(subsystem LogSubsystem, level LogLevel, msg string, args ...interface{}) {
+	sink := c.logSinks[subsystem]
+	if sink != nil && !c.suppressLog(subsystem, level, msg, args) {
+		sink.Log(context.Background(), slog.Level(level), msg, args...)
+	}
+}
+
+// Handler dropping records below the level before passing them on.
+type levelHandler struct {
+	slog.Handler
+	level LogLevel
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.Level(h.level) && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// Handler writing records to a *log.Logger as lines of the level, the
+// message and the attributes as key=value pairs.
+type stdLogHandler struct {
+	logger *log.Logger
+	level  LogLevel
+
+	// Preformatted attributes added via WithAttrs, and the current group
+	// prefix of attribute keys.
+	attrs  string
+	prefix string
+}
+
+func (h *stdLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.Level(h.level)
+}
+
+func (h *stdLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	var b strings.Builder
+	b.WriteString(LogLevel(record.Level).String())
+	b.WriteByte(' ')
+	b.WriteString(record.Message)
+	b.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendLogAttr(&b, h.prefix, attr)
+		return true
+	})
+	h.logger.Print(b.String())
+	return nil
+}
+
+func (h *stdLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, attr := range attrs {
+		appendLogAttr(&b, h.prefix, attr)
+	}
+	h2 := *h
+	h2.attrs = b.String()
+	return &h2
+}
+
+func (h *stdLogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// Appends the attribute as " key=value", quoting the value if needed.
+func appendLogAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			appendLogAttr(b, prefix, a)
+		}
+		return
+	}
+
+	var value string
+	switch attr.Value.Kind() {
+	case slog.KindDuration:
+		value = attr.Value.Duration().String()
+	case slog.KindTime:
+		value = attr.Value.Time().Format(time.RFC3339Nano)
+	default:
+		value = fmt.Sprint(attr.Value.Any())
+	}
+	if value == "" || strings.ContainsAny(value, " \"=\t\n") {
+		value = strconv.Quote(value)
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(prefix)
+	b.WriteString(attr.Key)
+	b.WriteByte('=')
+	b.WriteString(value)
+}
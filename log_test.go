@@ -0,0 +1,188 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_LogLevel(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		options  []func(*Client)
+		want     []string
+		wantNot  []string
+	}{
+		{
+			name:     "debug",
+			endpoint: ok.URL,
+			options:  []func(*Client){WithLogLevel(LogLevelDebug)},
+			want: []string{
+				"DEBUG enqueued signal subsystem=queue type=TestNamespace.logTest\n",
+				"DEBUG delivering signals subsystem=retry count=1 endpoint=" + ok.URL + " attempt=1\n",
+				"INFO ingest result subsystem=transport count=1 status=200 duration=",
+			},
+		},
+		{
+			name:     "info",
+			endpoint: ok.URL,
+			options:  []func(*Client){WithLogLevel(LogLevelInfo)},
+			want:     []string{"INFO ingest result subsystem=transport count=1"},
+			wantNot:  []string{"DEBUG"},
+		},
+		{
+			name:     "default",
+			endpoint: ok.URL,
+			wantNot:  []string{"DEBUG", "INFO"},
+		},
+		{
+			name:     "subsystem",
+			endpoint: ok.URL,
+			options:  []func(*Client){WithSubsystemLogLevel(LogSubsystemQueue, LogLevelDebug)},
+			want:     []string{"DEBUG enqueued signal subsystem=queue type=TestNamespace.logTest\n"},
+			wantNot:  []string{"delivering signals", "INFO"},
+		},
+		{
+			name:     "error",
+			endpoint: rejecting.URL,
+			options:  []func(*Client){WithLogLevel(LogLevelError)},
+			want:     []string{"ERROR signals rejected subsystem=transport count=1 status=400 requestID="},
+			wantNot:  []string{"DEBUG", "INFO"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			options := append([]func(*Client){WithEndpoint(tt.endpoint), WithLogger(log.New(&logs, "", 0))}, tt.options...)
+			c, err := NewClient("my-app-id", options...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if err := c.SendSignal(context.Background(), "TestNamespace.logTest", nil); err != nil {
+				t.Fatalf("Client.SendSignal() error = %v", err)
+			}
+			if err := c.Flush(context.Background()); err != nil {
+				t.Fatalf("Client.Flush() error = %v", err)
+			}
+
+			output := logs.String()
+			for _, want := range tt.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("log output doesn't contain %q:\n%s", want, output)
+				}
+			}
+			for _, wantNot := range tt.wantNot {
+				if strings.Contains(output, wantNot) {
+					t.Errorf("log output contains %q:\n%s", wantNot, output)
+				}
+			}
+		})
+	}
+}
+
+func TestLogLevel_String(t *testing.T) {
+	if got := LogLevelWarn.String(); got != "WARN" {
+		t.Errorf("LogLevelWarn.String() = %q, want WARN", got)
+	}
+	if got := LogLevel(2).String(); got != "LEVEL(2)" {
+		t.Errorf("LogLevel(2).String() = %q, want LEVEL(2)", got)
+	}
+}
+
+func TestClient_SlogLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		options   []func(*Client)
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{name: "handler level", wantInfo: true},
+		{name: "client level", options: []func(*Client){WithLogLevel(LogLevelError)}},
+		{name: "debug handler", wantDebug: true, wantInfo: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			handlerLevel := slog.LevelInfo
+			if tt.wantDebug {
+				handlerLevel = slog.LevelDebug
+			}
+			logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: handlerLevel}))
+
+			options := append([]func(*Client){WithEndpoint(server.URL), WithSlogLogger(logger), WithTestMode()}, tt.options...)
+			c, err := NewClient("my-app-id", options...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if err := c.SendSignal(context.Background(), "TestNamespace.slogTest", nil); err != nil {
+				t.Fatalf("Client.SendSignal() error = %v", err)
+			}
+			if err := c.Flush(context.Background()); err != nil {
+				t.Fatalf("Client.Flush() error = %v", err)
+			}
+
+			var rejected map[string]interface{}
+			var debug, info bool
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				var record map[string]interface{}
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("invalid log line %s: %v", line, err)
+				}
+				if record["msg"] == "signals rejected" {
+					rejected = record
+				}
+				debug = debug || record["level"] == "DEBUG"
+				info = info || record["level"] == "INFO"
+			}
+
+			if rejected == nil {
+				t.Fatalf("no rejection logged:\n%s", logs.String())
+			}
+			if rejected["level"] != "ERROR" || rejected["count"] != 1.0 || rejected["status"] != 400.0 || rejected["requestBody"] == nil {
+				t.Errorf("rejection record = %v, want error with count, status and request body", rejected)
+			}
+			if debug != tt.wantDebug {
+				t.Errorf("debug messages logged = %v, want %v", debug, tt.wantDebug)
+			}
+			if info != tt.wantInfo {
+				t.Errorf("info messages logged = %v, want %v", info, tt.wantInfo)
+			}
+		})
+	}
+}
+
+func Test_stdLogHandler(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(&stdLogHandler{logger: log.New(&logs, "", 0), level: LogLevelInfo})
+
+	logger.With("client", "test").WithGroup("delivery").Info("delivered signals",
+		"count", 2, "duration", 1500*time.Millisecond, "reason", "quoted value", slog.Group("response", "status", 200))
+	logger.Debug("not logged")
+
+	want := `INFO delivered signals client=test delivery.count=2 delivery.duration=1.5s delivery.reason="quoted value" delivery.response.status=200` + "\n"
+	if got := logs.String(); got != want {
+		t.Errorf("log output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Default time within which repeated errors are logged only once, see
+// WithLogDeduplication.
+const DefaultLogDeduplicationWindow = time.Minute
+
+// WithLogDeduplication specifies the time within which a warning or error
+// repeating the message and error of a previous one isn't logged, e.g.
+// when deliveries fail over and over during an outage. Errors are compared
+// by their kind, e.g. the response status or whether the endpoint was
+// unreachable, not by details like the request ID. Instead, the number of
+// repetitions is logged at the end of the window, as in "error submitting
+// HTTP request: repeated 12 times in the last 1m0s". Defaults to
+// DefaultLogDeduplicationWindow, zero disables deduplication.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithLogDeduplication(window time.Duration) func(*Client) {
+	return func(c *Client) {
+		if window >= 0 {
+			c.logDedup.window = window
+		}
+	}
+}
+
+// Suppresses repeated log messages.
+type logDeduplicator struct {
+	window time.Duration
+
+	mu sync.Mutex
+	// Messages logged within the window, by message and error kind
+	recent map[string]*repeatedLog
+}
+
+// A message logged within the window.
+type repeatedLog struct {
+	subsystem LogSubsystem
+	level     LogLevel
+	msg       string
+
+	// Attribute the message is deduplicated by, see dedupAttr
+	attr  string
+	value interface{}
+
+	// Number of times the message was suppressed
+	repeated int
+}
+
+// Returns whether the message repeats one logged within the window and is
+// to be suppressed. Only warnings and errors with an "error" or "status"
+// attribute are deduplicated.
+func (c *Client) suppressLog(subsystem LogSubsystem, level LogLevel, msg string, args []interface{}) bool {
+	d := &c.logDedup
+	if d.window <= 0 || level < LogLevelWarn || !c.logEnabled(subsystem, level) {
+		return false
+	}
+	attr, value, ok := dedupAttr(args)
+	if !ok {
+		return false
+	}
+
+	key := fmt.Sprintf("%s\x00%s\x00%s=%v", subsystem, msg, attr, value)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if r, ok := d.recent[key]; ok {
+		r.repeated++
+		return true
+	}
+	if d.recent == nil {
+		d.recent = map[string]*repeatedLog{}
+	}
+	d.recent[key] = &repeatedLog{subsystem: subsystem, level: level, msg: msg, attr: attr, value: value}
+	c.clock.AfterFunc(d.window, func() { c.flushRepeatedLog(key) })
+	return false
+}
+
+// Ends the window of the message, logging the number of repetitions if
+// any.
+func (c *Client) flushRepeatedLog(key string) {
+	d := &c.logDedup
+	d.mu.Lock()
+	r, ok := d.recent[key]
+	delete(d.recent, key)
+	d.mu.Unlock()
+
+	if ok && r.repeated > 0 {
+		msg := fmt.Sprintf("%s: repeated %d times in the last %s", r.msg, r.repeated, d.window)
+		c.logSinks[r.subsystem].Log(context.Background(), slog.Level(r.level), msg, r.attr, r.value)
+	}
+}
+
+// Returns the attribute of the log message repetitions are detected by:
+// the kind of its "error" attribute (see errorKind), or else its "status"
+// attribute.
+func dedupAttr(args []interface{}) (attr string, value interface{}, ok bool) {
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "error" {
+			return "error", errorKind(args[i+1]), true
+		}
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] == "status" {
+			return "status", args[i+1], true
+		}
+	}
+	return "", nil, false
+}
+
+// Describes the error without details differing between repetitions of
+// the same failure, like the request IDs of delivery errors.
+func errorKind(v interface{}) string {
+	err, ok := v.(error)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	var responseErr *ResponseError
+	switch {
+	case errors.As(err, &responseErr):
+		return fmt.Sprintf("unexpected response status %d", responseErr.StatusCode)
+	case errors.Is(err, ErrUnreachable):
+		return ErrUnreachable.Error()
+	}
+	return err.Error()
+}
+
+// Ends the windows of all messages, e.g. when the client is closed.
+func (c *Client) flushRepeatedLogs() {
+	d := &c.logDedup
+	d.mu.Lock()
+	keys := make([]string, 0, len(d.recent))
+	for key := range d.recent {
+		keys = append(keys, key)
+	}
+	d.mu.Unlock()
+
+	for _, key := range keys {
+		c.flushRepeatedLog(key)
+	}
+}
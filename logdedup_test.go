@@ -0,0 +1,99 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_LogDeduplication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+
+	var buf bytes.Buffer
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithClock(clock),
+		WithLogger(log.New(&buf, "", 0)),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 1}),
+		deliverImmediately,
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	send := func() {
+		t.Helper()
+		if err := c.SendSignal(context.Background(), "TestNamespace.logTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+		if err := c.Flush(context.Background()); err != nil {
+			t.Fatalf("Client.Flush() error = %v", err)
+		}
+	}
+
+	// Every failed request has its own request ID
+	for i := 0; i < 3; i++ {
+		send()
+	}
+	if err := c.Reconfigure(WithEndpoint(unreachable.URL)); err != nil {
+		t.Fatalf("Client.Reconfigure() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		send()
+	}
+	c.flushRepeatedLogs()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"ERROR signals rejected subsystem=transport count=1 status=503",
+		"ERROR error submitting HTTP request subsystem=transport count=1 error=",
+		"ERROR error submitting HTTP request: repeated 2 times in the last 1m0s subsystem=transport error=\"endpoint unreachable\"",
+		"ERROR signals rejected: repeated 2 times in the last 1m0s subsystem=transport status=503",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("log =\n%s\nwant %d lines", buf.String(), len(want))
+	}
+	for i, line := range lines[:2] {
+		if !strings.HasPrefix(line, want[i]) {
+			t.Errorf("log line %d = %s, want prefix %s", i, line, want[i])
+		}
+	}
+	// Repetitions are logged in no particular order
+	sort.Strings(lines[2:])
+	if got := strings.Join(lines[2:], "\n"); got != strings.Join(want[2:], "\n") {
+		t.Errorf("repetitions logged =\n%s\nwant\n%s", got, strings.Join(want[2:], "\n"))
+	}
+
+	// The window ended, so the error is logged again
+	buf.Reset()
+	send()
+	if buf.Len() == 0 {
+		t.Error("error not logged after the window ended")
+	}
+}
+
+func TestClient_LogDeduplication_disabled(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := NewClient("my-app-id", WithLogger(log.New(&buf, "", 0)), WithLogDeduplication(0))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.log(LogSubsystemTransport, LogLevelError, "error submitting HTTP request", "error", errors.New("connection refused"))
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("logged lines = %d, want 3", n)
+	}
+}
@@ -0,0 +1,19 @@
+package telemetrydeck
+
+import (
+	"log/slog"
+
+	"github.com/go-logr/logr"
+)
+
+// WithLogrLogger specifies a logr.Logger to log to, like WithSlogLogger.
+// Debug messages are logged with verbosity V(4), info and warning messages
+// with V(0), and errors via Error. The verbosity of the logger decides
+// which messages are logged, unless a level is given via WithLogLevel.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithLogrLogger(logger logr.Logger) func(*Client) {
+	return func(c *Client) {
+		c.slogLogger = slog.New(logr.ToSlogHandler(logger))
+	}
+}
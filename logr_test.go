@@ -0,0 +1,55 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+)
+
+func TestClient_LogrLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		verbosity int
+		wantDebug bool
+	}{
+		{name: "V(0)"},
+		{name: "V(4)", verbosity: 4, wantDebug: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			logger := funcr.New(func(prefix, args string) {
+				lines = append(lines, args)
+			}, funcr.Options{Verbosity: tt.verbosity})
+
+			c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithLogrLogger(logger), WithWorkers(1))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if err := c.SendSignal(context.Background(), "TestNamespace.logrTest", nil); err != nil {
+				t.Fatalf("Client.SendSignal() error = %v", err)
+			}
+			if err := c.Flush(context.Background()); err != nil {
+				t.Fatalf("Client.Flush() error = %v", err)
+			}
+
+			output := strings.Join(lines, "\n")
+			if !strings.Contains(output, `"msg"="signals rejected" "error"=null "subsystem"="transport" "count"=1 "status"=400`) {
+				t.Errorf("log output doesn't contain rejection:\n%s", output)
+			}
+			if got := strings.Contains(output, `"msg"="enqueued signal"`); got != tt.wantDebug {
+				t.Errorf("debug messages logged = %v, want %v:\n%s", got, tt.wantDebug, output)
+			}
+		})
+	}
+}
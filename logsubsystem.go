@@ -0,0 +1,84 @@
+package telemetrydeck
+
+import "log/slog"
+
+// LogSubsystem is the part of the client a log message originates from. It
+// is added to every message as the "subsystem" attribute, and allows to
+// change the level of messages of a single subsystem via
+// WithSubsystemLogLevel.
+type LogSubsystem string
+
+const (
+	// Enqueueing signals, checking their payloads and batching them.
+	LogSubsystemQueue LogSubsystem = "queue"
+
+	// Requests to the ingest endpoint and their results, including
+	// failing over to fallback endpoints.
+	LogSubsystemTransport LogSubsystem = "transport"
+
+	// Delivery attempts and signals rejected by the endpoint.
+	LogSubsystemRetry LogSubsystem = "retry"
+
+	// Sessions, and signals sent by the client itself, like session
+	// starts and delivery reports.
+	LogSubsystemSession LogSubsystem = "session"
+
+	// Persisting undelivered signals and delivering them later, see
+	// WithSpoolDir.
+	LogSubsystemSpool LogSubsystem = "spool"
+
+	// Changes of the configuration, e.g. via the kill switch, the remote
+	// config or Reconfigure.
+	LogSubsystemConfig LogSubsystem = "config"
+)
+
+// All subsystems, each of which gets its own logger.
+var logSubsystems = []LogSubsystem{
+	LogSubsystemQueue,
+	LogSubsystemTransport,
+	LogSubsystemRetry,
+	LogSubsystemSession,
+	LogSubsystemSpool,
+	LogSubsystemConfig,
+}
+
+// WithSubsystemLogLevel specifies the minimum level of messages of the
+// subsystem to log, overriding WithLogLevel, e.g. to troubleshoot
+// deliveries with LogLevelDebug for LogSubsystemTransport without drowning
+// the application's logs in messages of other subsystems. Loggers given
+// via WithSlogLogger or WithLogrLogger still only receive messages their
+// handler is enabled for.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSubsystemLogLevel(subsystem LogSubsystem, level LogLevel) func(*Client) {
+	return func(c *Client) {
+		if c.subsystemLogLevels == nil {
+			c.subsystemLogLevels = map[LogSubsystem]LogLevel{}
+		}
+		c.subsystemLogLevels[subsystem] = level
+	}
+}
+
+// Returns the handler of the logger messages of the subsystem are passed
+// to, applying the level of the subsystem.
+func (c *Client) subsystemLogHandler(subsystem LogSubsystem) slog.Handler {
+	level, levelSet := c.logLevel, c.logLevelSet
+	if l, ok := c.subsystemLogLevels[subsystem]; ok {
+		level, levelSet = l, true
+	}
+
+	switch {
+	case c.slogLogger != nil:
+		handler := c.slogLogger.Handler()
+		if levelSet {
+			handler = &levelHandler{Handler: handler, level: level}
+		}
+		return handler
+	case c.logger != nil:
+		if !levelSet {
+			level = LogLevelWarn
+		}
+		return &stdLogHandler{logger: c.logger, level: level}
+	}
+	return nil
+}
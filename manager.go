@@ -0,0 +1,196 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Manager holds clients for several TelemetryDeck apps, e.g. one per
+// product, behind a single API sending signals to the app registered under
+// a key, or to the app their type is routed to (see SetRoutes). The
+// clients share an HTTP client and a limit on concurrent deliveries, so
+// that resource usage doesn't grow with the number of apps. Safe for
+// concurrent use.
+type Manager struct {
+	// Options applied to every client before its own ones
+	options []func(*Client)
+
+	httpClient *http.Client
+	pool       *workerPool
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	routes  []Route
+}
+
+// NewManager returns a manager without clients. The options are applied
+// to every client added, before the options passed to Add. Transport
+// settings (like WithTimeout) and the number of workers (WithWorkers)
+// given here apply to all clients together, transport settings passed to
+// Add have no effect.
+func NewManager(options ...func(*Client)) *Manager {
+	shared := &Client{maxWorkers: defaultWorkers}
+	for _, o := range options {
+		o(shared)
+	}
+
+	m := &Manager{
+		options:    options,
+		httpClient: shared.transport.newHTTPClient(),
+		clients:    map[string]*Client{},
+	}
+	m.pool = &workerPool{max: int32(shared.maxWorkers), onStopped: m.startWaitingWorkers}
+	return m
+}
+
+// Add creates a client for the app and registers it under the key, e.g.
+// the name of a product. Returns an error if NewClient fails or a client
+// has been registered under the key already.
+func (m *Manager) Add(key, appID string, options ...func(*Client)) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[key]; ok {
+		return nil, fmt.Errorf("app key %s added twice", key)
+	}
+
+	all := make([]func(*Client), 0, len(m.options)+len(options)+1)
+	all = append(all, m.options...)
+	all = append(all, options...)
+	all = append(all, func(c *Client) {
+		c.httpClient = m.httpClient
+		c.workerPool = m.pool
+	})
+	client, err := NewClient(appID, all...)
+	if err != nil {
+		return nil, err
+	}
+	m.clients[key] = client
+	return client, nil
+}
+
+// Client returns the client registered under the key, if any.
+func (m *Manager) Client(key string) (*Client, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	client, ok := m.clients[key]
+	return client, ok
+}
+
+// Keys returns the keys of all clients, sorted.
+func (m *Manager) Keys() []string {
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.clients))
+	for key := range m.clients {
+		keys = append(keys, key)
+	}
+	m.mu.RUnlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+// SendSignal sends the signal with the client registered under the key,
+// see Client.SendSignal. Returns an error wrapping ErrUnknownAppKey if
+// there is no such client.
+func (m *Manager) SendSignal(ctx context.Context, key, signalType string, payload map[string]interface{}) error {
+	client, ok := m.Client(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAppKey, key)
+	}
+	return client.SendSignal(ctx, signalType, payload)
+}
+
+// SendStringSignal sends the signal with the client registered under the
+// key, see Client.SendStringSignal. Returns an error wrapping
+// ErrUnknownAppKey if there is no such client.
+func (m *Manager) SendStringSignal(ctx context.Context, key, signalType string, payload map[string]string) error {
+	client, ok := m.Client(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAppKey, key)
+	}
+	return client.SendStringSignal(ctx, signalType, payload)
+}
+
+// Flush waits until the signals queued by all clients have been delivered,
+// see Client.Flush. Returns the errors of all clients.
+func (m *Manager) Flush(ctx context.Context) error {
+	return m.each(func(c *Client) error { return c.Flush(ctx) })
+}
+
+// Close closes all clients, see Client.Close. Returns the errors of all
+// clients, e.g. *UndeliveredError.
+func (m *Manager) Close(ctx context.Context) error {
+	return m.each(func(c *Client) error { return c.Close(ctx) })
+}
+
+// Calls f for all clients, in the order of their keys, and returns the
+// joined errors, annotated with the keys.
+func (m *Manager) each(f func(c *Client) error) error {
+	var errs []error
+	for _, key := range m.Keys() {
+		client, _ := m.Client(key)
+		if err := f(client); err != nil {
+			errs = append(errs, fmt.Errorf("app key %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Starts workers for clients with queued signals but no worker, which may
+// have been unable to start one while the pool was exhausted.
+func (m *Manager) startWaitingWorkers() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, client := range m.clients {
+		if client.workers.Load() == 0 && client.store.Len() > 0 {
+			client.startWorker()
+		}
+	}
+}
+
+// Limits the number of delivery workers of several clients. The methods
+// may be called on a nil pool, which doesn't limit workers.
+type workerPool struct {
+	max    int32
+	active atomic.Int32
+
+	// Called after a worker stopped, to start workers of clients that
+	// couldn't start one.
+	onStopped func()
+}
+
+// Reserves a worker slot. Returns false if the pool is exhausted.
+func (p *workerPool) acquire() bool {
+	if p == nil {
+		return true
+	}
+	for {
+		n := p.active.Load()
+		if n >= p.max {
+			return false
+		}
+		if p.active.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// Returns a slot that was reserved without starting a worker.
+func (p *workerPool) release() {
+	if p != nil {
+		p.active.Add(-1)
+	}
+}
+
+// Returns the slot of a worker that stopped.
+func (p *workerPool) workerStopped() {
+	if p != nil {
+		p.active.Add(-1)
+		p.onStopped()
+	}
+}
@@ -0,0 +1,109 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	var active, maxActive atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			peak := maxActive.Load()
+			if n <= peak || maxActive.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		for _, signal := range signals {
+			received[signal.AppID]++
+		}
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	m := NewManager(WithEndpoint(server.URL), WithWorkers(1), WithMaxBatchSize(1), deliverImmediately)
+	a, err := m.Add("a", "app-a")
+	if err != nil {
+		t.Fatalf("Manager.Add() error = %v", err)
+	}
+	b, err := m.Add("b", "app-b", WithWorkers(4))
+	if err != nil {
+		t.Fatalf("Manager.Add() error = %v", err)
+	}
+	if a.httpClient != b.httpClient {
+		t.Error("clients don't share the HTTP client")
+	}
+	if _, err := m.Add("a", "app-c"); err == nil {
+		t.Error("Manager.Add() succeeded for a duplicate key")
+	}
+
+	for i := 0; i < 5; i++ {
+		for _, key := range []string{"a", "b"} {
+			if err := m.SendSignal(context.Background(), key, "TestNamespace.managerTest", nil); err != nil {
+				t.Fatalf("Manager.SendSignal() error = %v", err)
+			}
+		}
+	}
+	if err := m.SendStringSignal(context.Background(), "b", "TestNamespace.managerTest", nil); err != nil {
+		t.Fatalf("Manager.SendStringSignal() error = %v", err)
+	}
+	if err := m.SendSignal(context.Background(), "c", "TestNamespace.managerTest", nil); !errors.Is(err, ErrUnknownAppKey) {
+		t.Errorf("Manager.SendSignal() error = %v, want ErrUnknownAppKey", err)
+	}
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Manager.Close() error = %v", err)
+	}
+
+	if want := map[string]int{"app-a": 5, "app-b": 6}; !reflect.DeepEqual(received, want) {
+		t.Errorf("received signals = %v, want %v", received, want)
+	}
+	if got := maxActive.Load(); got != 1 {
+		t.Errorf("concurrent requests = %d, want 1", got)
+	}
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Manager.Keys() = %v, want [a b]", got)
+	}
+}
+
+func TestManager_errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	m := NewManager(WithEndpoint(server.URL), deliverImmediately)
+	if _, err := m.Add("a", ""); !errors.Is(err, ErrNoAppID) {
+		t.Errorf("Manager.Add() error = %v, want ErrNoAppID", err)
+	}
+	if _, err := m.Add("a", "app-a"); err != nil {
+		t.Fatalf("Manager.Add() error = %v", err)
+	}
+	if err := m.SendSignal(context.Background(), "a", "TestNamespace.managerTest", nil); err != nil {
+		t.Fatalf("Manager.SendSignal() error = %v", err)
+	}
+
+	err := m.Close(context.Background())
+	var undelivered *UndeliveredError
+	if !errors.As(err, &undelivered) || undelivered.Count != 1 {
+		t.Errorf("Manager.Close() error = %v, want 1 undelivered signal", err)
+	}
+}
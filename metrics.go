@@ -0,0 +1,97 @@
+package telemetrydeck
+
+import (
+	"expvar"
+	"sync"
+)
+
+// Names of the metrics reported by the client.
+const (
+	// Counter of requests sent to the ingest endpoint
+	MetricRequests = "requests"
+	// Counter of requests that were retries of failed requests
+	MetricRetries = "retries"
+	// Counter of deliveries that failed permanently or after all retries
+	MetricFailures = "failures"
+	// Counter of signals delivered successfully
+	MetricDelivered = "delivered"
+	// Counter of signals dropped without attempting delivery
+	MetricDropped = "dropped"
+	// Gauge of the number of signals waiting in the queue
+	MetricQueueDepth = "queue_depth"
+	// Gauge of the highest number of signals waiting in the queue so far
+	MetricQueueDepthPeak = "queue_depth_peak"
+	// Gauge set to 1 when the queue reaches the high watermark, and to 0
+	// when it drops to the low watermark afterwards
+	MetricBackpressure = "backpressure"
+	// Counter of watermark crossings, in either direction
+	MetricWatermarkCrossings = "watermark_crossings"
+)
+
+// Metrics receives the values of the client's internal counters and gauges
+// (see the Metric constants for their names), so that they can be exported
+// to a monitoring system. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Add increments the counter with the given name by delta.
+	Add(name string, delta int64)
+
+	// Set sets the gauge with the given name to value.
+	Set(name string, value int64)
+}
+
+// Metrics published via the expvar package, shared by all clients not
+// configured otherwise.
+var defaultMetrics = sync.OnceValue(func() Metrics {
+	return NewExpvarMetrics("telemetrydeck")
+})
+
+// WithMetrics specifies where the client reports its internal metrics.
+// By default, metrics are published via the expvar package under the name
+// "telemetrydeck", shared by all clients: counters are summed over the
+// clients, while gauges hold the value most recently set by any of them.
+// To tell the gauges of several clients (e.g. of a Manager) apart, give
+// each client its own metrics, like NewExpvarMetrics with distinct names.
+// Pass nil to disable metrics.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMetrics(metrics Metrics) func(*Client) {
+	return func(c *Client) {
+		c.metrics = metrics
+	}
+}
+
+// Serializes looking up and publishing maps in NewExpvarMetrics, as
+// publishing a name twice panics.
+var expvarMu sync.Mutex
+
+// Metrics published as an expvar.Map.
+type expvarMetrics struct {
+	m *expvar.Map
+}
+
+// NewExpvarMetrics returns Metrics published via the expvar package as a map
+// with the given name. If a map with that name has been published already,
+// it's reused. If the name is taken by another variable, the metrics are
+// not published.
+func NewExpvarMetrics(name string) Metrics {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	switch v := expvar.Get(name).(type) {
+	case *expvar.Map:
+		return expvarMetrics{m: v}
+	case nil:
+		return expvarMetrics{m: expvar.NewMap(name)}
+	default:
+		return expvarMetrics{m: new(expvar.Map).Init()}
+	}
+}
+
+func (e expvarMetrics) Add(name string, delta int64) {
+	e.m.Add(name, delta)
+}
+
+func (e expvarMetrics) Set(name string, value int64) {
+	// Adding zero creates the variable if needed
+	e.m.Add(name, 0)
+	e.m.Get(name).(*expvar.Int).Set(value)
+}
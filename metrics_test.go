@@ -0,0 +1,169 @@
+package telemetrydeck
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Metrics recorded in memory.
+type testMetrics struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+func (m *testMetrics) Add(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] += delta
+}
+
+func (m *testMetrics) Set(name string, value int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = value
+}
+
+func (m *testMetrics) get(name string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[name]
+}
+
+func TestWithMetrics(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusOK, http.StatusUnauthorized}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.WriteHeader(statuses[0])
+		statuses = statuses[1:]
+	}))
+	defer server.Close()
+
+	metrics := &testMetrics{values: map[string]int64{}}
+	policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithMetrics(metrics), WithRetryPolicy(policy), WithWorkers(1))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 2; i++ {
+		if err := c.SendSignal(ctx, "TestNamespace.metricsTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+		if err := c.Flush(ctx); err != nil {
+			t.Fatalf("Client.Flush() error = %v", err)
+		}
+	}
+
+	want := map[string]int64{
+		MetricRequests:       3,
+		MetricRetries:        1,
+		MetricFailures:       1,
+		MetricDropped:        0,
+		MetricQueueDepth:     0,
+		MetricQueueDepthPeak: 1,
+	}
+	for name, value := range want {
+		if got := metrics.get(name); got != value {
+			t.Errorf("metric %s = %d, want %d", name, got, value)
+		}
+	}
+}
+
+func TestClient_QueueDepthMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	metrics := &testMetrics{values: map[string]int64{}}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithMetrics(metrics),
+		WithQueueWatermarks(2, 1),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.depthTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	checkMetrics := func(when string, want map[string]int64) {
+		t.Helper()
+		for name, value := range want {
+			if got := metrics.get(name); got != value {
+				t.Errorf("%s: metric %s = %d, want %d", when, name, got, value)
+			}
+		}
+	}
+	checkMetrics("before Flush", map[string]int64{
+		MetricQueueDepth:         3,
+		MetricQueueDepthPeak:     3,
+		MetricBackpressure:       1,
+		MetricWatermarkCrossings: 1,
+	})
+
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	checkMetrics("after Flush", map[string]int64{
+		MetricQueueDepth:         0,
+		MetricQueueDepthPeak:     3,
+		MetricBackpressure:       0,
+		MetricWatermarkCrossings: 2,
+	})
+	if got := c.Stats().PeakQueued; got != 3 {
+		t.Errorf("Stats().PeakQueued = %d, want 3", got)
+	}
+}
+
+func TestNewExpvarMetrics(t *testing.T) {
+	m := NewExpvarMetrics("telemetrydeck_test")
+	m.Add(MetricRequests, 2)
+	m.Set(MetricQueueDepth, 5)
+
+	// Published maps are reused
+	NewExpvarMetrics("telemetrydeck_test").Add(MetricRequests, 1)
+
+	published := expvar.Get("telemetrydeck_test").(*expvar.Map)
+	if got := published.Get(MetricRequests).String(); got != "3" {
+		t.Errorf("%s = %s, want 3", MetricRequests, got)
+	}
+	if got := published.Get(MetricQueueDepth).String(); got != "5" {
+		t.Errorf("%s = %s, want 5", MetricQueueDepth, got)
+	}
+
+	// Names taken by other variables don't panic
+	expvar.NewString("telemetrydeck_test_string")
+	NewExpvarMetrics("telemetrydeck_test_string").Add(MetricRequests, 1)
+}
+
+func TestNewExpvarMetrics_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			NewExpvarMetrics("telemetrydeck_concurrent_test").Add(MetricRequests, 1)
+		}()
+	}
+	wg.Wait()
+
+	m, ok := expvar.Get("telemetrydeck_concurrent_test").(*expvar.Map)
+	if !ok {
+		t.Fatal("metrics not published")
+	}
+	if got := m.Get(MetricRequests).String(); got != "10" {
+		t.Errorf("%s = %s, want 10", MetricRequests, got)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package telemetrydeck
+
+const raceEnabled = false
@@ -0,0 +1,64 @@
+package telemetrydeck
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithPayloadNormalization makes the client normalize payload string
+// values to Unicode NFC and strip control characters from them before
+// sending, so that equivalent values entered on different platforms, e.g.
+// "é" as one or two code points, are grouped together in the dashboard.
+// Tabs and line breaks are replaced by spaces instead, so that words don't
+// run together.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithPayloadNormalization() func(*Client) {
+	return func(c *Client) {
+		c.normalizePayload = true
+	}
+}
+
+// Returns the payload with string values normalized, which is a copy if
+// any value was changed.
+func normalizeValues[V any](payload map[string]V) map[string]V {
+	return replaceStringValues(payload, func(s string) (string, bool) {
+		if isNormalized(s) {
+			return s, false
+		}
+		return normalizeValue(s), true
+	})
+}
+
+// Returns whether the value is in NFC and free of control characters.
+func isNormalized(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] == 0x7f {
+			return false
+		}
+		if s[i] >= utf8.RuneSelf {
+			// Checks the rest for C1 control characters, and the whole
+			// value, as combining characters compose with the preceding
+			// ones
+			return strings.IndexFunc(s[i:], unicode.IsControl) < 0 && norm.NFC.IsNormalString(s)
+		}
+	}
+	return true
+}
+
+// Normalizes the value to NFC, strips control characters and replaces tabs
+// and line breaks by spaces.
+func normalizeValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, norm.NFC.String(s))
+}
@@ -0,0 +1,35 @@
+package telemetrydeck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeValues(t *testing.T) {
+	payload := map[string]interface{}{
+		"TestNamespace.decomposed": "Cafe\u0301",
+		"TestNamespace.control":    "a\x00b\u0085c",
+		"TestNamespace.lines":      "first\nsecond\tthird",
+		"TestNamespace.normal":     "Caf\u00e9",
+		"TestNamespace.number":     1,
+	}
+	got := normalizeValues(payload)
+	want := map[string]interface{}{
+		"TestNamespace.decomposed": "Caf\u00e9",
+		"TestNamespace.control":    "abc",
+		"TestNamespace.lines":      "first second third",
+		"TestNamespace.normal":     "Caf\u00e9",
+		"TestNamespace.number":     1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeValues() = %+q, want %+q", got, want)
+	}
+	if payload["TestNamespace.decomposed"] != "Cafe\u0301" {
+		t.Error("caller's payload was modified")
+	}
+
+	normal := map[string]string{"TestNamespace.normal": "Caf\u00e9"}
+	if got := normalizeValues(normal); reflect.ValueOf(got).Pointer() != reflect.ValueOf(normal).Pointer() {
+		t.Error("normalizeValues() copied a normalized payload")
+	}
+}
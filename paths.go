@@ -0,0 +1,202 @@
+package telemetrydeck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// PathResolver resolves the directories in which clients persist data by
+// default, see WithDefaultSpoolDir, WithDefaultDiskQueue and
+// WithDefaultStateFile.
+type PathResolver interface {
+	// CacheDir returns the directory for data that may be lost without
+	// harm, like signals waiting for delivery.
+	CacheDir() (string, error)
+
+	// StateDir returns the directory for data that should be kept, like
+	// the state file.
+	StateDir() (string, error)
+}
+
+// SystemPaths is a PathResolver following the conventions of the operating
+// system: on Linux and other Unix systems, the XDG base directories
+// ($XDG_CACHE_HOME or ~/.cache, and $XDG_STATE_HOME or ~/.local/state), on
+// macOS ~/Library/Caches and ~/Library/Application Support, and on Windows
+// %LOCALAPPDATA%, which isn't synced to other machines unlike %APPDATA%.
+// The directories returned are subdirectories named after the program.
+type SystemPaths struct {
+	// Name of the subdirectories, e.g. the name of the program. Defaults
+	// to "telemetrydeck".
+	Name string
+
+	// Base directories used instead of the ones of the operating system,
+	// if set, e.g. given via command-line flags.
+	CacheHome string
+	StateHome string
+}
+
+// Resolver used if none is given via WithPathResolver.
+var defaultPathResolver PathResolver = SystemPaths{}
+
+// Cache and state directories, see SystemPaths.
+const (
+	cacheDir = iota
+	stateDir
+)
+
+// CacheDir returns the directory for data that may be lost without harm.
+func (p SystemPaths) CacheDir() (string, error) {
+	return p.dir(cacheDir, runtime.GOOS, os.Getenv)
+}
+
+// StateDir returns the directory for data that should be kept.
+func (p SystemPaths) StateDir() (string, error) {
+	return p.dir(stateDir, runtime.GOOS, os.Getenv)
+}
+
+// Returns the cache or state directory on the operating system, given the
+// environment variables via getenv.
+func (p SystemPaths) dir(kind int, goos string, getenv func(string) string) (string, error) {
+	name := p.Name
+	if name == "" {
+		name = "telemetrydeck"
+	}
+	base := p.CacheHome
+	if kind == stateDir {
+		base = p.StateHome
+	}
+	if base != "" {
+		return filepath.Join(base, name), nil
+	}
+
+	switch goos {
+	case "windows":
+		base = getenv("LOCALAPPDATA")
+		if base == "" {
+			return "", errors.New("%LOCALAPPDATA% is not defined")
+		}
+		if kind == cacheDir {
+			return filepath.Join(base, name, "cache"), nil
+		}
+		return filepath.Join(base, name), nil
+	case "darwin", "ios":
+		home := getenv("HOME")
+		if home == "" {
+			return "", errors.New("$HOME is not defined")
+		}
+		if kind == cacheDir {
+			return filepath.Join(home, "Library", "Caches", name), nil
+		}
+		return filepath.Join(home, "Library", "Application Support", name), nil
+	default:
+		xdg, fallback := "XDG_CACHE_HOME", ".cache"
+		if kind == stateDir {
+			xdg, fallback = "XDG_STATE_HOME", filepath.Join(".local", "state")
+		}
+		// Relative paths are invalid according to the XDG specification
+		if base = getenv(xdg); filepath.IsAbs(base) {
+			return filepath.Join(base, name), nil
+		}
+		home := getenv("HOME")
+		if home == "" {
+			return "", fmt.Errorf("neither $%s nor $HOME are defined", xdg)
+		}
+		return filepath.Join(home, fallback, name), nil
+	}
+}
+
+// WithPathResolver specifies how the directories in which data is
+// persisted by default are resolved. Defaults to SystemPaths{}.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithPathResolver(r PathResolver) func(*Client) {
+	return func(c *Client) {
+		c.pathResolver = r
+	}
+}
+
+// WithDefaultSpoolDir is like WithSpoolDir, with a directory for the app
+// in the cache directory (see WithPathResolver), so that programs don't
+// need to know where caches belong on each operating system. NewClient
+// returns an error if the directory can't be resolved.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDefaultSpoolDir() func(*Client) {
+	return func(c *Client) {
+		c.defaultPaths.spoolDir = true
+	}
+}
+
+// WithDefaultDiskQueue makes the client keep signals waiting for delivery
+// in a store returned by NewDiskQueueStore, with the given capacity, in a
+// directory for the app in the cache directory (see WithPathResolver).
+// NewClient returns an error if the directory can't be resolved or the
+// store can't be opened.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDefaultDiskQueue(capacity int) func(*Client) {
+	return func(c *Client) {
+		c.defaultPaths.diskQueue = true
+		c.defaultPaths.diskQueueCapacity = capacity
+	}
+}
+
+// WithDefaultStateFile is like WithStateFile, with a file in the state
+// directory (see WithPathResolver) shared by all apps. NewClient returns an
+// error if the directory can't be resolved.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDefaultStateFile() func(*Client) {
+	return func(c *Client) {
+		c.defaultPaths.stateFile = true
+	}
+}
+
+// Persistence features using default paths, see WithPathResolver.
+type defaultPaths struct {
+	spoolDir          bool
+	stateFile         bool
+	diskQueue         bool
+	diskQueueCapacity int
+
+	// Store opened for WithDefaultDiskQueue, closed with the client
+	store *DiskQueueStore
+}
+
+// Sets the paths of the persistence features using default paths. Explicit
+// paths take precedence.
+func (c *Client) resolveDefaultPaths() error {
+	r := c.pathResolver
+	if r == nil {
+		r = defaultPathResolver
+	}
+	if (c.defaultPaths.spoolDir && c.spoolDir == "") || (c.defaultPaths.diskQueue && c.store == nil) {
+		dir, err := r.CacheDir()
+		if err != nil {
+			return fmt.Errorf("resolving cache directory: %w", err)
+		}
+		dir = filepath.Join(dir, c.appID)
+		if c.defaultPaths.spoolDir && c.spoolDir == "" {
+			c.spoolDir = filepath.Join(dir, "spool")
+		}
+		if c.defaultPaths.diskQueue && c.store == nil {
+			store, err := NewDiskQueueStore(filepath.Join(dir, "queue"), c.defaultPaths.diskQueueCapacity)
+			if err != nil {
+				return err
+			}
+			c.store = store
+			c.defaultPaths.store = store
+		}
+	}
+	if c.defaultPaths.stateFile && c.stateFile == "" {
+		dir, err := r.StateDir()
+		if err != nil {
+			return fmt.Errorf("resolving state directory: %w", err)
+		}
+		c.stateFile = filepath.Join(dir, "state.json")
+	}
+	return nil
+}
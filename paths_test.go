@@ -0,0 +1,148 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSystemPaths_dir(t *testing.T) {
+	env := map[string]string{
+		"HOME":         "/home/user",
+		"LOCALAPPDATA": `C:\Users\user\AppData\Local`,
+	}
+	tests := []struct {
+		name      string
+		paths     SystemPaths
+		goos      string
+		env       map[string]string
+		wantCache string
+		wantState string
+		wantErr   bool
+	}{
+		{
+			name:      "linux",
+			goos:      "linux",
+			env:       env,
+			wantCache: "/home/user/.cache/telemetrydeck",
+			wantState: "/home/user/.local/state/telemetrydeck",
+		},
+		{
+			name:      "linux with XDG",
+			paths:     SystemPaths{Name: "kubectl-gs"},
+			goos:      "linux",
+			env:       map[string]string{"XDG_CACHE_HOME": "/cache", "XDG_STATE_HOME": "/state"},
+			wantCache: "/cache/kubectl-gs",
+			wantState: "/state/kubectl-gs",
+		},
+		{
+			name:      "relative XDG ignored",
+			goos:      "freebsd",
+			env:       map[string]string{"HOME": "/home/user", "XDG_CACHE_HOME": "cache"},
+			wantCache: "/home/user/.cache/telemetrydeck",
+			wantState: "/home/user/.local/state/telemetrydeck",
+		},
+		{
+			name:      "darwin",
+			goos:      "darwin",
+			env:       env,
+			wantCache: "/home/user/Library/Caches/telemetrydeck",
+			wantState: "/home/user/Library/Application Support/telemetrydeck",
+		},
+		{
+			name:      "windows",
+			goos:      "windows",
+			env:       env,
+			wantCache: filepath.Join(`C:\Users\user\AppData\Local`, "telemetrydeck", "cache"),
+			wantState: filepath.Join(`C:\Users\user\AppData\Local`, "telemetrydeck"),
+		},
+		{
+			name:      "overrides",
+			paths:     SystemPaths{CacheHome: "/var/cache", StateHome: "/var/lib"},
+			goos:      "windows",
+			wantCache: "/var/cache/telemetrydeck",
+			wantState: "/var/lib/telemetrydeck",
+		},
+		{
+			name:    "no home",
+			goos:    "linux",
+			wantErr: true,
+		},
+		{
+			name:    "no LOCALAPPDATA",
+			goos:    "windows",
+			env:     map[string]string{"HOME": "/home/user"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			cache, err := tt.paths.dir(cacheDir, tt.goos, getenv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dir(cacheDir) error = %v, wantErr %v", err, tt.wantErr)
+			}
+			state, err := tt.paths.dir(stateDir, tt.goos, getenv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dir(stateDir) error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cache != tt.wantCache || state != tt.wantState {
+				t.Errorf("dir() = %q, %q, want %q, %q", cache, state, tt.wantCache, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestClient_DefaultPaths(t *testing.T) {
+	dir := t.TempDir()
+	paths := SystemPaths{CacheHome: filepath.Join(dir, "cache"), StateHome: filepath.Join(dir, "state")}
+
+	c, err := NewClient("app", WithPathResolver(paths), WithDefaultSpoolDir(), WithDefaultDiskQueue(10), WithDefaultStateFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+
+	if want := filepath.Join(dir, "cache", "telemetrydeck", "app", "spool"); c.spoolDir != want {
+		t.Errorf("spool directory = %s, want %s", c.spoolDir, want)
+	}
+	if _, ok := c.store.(*DiskQueueStore); !ok {
+		t.Errorf("store = %T, want *DiskQueueStore", c.store)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cache", "telemetrydeck", "app", "queue")); err != nil {
+		t.Errorf("queue directory: %v", err)
+	}
+	if want := filepath.Join(dir, "state", "telemetrydeck", "state.json"); c.stateFile != want {
+		t.Errorf("state file = %s, want %s", c.stateFile, want)
+	}
+
+	// Explicit paths take precedence
+	explicit := filepath.Join(dir, "explicit")
+	c, err = NewClient("app", WithPathResolver(paths), WithDefaultSpoolDir(), WithSpoolDir(explicit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+	if c.spoolDir != explicit {
+		t.Errorf("spool directory = %s, want %s", c.spoolDir, explicit)
+	}
+}
+
+type failingPaths struct{}
+
+func (failingPaths) CacheDir() (string, error) { return "", errors.New("no cache") }
+func (failingPaths) StateDir() (string, error) { return "", errors.New("no state") }
+
+func TestNewClient_PathResolverError(t *testing.T) {
+	if _, err := NewClient("app", WithPathResolver(failingPaths{}), WithDefaultStateFile()); err == nil {
+		t.Error("NewClient() error = nil, want error for unresolvable state directory")
+	}
+	// Only resolved if needed
+	c, err := NewClient("app", WithPathResolver(failingPaths{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close(context.Background())
+}
@@ -0,0 +1,50 @@
+package telemetrydeck
+
+import "bytes"
+
+// PayloadTypePolicy specifies how payload values of SendSignal and
+// SendSignalSync are encoded, for use with WithPayloadTypePolicy.
+type PayloadTypePolicy int
+
+const (
+	// Encode values with their native JSON types, e.g. numbers as JSON
+	// numbers (the default).
+	PayloadTypesPreserve PayloadTypePolicy = iota
+	// Encode all values as JSON strings, containing the JSON encoding of
+	// values that aren't strings, e.g. "1.5" for 1.5.
+	PayloadTypesStringify
+)
+
+// WithPayloadTypePolicy specifies how payload values are encoded. As
+// TelemetryDeck treats numeric and string values differently in queries,
+// stringifying all values makes them appear consistently, e.g. when a key
+// is sent with numbers by some call sites and strings by others. Defaults
+// to PayloadTypesPreserve. Ingest API v1 always encodes values as strings.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithPayloadTypePolicy(policy PayloadTypePolicy) func(*Client) {
+	return func(c *Client) {
+		c.payloadTypePolicy = policy
+	}
+}
+
+// Writes a payload value as a JSON string, see PayloadTypesStringify.
+func writeJSONStringifiedValue(buf *bytes.Buffer, value interface{}) error {
+	if s, ok := value.(string); ok {
+		writeJSONString(buf, s)
+		return nil
+	}
+
+	encoded := getBuffer()
+	defer putBuffer(encoded)
+	if err := writeJSONValue(encoded, value); err != nil {
+		return err
+	}
+	if b := encoded.Bytes(); len(b) > 0 && b[0] == '"' {
+		// Errors are encoded as strings already
+		buf.Write(b)
+		return nil
+	}
+	writeJSONString(buf, encoded.String())
+	return nil
+}
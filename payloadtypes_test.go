@@ -0,0 +1,59 @@
+package telemetrydeck
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestClient_PayloadTypePolicy(t *testing.T) {
+	payload := map[string]interface{}{
+		"TestNamespace.string": "value",
+		"TestNamespace.number": 1.5,
+		"TestNamespace.bool":   true,
+		"TestNamespace.nil":    nil,
+		"TestNamespace.list":   []string{"a", "b"},
+		"TestNamespace.error":  errors.New("failed"),
+	}
+	tests := map[PayloadTypePolicy]map[string]interface{}{
+		PayloadTypesPreserve: {
+			"TestNamespace.string": "value",
+			"TestNamespace.number": 1.5,
+			"TestNamespace.bool":   true,
+			"TestNamespace.nil":    nil,
+			"TestNamespace.list":   []interface{}{"a", "b"},
+			"TestNamespace.error":  "failed (*errors.errorString)",
+		},
+		PayloadTypesStringify: {
+			"TestNamespace.string": "value",
+			"TestNamespace.number": "1.5",
+			"TestNamespace.bool":   "true",
+			"TestNamespace.nil":    "null",
+			"TestNamespace.list":   `["a","b"]`,
+			"TestNamespace.error":  "failed (*errors.errorString)",
+		},
+	}
+	for policy, want := range tests {
+		c, err := NewClient("my-app-id", WithPayloadTypePolicy(policy))
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		d, err := c.newDelivery([]SignalBody{c.newSignal("TestNamespace.typeTest", payload)}, "")
+		if err != nil {
+			t.Fatalf("Client.newDelivery() error = %v", err)
+		}
+
+		var body []SignalBody
+		if err := json.Unmarshal(d.body, &body); err != nil {
+			t.Fatalf("invalid request body %s: %v", d.body, err)
+		}
+		d.release()
+		for key := range defaultPayload {
+			delete(body[0].Payload, key)
+		}
+		if !reflect.DeepEqual(body[0].Payload, want) {
+			t.Errorf("payload with policy %d = %v, want %v", policy, body[0].Payload, want)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package telemetrydeck
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// SignalPolicy describes the signal types and payload keys allowed by a
+// platform team, see WithSignalPolicy. Signals violating the policy are
+// rejected with an error wrapping ErrPolicyViolation, unless they can be
+// rewritten to conform.
+type SignalPolicy struct {
+	// Patterns signal types and payload keys must match, if set.
+	TypePattern *regexp.Regexp
+	KeyPattern  *regexp.Regexp
+
+	// Functions rewriting signal types and payload keys not matching the
+	// patterns, e.g. to convert them to the naming convention. Signals are
+	// only rejected if the rewritten value doesn't match either.
+	RewriteType func(signalType string) string
+	RewriteKey  func(key string) string
+
+	// Validate is called with the (rewritten) signal type and the sorted
+	// payload keys of signals conforming to the patterns, and returns an
+	// error if the signal is to be rejected anyway.
+	Validate func(signalType string, keys []string) error
+}
+
+// WithSignalPolicy makes SendSignal, SendStringSignal and SendSignalSync
+// enforce the given policy on signal types and payload keys, rejecting or
+// rewriting non-conforming signals, so that conventions can be enforced at
+// the SDK boundary across all tools of an organization.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSignalPolicy(policy SignalPolicy) func(*Client) {
+	return func(c *Client) {
+		c.signalPolicy = &policy
+	}
+}
+
+// Applies the policy to the signal type and payload. Returns the type and
+// payload to send, which is a copy if keys were rewritten, or an error
+// wrapping ErrPolicyViolation.
+func applySignalPolicy[V any](p *SignalPolicy, signalType string, payload map[string]V) (string, map[string]V, error) {
+	signalType, ok := conform(p.TypePattern, p.RewriteType, signalType)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: signal type %q doesn't match %s", ErrPolicyViolation, signalType, p.TypePattern)
+	}
+
+	rewritten := false
+	for key, value := range payload {
+		newKey, ok := conform(p.KeyPattern, p.RewriteKey, key)
+		if !ok {
+			return "", nil, fmt.Errorf("%w: payload key %q of signal %s doesn't match %s", ErrPolicyViolation, key, signalType, p.KeyPattern)
+		}
+		if newKey == key {
+			continue
+		}
+
+		// Renamed in a copy: renaming in place would change the caller's
+		// map, and the loop might visit the new keys
+		if !rewritten {
+			rewritten = true
+			payload = copyPayload(payload)
+		}
+		delete(payload, key)
+		payload[newKey] = value
+	}
+
+	if p.Validate != nil {
+		keys := make([]string, 0, len(payload))
+		for key := range payload {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if err := p.Validate(signalType, keys); err != nil {
+			return "", nil, fmt.Errorf("%w: %w", ErrPolicyViolation, err)
+		}
+	}
+
+	return signalType, payload, nil
+}
+
+// Returns the value, rewritten if it doesn't match the pattern, and
+// whether the result matches.
+func conform(pattern *regexp.Regexp, rewrite func(string) string, value string) (string, bool) {
+	if pattern == nil || pattern.MatchString(value) {
+		return value, true
+	}
+	if rewrite == nil {
+		return value, false
+	}
+	value = rewrite(value)
+	return value, pattern.MatchString(value)
+}
+
+func copyPayload[V any](payload map[string]V) map[string]V {
+	c := make(map[string]V, len(payload))
+	for key, value := range payload {
+		c[key] = value
+	}
+	return c
+}
+
+// Returns the payload with its string values passed through replace, which
+// returns the new value and whether it differs. The payload is copied on
+// the first change only, so that the caller's map is left alone and
+// payloads needing no change aren't copied at all.
+func replaceStringValues[V any](payload map[string]V, replace func(string) (string, bool)) map[string]V {
+	copied := false
+	for key, value := range payload {
+		s, ok := any(value).(string)
+		if !ok {
+			continue
+		}
+		s, changed := replace(s)
+		if !changed {
+			continue
+		}
+
+		if !copied {
+			copied = true
+			payload = copyPayload(payload)
+		}
+		payload[key] = any(s).(V)
+	}
+	return payload
+}
@@ -0,0 +1,129 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_applySignalPolicy(t *testing.T) {
+	policy := &SignalPolicy{
+		TypePattern: regexp.MustCompile(`^Acme\.[A-Z]\w*\.\w+$`),
+		KeyPattern:  regexp.MustCompile(`^Acme\.\w+$`),
+		RewriteKey: func(key string) string {
+			return "Acme." + key
+		},
+		Validate: func(signalType string, keys []string) error {
+			if signalType == "Acme.CLI.secret" {
+				return errors.New("secret signals are not allowed")
+			}
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name        string
+		signalType  string
+		payload     map[string]string
+		wantPayload map[string]string
+		wantErr     string
+	}{
+		{
+			name:        "conforming",
+			signalType:  "Acme.CLI.started",
+			payload:     map[string]string{"Acme.command": "create"},
+			wantPayload: map[string]string{"Acme.command": "create"},
+		},
+		{
+			name:        "rewritten key",
+			signalType:  "Acme.CLI.started",
+			payload:     map[string]string{"command": "create", "Acme.flags": "2"},
+			wantPayload: map[string]string{"Acme.command": "create", "Acme.flags": "2"},
+		},
+		{
+			name:       "type not matching",
+			signalType: "cliStarted",
+			wantErr:    `signal type "cliStarted" doesn't match`,
+		},
+		{
+			name:       "key not matching after rewrite",
+			signalType: "Acme.CLI.started",
+			payload:    map[string]string{"command-name": "create"},
+			wantErr:    `payload key "command-name" of signal Acme.CLI.started doesn't match`,
+		},
+		{
+			name:       "rejected by validate",
+			signalType: "Acme.CLI.secret",
+			wantErr:    "secret signals are not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := fmt.Sprint(tt.payload)
+			signalType, payload, err := applySignalPolicy(policy, tt.signalType, tt.payload)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrPolicyViolation) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("applySignalPolicy() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applySignalPolicy() error = %v", err)
+			}
+			if signalType != tt.signalType || fmt.Sprint(payload) != fmt.Sprint(tt.wantPayload) {
+				t.Errorf("applySignalPolicy() = %s, %v, want %s, %v", signalType, payload, tt.signalType, tt.wantPayload)
+			}
+			if got := fmt.Sprint(tt.payload); got != original {
+				t.Errorf("payload modified to %s, want %s", got, original)
+			}
+		})
+	}
+}
+
+func TestClient_SignalPolicy(t *testing.T) {
+	received := make(chan []SignalBody, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Error(err)
+		}
+		received <- signals
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithSignalPolicy(SignalPolicy{
+			TypePattern: regexp.MustCompile(`^Acme\.`),
+			RewriteType: func(signalType string) string {
+				return "Acme." + signalType
+			},
+			KeyPattern: regexp.MustCompile(`^Acme\.`),
+		}),
+		deliverImmediately,
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if err := c.SendSignal(context.Background(), "started", nil); err != nil {
+		t.Errorf("Client.SendSignal() with rewritten type error = %v", err)
+	}
+	if signals := <-received; len(signals) != 1 || signals[0].Type != "Acme.started" {
+		t.Errorf("server received %+v, want signal of type Acme.started", signals)
+	}
+
+	if err := c.SendStringSignal(context.Background(), "Acme.started", map[string]string{"key": "value"}); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Client.SendStringSignal() error = %v, want %v", err, ErrPolicyViolation)
+	}
+	if _, err := c.SendSignalSync(context.Background(), "Acme.started", map[string]interface{}{"key": 1}); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Client.SendSignalSync() error = %v, want %v", err, ErrPolicyViolation)
+	}
+}
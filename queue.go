@@ -0,0 +1,691 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Maximum number of signals waiting for delivery, unless configured
+	// otherwise.
+	defaultQueueSize = 1000
+
+	// Maximum number of concurrent deliveries, unless configured otherwise.
+	defaultWorkers = 4
+
+	// Maximum number of signals per request, unless configured otherwise.
+	defaultMaxBatchSize = 100
+
+	// Maximum size of a request body, unless configured otherwise.
+	defaultMaxRequestBytes = 1 << 20
+
+	// Maximum number of queue shards.
+	maxQueueShards = 8
+)
+
+// Fixed-size queue of signals waiting for delivery, and the default
+// QueueStore. When full, the oldest signals may be overwritten.
+//
+// To keep contention low when many goroutines send signals concurrently,
+// the queue is split into shards with separate locks. Enqueueing picks
+// shards round-robin, so items are delivered in FIFO order per shard only.
+type ringQueue struct {
+	shards []ringShard
+
+	nextPush atomic.Uint64
+	nextPop  atomic.Uint64
+	length   atomic.Int64
+}
+
+type ringShard struct {
+	mu    sync.Mutex
+	items []QueuedSignal
+	head  int // index of the oldest item
+	size  int
+
+	// Avoid false sharing between the locks of adjacent shards
+	_ [64]byte
+}
+
+// Returns a queue holding up to capacity items (rounded up to a multiple
+// of the number of shards).
+func newRingQueue(capacity int) *ringQueue {
+	shards := runtime.GOMAXPROCS(0)
+	if shards > maxQueueShards {
+		shards = maxQueueShards
+	}
+	if shards > capacity {
+		shards = capacity
+	}
+	if shards < 1 {
+		shards = 1
+	}
+
+	perShard := (capacity + shards - 1) / shards
+	if perShard < 1 {
+		perShard = 1
+	}
+
+	q := &ringQueue{shards: make([]ringShard, shards)}
+	for i := range q.shards {
+		q.shards[i].items = make([]QueuedSignal, perShard)
+	}
+	return q
+}
+
+// Adds the item to the queue without blocking. If the shard the item goes
+// to is full, its oldest item is removed and returned.
+func (q *ringQueue) push(item QueuedSignal) (overwritten QueuedSignal, ok bool) {
+	shard := &q.shards[q.nextPush.Add(1)%uint64(len(q.shards))]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	capacity := len(shard.items)
+	if shard.size == capacity {
+		overwritten, ok = shard.items[shard.head], true
+		shard.items[shard.head] = item
+		shard.head = (shard.head + 1) % capacity
+		return overwritten, ok
+	}
+
+	shard.items[(shard.head+shard.size)%capacity] = item
+	shard.size++
+	q.length.Add(1)
+	return QueuedSignal{}, false
+}
+
+// Adds the item to the queue, unless the shard it goes to is full. Never
+// blocks. Returns false if the item was not added.
+func (q *ringQueue) tryPush(item QueuedSignal) bool {
+	shard := &q.shards[q.nextPush.Add(1)%uint64(len(q.shards))]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if shard.size == len(shard.items) {
+		return false
+	}
+
+	shard.items[(shard.head+shard.size)%len(shard.items)] = item
+	shard.size++
+	q.length.Add(1)
+	return true
+}
+
+// Removes and returns the oldest item of the next non-empty shard. Returns
+// false if the queue is empty.
+func (q *ringQueue) pop() (QueuedSignal, bool) {
+	start := q.nextPop.Add(1)
+	for i := 0; i < len(q.shards); i++ {
+		shard := &q.shards[(start+uint64(i))%uint64(len(q.shards))]
+
+		shard.mu.Lock()
+		if shard.size > 0 {
+			item := shard.items[shard.head]
+			shard.items[shard.head] = QueuedSignal{}
+			shard.head = (shard.head + 1) % len(shard.items)
+			shard.size--
+			q.length.Add(-1)
+			shard.mu.Unlock()
+			return item, true
+		}
+		shard.mu.Unlock()
+	}
+	return QueuedSignal{}, false
+}
+
+// Enqueue implements QueueStore. It returns ErrQueueFull if the shard the
+// signal goes to is full.
+func (q *ringQueue) Enqueue(signal QueuedSignal) error {
+	if !q.tryPush(signal) {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// DequeueBatch implements QueueStore.
+func (q *ringQueue) DequeueBatch(max int) ([]QueuedSignal, error) {
+	var batch []QueuedSignal
+	for len(batch) < max {
+		item, ok := q.pop()
+		if !ok {
+			break
+		}
+		if batch == nil {
+			batch = make([]QueuedSignal, 0, min(max, q.Len()+1))
+		}
+		batch = append(batch, item)
+	}
+	return batch, nil
+}
+
+// Ack implements QueueStore. Signals are discarded when dequeued already.
+func (q *ringQueue) Ack(signals []QueuedSignal) error {
+	return nil
+}
+
+// Len implements QueueStore.
+func (q *ringQueue) Len() int {
+	return int(q.length.Load())
+}
+
+// QueueFullPolicy specifies what SendSignal does when the queue is full,
+// for use with WithQueueFullPolicy.
+type QueueFullPolicy int
+
+const (
+	// Drop the new signal and return ErrQueueFull (the default).
+	QueueFullReject QueueFullPolicy = iota
+	// Drop the oldest queued signal to make room for the new one. If the
+	// limit set via WithMaxQueueBytes is exceeded, the new signal is
+	// dropped and ErrQueueFull is returned.
+	QueueFullDropOldest
+	// Block until there is room for the new signal, or the context passed
+	// to SendSignal is done.
+	QueueFullBlock
+)
+
+// WithQueueFullPolicy specifies what SendSignal does when the queue is
+// full. Defaults to QueueFullReject, so that SendSignal never blocks.
+// Dropped signals are counted in Stats.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithQueueFullPolicy(policy QueueFullPolicy) func(*Client) {
+	return func(c *Client) {
+		c.queueFullPolicy = policy
+	}
+}
+
+// WithQueueSize specifies how many signals can wait for delivery (see
+// WithQueueFullPolicy for what happens when the queue is full). Defaults
+// to 1000.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithQueueSize(n int) func(*Client) {
+	return func(c *Client) {
+		if n > 0 {
+			c.queueSize = n
+		}
+	}
+}
+
+// WithWorkers specifies how many deliveries may be in progress at the same
+// time. Defaults to 4.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithWorkers(n int) func(*Client) {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxWorkers = n
+		}
+	}
+}
+
+// WithMaxBatchSize specifies how many queued signals may be sent in a
+// single request. Defaults to 100.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMaxBatchSize(n int) func(*Client) {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxBatchSize = n
+		}
+	}
+}
+
+// WithMaxRequestBytes specifies the maximum size of a request body. Batches
+// of queued signals exceeding it are split into several requests. Batches
+// rejected by the endpoint as too large (status 413) are split as well.
+// A single signal exceeding the limit is sent on its own. Defaults to 1 MiB.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMaxRequestBytes(n int) func(*Client) {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxRequestBytes = n
+		}
+	}
+}
+
+// WithMaxQueueBytes limits the memory used by signals which have been
+// passed to SendSignal but not been delivered yet, e.g. because deliveries
+// are being retried during a network outage. Signals exceeding the limit
+// are handled according to the queue full policy (see WithQueueFullPolicy).
+// By default, only the number of signals is limited (see WithQueueSize).
+//
+// The size of a signal is the estimated size of its encoding.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMaxQueueBytes(n int) func(*Client) {
+	return func(c *Client) {
+		c.maxQueueBytes = int64(n)
+	}
+}
+
+// Adds the signal to the queue and makes sure a worker is running to
+// deliver it. If the queue is full, the queue full policy applies. Returns
+// ErrQueueFull if the signal was dropped, or the context's error if the
+// context was done while waiting for space.
+func (c *Client) enqueue(ctx context.Context, signal SignalBody, token string) error {
+	// Queued signals of the same type share a single copy of it
+	signal.Type = internedStrings.intern(signal.Type)
+	item := QueuedSignal{Signal: signal, Token: token, size: c.estimateSignalSize(&signal), ctx: ctx}
+
+	for {
+		// Register for notification before trying, so that no removal
+		// between a failed attempt and waiting is missed.
+		var space <-chan struct{}
+		if c.queueFullPolicy == QueueFullBlock {
+			space = c.spaceAvailable()
+		}
+
+		err := c.tryEnqueue(item)
+		if err == nil {
+			// Checked first, as passing the arguments allocates
+			if c.logEnabled(LogSubsystemQueue, LogLevelDebug) {
+				c.log(LogSubsystemQueue, LogLevelDebug, "enqueued signal", "type", signal.Type)
+			}
+			c.queueLengthChanged()
+			c.checkFlushTriggers()
+			return nil
+		}
+		if !errors.Is(err, ErrQueueFull) {
+			err = fmt.Errorf("error enqueueing signal: %w", err)
+			c.drop(signal, err)
+			return err
+		}
+
+		// Make room, regardless of the flush triggers
+		c.startWorkers()
+
+		if c.queueFullPolicy != QueueFullBlock {
+			c.drop(signal, ErrQueueFull)
+			return ErrQueueFull
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-space:
+		}
+	}
+}
+
+// Adds the item to the queue store without blocking. Returns ErrQueueFull
+// if the queue or the byte limit is exhausted. With the QueueFullDropOldest
+// policy and the default store, the oldest item is dropped if the queue is
+// full.
+//
+// Only signals in the default store are counted as pending bytes, as other
+// stores may not keep them in memory.
+func (c *Client) tryEnqueue(item QueuedSignal) error {
+	inMemory := c.queue != nil
+	if inMemory && !c.reservePending(item.size) {
+		return ErrQueueFull
+	}
+
+	// Counted before pushing, so that a worker can't finish the item first
+	c.unfinished.Add(1)
+
+	if !inMemory || c.queueFullPolicy != QueueFullDropOldest {
+		if err := c.store.Enqueue(item); err != nil {
+			if inMemory {
+				c.releasePending(item.size)
+			}
+			c.finish(1)
+			return err
+		}
+		return nil
+	}
+
+	if overwritten, ok := c.queue.push(item); ok {
+		c.releasePending(overwritten.size)
+		c.finish(1)
+		c.drop(overwritten.Signal, ErrQueueFull)
+		c.log(LogSubsystemQueue, LogLevelWarn, "queue full, dropped oldest signal", "type", overwritten.Signal.Type)
+	}
+	return nil
+}
+
+// Returns a channel which is closed the next time signals are dequeued.
+func (c *Client) spaceAvailable() <-chan struct{} {
+	c.spaceMu.Lock()
+	defer c.spaceMu.Unlock()
+
+	if c.space == nil {
+		c.space = make(chan struct{})
+	}
+	return c.space
+}
+
+// Wakes up producers waiting for space.
+func (c *Client) notifySpace() {
+	c.spaceMu.Lock()
+	defer c.spaceMu.Unlock()
+
+	if c.space != nil {
+		close(c.space)
+		c.space = nil
+	}
+}
+
+// Starts a worker, unless the maximum number of workers is running.
+func (c *Client) startWorker() {
+	for {
+		n := c.workers.Load()
+		if n >= int32(c.maxWorkers) || !c.workerPool.acquire() {
+			return
+		}
+		if c.workers.CompareAndSwap(n, n+1) {
+			go c.work()
+			return
+		}
+		c.workerPool.release()
+	}
+}
+
+// Reports the changed number of queued signals to the metrics and the
+// OnBackpressure hook.
+func (c *Client) queueLengthChanged() {
+	n := c.store.Len()
+	if c.metrics != nil {
+		c.metrics.Set(MetricQueueDepth, int64(n))
+	}
+	c.updatePeakQueued(int64(n))
+	c.checkWatermarks(n)
+}
+
+// Records the number of queued signals as the peak if it's higher than the
+// current one.
+func (c *Client) updatePeakQueued(n int64) {
+	for {
+		peak := c.peakQueued.Load()
+		if n <= peak {
+			return
+		}
+		if c.peakQueued.CompareAndSwap(peak, n) {
+			if c.metrics != nil {
+				c.metrics.Set(MetricQueueDepthPeak, n)
+			}
+			return
+		}
+	}
+}
+
+// Starts workers up to the maximum number.
+func (c *Client) startWorkers() {
+	for i := 0; i < c.maxWorkers; i++ {
+		c.startWorker()
+	}
+}
+
+// Encodes and delivers batches of queued signals until the queue is empty.
+func (c *Client) work() {
+	for {
+		for c.deliverNextBatch() {
+		}
+
+		c.workers.Add(-1)
+		c.workerPool.workerStopped()
+
+		// A signal may have been enqueued after the queue was found empty
+		// but before the worker count was decremented, in which case no
+		// new worker was started for it. If the store failed, give up until
+		// the next signal is enqueued rather than spinning.
+		if c.store.Len() == 0 || c.storeFailed.Load() {
+			return
+		}
+		n := c.workers.Load()
+		if n >= int32(c.maxWorkers) || !c.workerPool.acquire() {
+			return
+		}
+		if !c.workers.CompareAndSwap(n, n+1) {
+			c.workerPool.release()
+			return
+		}
+	}
+}
+
+// Dequeues a batch of up to maxBatchSize signals, delivers it and
+// acknowledges it to the store. Returns false if the queue is empty, or the
+// store failed.
+func (c *Client) deliverNextBatch() bool {
+	batch, err := c.store.DequeueBatch(c.live().maxBatchSize)
+	c.storeFailed.Store(err != nil)
+	if err != nil {
+		c.log(LogSubsystemQueue, LogLevelError, "error dequeueing signals", "error", err)
+		return false
+	}
+	skipped := c.dropSkipped()
+	if len(batch) == 0 && skipped == 0 {
+		return false
+	}
+	c.notifySpace()
+	c.queueLengthChanged()
+	if len(batch) == 0 {
+		return true
+	}
+
+	// Signals of other stores are counted as pending while being delivered
+	var size int
+	for i := range batch {
+		if batch[i].size == 0 {
+			batch[i].size = c.estimateSignalSize(&batch[i].Signal)
+		}
+		size += batch[i].size
+	}
+	if c.queue == nil {
+		c.pendingBytes.Add(int64(size))
+	}
+
+	if !c.discardBatch(batch) {
+		c.deliverBatch(batch)
+	}
+
+	if err := c.store.Ack(batch); err != nil {
+		c.log(LogSubsystemQueue, LogLevelError, "error acknowledging signals", "count", len(batch), "error", err)
+	}
+	c.releasePending(size)
+	c.finish(len(batch))
+	return true
+}
+
+// Delivers the batch of queued signals, in one request per run of signals
+// with the same bearer token.
+func (c *Client) deliverBatch(batch []QueuedSignal) {
+	for len(batch) > 0 {
+		n := 1
+		for n < len(batch) && batch[n].Token == batch[0].Token {
+			n++
+		}
+		c.deliverItems(batch[:n])
+		batch = batch[n:]
+	}
+}
+
+// Encodes and delivers queued signals with the same token, in as few
+// requests as the request size limit allows.
+func (c *Client) deliverItems(items []QueuedSignal) {
+	maxRequestBytes := c.live().maxRequestBytes
+
+	// Split by estimated size first, to avoid encoding in vain
+	for len(items) > 0 {
+		n, size := 1, items[0].size
+		for n < len(items) && size+items[n].size <= maxRequestBytes {
+			size += items[n].size
+			n++
+		}
+		c.deliverChunk(items[:n])
+		items = items[n:]
+	}
+}
+
+// Delivers the signal in the calling goroutine, bypassing the queue, see
+// WithTestMode.
+func (c *Client) deliverNow(ctx context.Context, signal SignalBody, token string) {
+	c.deliverChunk([]QueuedSignal{{Signal: signal, Token: token, ctx: ctx}})
+}
+
+// Encodes and delivers queued signals with the same token in one request.
+// If the request body turns out to exceed the size limit, or is rejected
+// as too large, the signals are split in half and delivered separately.
+// Signals whose context is done are not delivered.
+func (c *Client) deliverChunk(items []QueuedSignal) {
+	items, ctx, cancel := c.deliveryContext(items)
+	defer cancel()
+	if len(items) == 0 {
+		return
+	}
+
+	signals := make([]SignalBody, len(items))
+	for i, item := range items {
+		signals[i] = item.Signal
+	}
+
+	d, err := c.newDelivery(signals, items[0].Token)
+	if err != nil {
+		c.reportFailure(err, len(items))
+		c.log(LogSubsystemQueue, LogLevelError, "error encoding signals", "count", len(signals), "error", err)
+		return
+	}
+
+	if len(d.body) <= c.live().maxRequestBytes || len(items) == 1 {
+		var result IngestResult
+		result, err = c.submit(ctx, d)
+		if !isTooLarge(err) || len(items) == 1 {
+			if isCanceled(ctx, err) {
+				c.dropCanceled(items, err)
+			} else {
+				c.handleDeliveryError(d, err)
+			}
+			d.release()
+			if err == nil && len(result.RejectedSignals) > 0 {
+				c.handleRejectedSignals(items, result.RejectedSignals)
+			}
+			return
+		}
+	}
+
+	// Too large, split in half
+	d.release()
+	half := len(items) / 2
+	c.deliverChunk(items[:half])
+	c.deliverChunk(items[half:])
+}
+
+// Returns the signals whose context isn't done, dropping the others, and
+// the context of their delivery, which is done once the
+// contexts of all of them are. The returned function must be called once
+// the delivery is complete.
+func (c *Client) deliveryContext(items []QueuedSignal) ([]QueuedSignal, context.Context, context.CancelFunc) {
+	var remaining, canceled []QueuedSignal
+	var err error
+	for i, item := range items {
+		if item.ctx == nil || item.ctx.Err() == nil {
+			if remaining != nil {
+				remaining = append(remaining, item)
+			}
+			continue
+		}
+		if remaining == nil {
+			remaining = append(make([]QueuedSignal, 0, len(items)), items[:i]...)
+		}
+		canceled = append(canceled, item)
+		err = item.ctx.Err()
+	}
+	if remaining != nil {
+		c.dropCanceled(canceled, err)
+		items = remaining
+	}
+
+	// Signals sent without a cancelable context are delivered regardless
+	for _, item := range items {
+		if item.ctx == nil || item.ctx.Done() == nil {
+			return items, context.Background(), func() {}
+		}
+	}
+	if len(items) == 1 {
+		return items, items[0].ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var pending atomic.Int32
+	pending.Store(int32(len(items)))
+	stops := make([]func() bool, len(items))
+	for i, item := range items {
+		stops[i] = context.AfterFunc(item.ctx, func() {
+			if pending.Add(-1) == 0 {
+				cancel()
+			}
+		})
+	}
+	return items, ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+// Drops the signals whose context was done before they were delivered.
+// The caller gave up on them, so they aren't reported as failed.
+func (c *Client) dropCanceled(items []QueuedSignal, err error) {
+	err = fmt.Errorf("signal canceled before delivery: %w", err)
+	for _, item := range items {
+		c.drop(item.Signal, err)
+	}
+	c.log(LogSubsystemQueue, LogLevelDebug, "signals canceled before delivery", "count", len(items), "error", err)
+}
+
+// Drops the signals the store skipped because they could not be read,
+// see NewDiskQueueStore, and returns their number.
+func (c *Client) dropSkipped() int {
+	store, ok := c.store.(interface{ takeSkipped() []error })
+	if !ok {
+		return 0
+	}
+	skipped := store.takeSkipped()
+	for _, err := range skipped {
+		c.log(LogSubsystemQueue, LogLevelWarn, "dropping unreadable queued signal", "error", err)
+	}
+	c.stats.recordDrops(len(skipped))
+	c.finish(len(skipped))
+	return len(skipped)
+}
+
+// Reports whether the error is a response with status 413 (Content Too
+// Large).
+func isTooLarge(err error) bool {
+	var responseErr *ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// Reserves n bytes for a pending delivery. Returns false if that would
+// exceed the configured limit, in which case nothing is reserved.
+func (c *Client) reservePending(n int) bool {
+	if c.maxQueueBytes <= 0 {
+		c.pendingBytes.Add(int64(n))
+		return true
+	}
+
+	for {
+		current := c.pendingBytes.Load()
+		if current+int64(n) > c.maxQueueBytes {
+			return false
+		}
+		if c.pendingBytes.CompareAndSwap(current, current+int64(n)) {
+			return true
+		}
+	}
+}
+
+// Releases n bytes reserved by reservePending.
+func (c *Client) releasePending(n int) {
+	c.pendingBytes.Add(-int64(n))
+}
@@ -0,0 +1,470 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ringQueue(t *testing.T) {
+	q := newRingQueue(4)
+	// Use a single shard for deterministic order
+	q.shards = q.shards[:1]
+	q.shards[0].items = make([]QueuedSignal, 4)
+
+	for i := 1; i <= 4; i++ {
+		if _, ok := q.push(QueuedSignal{ID: uint64(i)}); ok {
+			t.Fatalf("push() %d overwrote an item in non-full queue", i)
+		}
+	}
+	if q.Len() != 4 {
+		t.Errorf("len() = %d, want 4", q.Len())
+	}
+
+	overwritten, ok := q.push(QueuedSignal{ID: 5})
+	if !ok || overwritten.ID != 1 {
+		t.Errorf("push() to full queue overwrote %v (%v), want oldest item", overwritten.ID, ok)
+	}
+	if q.Len() != 4 {
+		t.Errorf("len() = %d, want 4", q.Len())
+	}
+
+	for want := uint64(2); want <= 5; want++ {
+		item, ok := q.pop()
+		if !ok || item.ID != want {
+			t.Errorf("pop() = %d (%v), want %d", item.ID, ok, want)
+		}
+	}
+	if _, ok := q.pop(); ok {
+		t.Errorf("pop() from empty queue returned an item")
+	}
+}
+
+func Test_ringQueue_Concurrent(t *testing.T) {
+	q := newRingQueue(100000)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				q.push(QueuedSignal{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if q.Len() != 8000 {
+		t.Errorf("len() = %d, want 8000", q.Len())
+	}
+
+	popped := 0
+	for {
+		if _, ok := q.pop(); !ok {
+			break
+		}
+		popped++
+	}
+	if popped != 8000 || q.Len() != 0 {
+		t.Errorf("popped %d items, len() = %d, want 8000 and 0", popped, q.Len())
+	}
+}
+
+func TestClient_QueueDelivery(t *testing.T) {
+	var received atomic.Int32
+	var concurrent, maxConcurrent atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := concurrent.Add(1)
+		defer concurrent.Add(-1)
+		for {
+			m := maxConcurrent.Load()
+			if n <= m || maxConcurrent.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		received.Add(int32(len(signals)))
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithWorkers(2))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	if got := received.Load(); got != 50 {
+		t.Errorf("server received %d signals, want 50", got)
+	}
+	if got := maxConcurrent.Load(); got > 2 {
+		t.Errorf("%d concurrent deliveries, want at most 2", got)
+	}
+	if stats := c.Stats(); stats.Queued != 0 || stats.PendingBytes != 0 {
+		t.Errorf("Stats() = %+v, want empty queue", stats)
+	}
+}
+
+func TestClient_QueueOverwritesOldest(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		deliverImmediately,
+		WithWorkers(1),
+		WithQueueSize(1),
+		WithQueueFullPolicy(QueueFullDropOldest),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// The first signal is being delivered
+	if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	<-started
+
+	// Of these, one remains queued and the rest is dropped.
+	for i := 0; i < 9; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	if stats := c.Stats(); stats.Dropped != 8 || stats.Queued != 1 {
+		t.Errorf("Stats() = %+v, want 8 dropped and 1 queued", stats)
+	}
+}
+
+func TestClient_MaxQueueBytes(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithMaxQueueBytes(1000))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// Each signal is a few hundred bytes, so only some fit.
+	var rejected int
+	for i := 0; i < 10; i++ {
+		err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil)
+		if errors.Is(err, ErrQueueFull) {
+			rejected++
+		} else if err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Dropped != rejected {
+		t.Errorf("Stats().Dropped = %d, but %d signals rejected", stats.Dropped, rejected)
+	}
+	if stats.Dropped == 0 || stats.Dropped == 10 {
+		t.Errorf("Stats().Dropped = %d, want some but not all signals dropped", stats.Dropped)
+	}
+	if stats.PendingBytes == 0 || stats.PendingBytes > 1000 {
+		t.Errorf("Stats().PendingBytes = %d, want within limit", stats.PendingBytes)
+	}
+}
+
+func TestClient_EncodingInWorker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	errs := make(chan error, 1)
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		deliverImmediately,
+		WithHooks(Hooks{OnError: func(err error) { errs <- err }}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// Encoding fails in the worker, not in SendSignal.
+	payload := map[string]interface{}{"TestNamespace.invalid": make(chan int)}
+	if err := c.SendSignal(context.Background(), "TestNamespace.encodingTest", payload); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Errorf("OnError hook got nil error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("OnError hook not called for encoding error")
+	}
+}
+
+func TestClient_QueueFullReject(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithWorkers(1), WithQueueSize(1), deliverImmediately)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// The first signal is being delivered, the second one is queued.
+	if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	<-started
+	if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Client.SendSignal() to full queue: error = %v, want ErrQueueFull", err)
+	}
+	if stats := c.Stats(); stats.Dropped != 1 {
+		t.Errorf("Stats().Dropped = %d, want 1", stats.Dropped)
+	}
+}
+
+func TestClient_QueueFullBlock(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		deliverImmediately,
+		WithWorkers(1),
+		WithQueueSize(1),
+		WithQueueFullPolicy(QueueFullBlock),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	<-started
+	if err := c.SendSignal(context.Background(), "TestNamespace.queueTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	// Blocks until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.SendSignal(ctx, "TestNamespace.queueTest", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Client.SendSignal() to full queue: error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// Blocks until there is space
+	done := make(chan error)
+	go func() {
+		done <- c.SendSignal(context.Background(), "TestNamespace.queueTest", nil)
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Client.SendSignal() to full queue returned early: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Client.SendSignal() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Client.SendSignal() still blocked after queue drained")
+	}
+	if stats := c.Stats(); stats.Dropped != 0 {
+		t.Errorf("Stats().Dropped = %d, want 0", stats.Dropped)
+	}
+}
+
+func TestClient_deliverItemsChunks(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxRequestBytes int
+		serverLimit     int // signals per request accepted by the server
+		wantMaxSignals  int
+	}{
+		{name: "request size limit", maxRequestBytes: 600, serverLimit: 100, wantMaxSignals: 2},
+		{name: "rejected as too large", maxRequestBytes: 1 << 20, serverLimit: 3, wantMaxSignals: 3},
+		{name: "single oversized signal", maxRequestBytes: 1, serverLimit: 100, wantMaxSignals: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received, maxSignals int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var signals []SignalBody
+				if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+					t.Errorf("decoding body: %v", err)
+				}
+				if len(signals) > tt.serverLimit {
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					return
+				}
+				received += len(signals)
+				if len(signals) > maxSignals {
+					maxSignals = len(signals)
+				}
+			}))
+			defer server.Close()
+
+			c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithMaxRequestBytes(tt.maxRequestBytes))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			items := make([]QueuedSignal, 10)
+			for i := range items {
+				signal := c.newSignal("TestNamespace.chunkTest", map[string]interface{}{"index": i})
+				items[i] = QueuedSignal{Signal: signal, size: c.estimateSignalSize(&signal)}
+			}
+			c.deliverItems(items)
+
+			if received != len(items) {
+				t.Errorf("server received %d signals, want %d", received, len(items))
+			}
+			if maxSignals > tt.wantMaxSignals {
+				t.Errorf("request with %d signals, want at most %d", maxSignals, tt.wantMaxSignals)
+			}
+			if stats := c.Stats(); stats.Failures != 0 {
+				t.Errorf("Stats().Failures = %d, want 0", stats.Failures)
+			}
+		})
+	}
+}
+
+func TestClient_deliverChunkContext(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		received.Add(int32(len(signals)))
+		if signals[0].Type == "TestNamespace.slow" {
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	item := func(ctx context.Context, signalType string) QueuedSignal {
+		return QueuedSignal{Signal: c.newSignal(signalType, nil), ctx: ctx}
+	}
+
+	// Signals whose context is done are discarded
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.deliverChunk([]QueuedSignal{item(canceled, "TestNamespace.canceled"), item(context.Background(), "TestNamespace.sent")})
+	if got := received.Load(); got != 1 {
+		t.Errorf("server received %d signals, want 1", got)
+	}
+	if stats := c.Stats(); stats.Dropped != 1 || stats.Failures != 0 {
+		t.Errorf("Stats() = %+v, want 1 dropped and no failures", stats)
+	}
+
+	// The request is canceled once the deadlines of all signals passed
+	ctx1, cancel1 := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel1()
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	done := make(chan struct{})
+	go func() {
+		c.deliverChunk([]QueuedSignal{item(ctx1, "TestNamespace.slow"), item(ctx2, "TestNamespace.slow")})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not canceled")
+	}
+	if ctx2.Err() == nil {
+		t.Error("delivery canceled before the deadlines of all signals passed")
+	}
+	if stats := c.Stats(); stats.Dropped != 3 || stats.Failures != 0 {
+		t.Errorf("Stats() = %+v, want 3 dropped and no failures", stats)
+	}
+}
+
+func TestClient_deliveryContext(t *testing.T) {
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	_, ctx, stop := c.deliveryContext([]QueuedSignal{{ctx: ctx1}, {ctx: context.Background()}})
+	cancel1()
+	if ctx.Err() != nil {
+		t.Error("context of signals sent without cancelable context done")
+	}
+	stop()
+
+	ctx1, cancel1 = context.WithCancel(context.Background())
+	defer cancel1()
+	_, ctx, stop = c.deliveryContext([]QueuedSignal{{ctx: ctx1}, {ctx: ctx2}})
+	defer stop()
+	cancel1()
+	if ctx.Err() != nil {
+		t.Error("context done before the contexts of all signals")
+	}
+	cancel2()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("context not done after the contexts of all signals")
+	}
+}
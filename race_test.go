@@ -0,0 +1,7 @@
+//go:build race
+
+package telemetrydeck
+
+// The race detector changes allocation behavior, e.g. sync.Pool randomly
+// drops items, so allocation guards are skipped.
+const raceEnabled = true
@@ -0,0 +1,114 @@
+package telemetrydeck
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Settings that may change while the client is running, see Reconfigure.
+// Replaced as a whole, never modified.
+type liveConfig struct {
+	endpoint        string
+	sampleRate      float64
+	maxBatchSize    int
+	maxRequestBytes int
+
+	userID     string
+	hashSalt   string
+	userIDHash string
+
+	// Current session, see WithSessionMaxDuration
+	sessionID    string
+	sessionStart time.Time
+
+	// Whether the session is the first one of the app, see WithStateFile
+	firstSession bool
+
+	// Format signals are encoded in, which depends on the hashed user ID
+	// and the session ID
+	format ingestFormat
+}
+
+// Returns the current settings.
+func (c *Client) live() *liveConfig {
+	return c.liveConfig.Load()
+}
+
+// WithSampleRate specifies the fraction of signals sent via SendSignal and
+// SendStringSignal that are actually sent, e.g. 0.1 for 10%, chosen at
+// random. The others are discarded without returning an error. Signals
+// sent via SendSignalSync are always sent. Defaults to 1. Rates outside of
+// [0, 1] are ignored.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSampleRate(rate float64) func(*Client) {
+	return func(c *Client) {
+		if rate >= 0 && rate <= 1 {
+			c.sampleRate = rate
+		}
+	}
+}
+
+// Returns whether the signal is discarded according to the sample rate,
+// which the remote config may override (see WithRemoteConfig).
+func (c *Client) sampledOut(signalType string) bool {
+	rate := c.live().sampleRate
+	if rc := c.remoteConfig.current.Load(); rc != nil {
+		if r, ok := rc.sampleRate(signalType); ok {
+			rate = r
+		}
+	}
+	return rate < 1 && rand.Float64() >= rate
+}
+
+// Reconfigure changes settings of the running client, so that long-running
+// agents can be retuned without restarting them. Queued signals are kept,
+// and delivered according to the new settings. Signals sent concurrently
+// use either the old or the new settings.
+//
+// The following options are supported: WithEndpoint, WithSampleRate,
+// WithMaxBatchSize, WithMaxRequestBytes, WithUserID and WithHashSalt.
+// Other options have no effect. Returns ErrClientClosed if the client has
+// been closed.
+func (c *Client) Reconfigure(options ...func(*Client)) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+
+	c.reconfigureMu.Lock()
+	defer c.reconfigureMu.Unlock()
+
+	old := c.live()
+	scratch := &Client{
+		endpoint:        old.endpoint,
+		sampleRate:      old.sampleRate,
+		maxBatchSize:    old.maxBatchSize,
+		maxRequestBytes: old.maxRequestBytes,
+		userID:          old.userID,
+		hashSalt:        old.hashSalt,
+	}
+	for _, o := range options {
+		o(scratch)
+	}
+
+	l := *old
+	l.endpoint = old.format.endpoint(scratch.endpoint)
+	l.sampleRate = scratch.sampleRate
+	l.maxBatchSize = scratch.maxBatchSize
+	l.maxRequestBytes = scratch.maxRequestBytes
+	l.userID = scratch.userID
+	l.hashSalt = scratch.hashSalt
+	if l.userID == "" {
+		l.userID = machineUserID()
+	}
+	if l.userID != old.userID || l.hashSalt != old.hashSalt {
+		l.userIDHash = hashUserId(l.userID, l.hashSalt)
+		l.format = c.newFormat(c, &l)
+	}
+	c.liveConfig.Store(&l)
+
+	if l.endpoint != old.endpoint {
+		c.log(LogSubsystemConfig, LogLevelInfo, "endpoint reconfigured", "endpoint", l.endpoint)
+	}
+	return nil
+}
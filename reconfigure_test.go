@@ -0,0 +1,84 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Reconfigure(t *testing.T) {
+	var oldRequests, newRequests atomic.Int32
+	oldServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		oldRequests.Add(1)
+	}))
+	defer oldServer.Close()
+	newServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		newRequests.Add(1)
+	}))
+	defer newServer.Close()
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(oldServer.URL),
+		WithUserID("old-user"),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.reconfigureTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	oldHash := c.UserIDHash()
+
+	if err := c.Reconfigure(WithEndpoint(newServer.URL), WithUserID("new-user"), WithHashSalt("salt")); err != nil {
+		t.Fatalf("Client.Reconfigure() error = %v", err)
+	}
+	if got, want := c.UserIDHash(), hashUserId("new-user", "salt"); got != want || got == oldHash {
+		t.Errorf("Client.UserIDHash() = %s, want %s", got, want)
+	}
+	if got := c.newSignal("TestNamespace.reconfigureTest", nil).ClientUser; got != c.UserIDHash() {
+		t.Errorf("ClientUser = %s, want new hash", got)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+
+	if oldRequests.Load() != 0 || newRequests.Load() != 1 {
+		t.Errorf("requests = %d to old endpoint, %d to new one, want queued signal sent to new one", oldRequests.Load(), newRequests.Load())
+	}
+	if err := c.Reconfigure(WithSampleRate(0)); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Client.Reconfigure() error = %v, want ErrClientClosed", err)
+	}
+}
+
+func TestClient_Reconfigure_sampleRate(t *testing.T) {
+	c, err := NewClient("my-app-id", WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Reconfigure(WithSampleRate(0), WithSampleRate(2)); err != nil {
+		t.Fatalf("Client.Reconfigure() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.sampleTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+	if n := c.store.Len(); n != 0 {
+		t.Errorf("queued signals = %d, want none with sample rate 0", n)
+	}
+
+	if err := c.Reconfigure(WithSampleRate(1)); err != nil {
+		t.Fatalf("Client.Reconfigure() error = %v", err)
+	}
+	if err := c.SendStringSignal(context.Background(), "TestNamespace.sampleTest", nil); err != nil {
+		t.Fatalf("Client.SendStringSignal() error = %v", err)
+	}
+	if n := c.store.Len(); n != 1 {
+		t.Errorf("queued signals = %d, want 1 with sample rate 1", n)
+	}
+}
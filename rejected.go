@@ -0,0 +1,104 @@
+package telemetrydeck
+
+import (
+	"fmt"
+	"time"
+)
+
+// Signals rejected temporarily by the endpoint, to be enqueued again once
+// their backoff has passed.
+type rejectedRetry struct {
+	items   []QueuedSignal
+	reasons []string
+}
+
+// Handles the signals the endpoint rejected individually while accepting
+// the rest of their batch. Signals rejected temporarily are enqueued
+// again after the backoff of the retry policy, up to its number of
+// attempts, without blocking the calling worker. Until then they count as
+// unfinished, so that Flush waits for them. Others, and those that can't
+// be enqueued again, are dropped and passed to the OnDrop hook.
+func (c *Client) handleRejectedSignals(items []QueuedSignal, rejected []RejectedSignal) {
+	var dropped int
+	retry := &rejectedRetry{}
+	var backoff time.Duration
+	for _, r := range rejected {
+		item := items[r.Index]
+		if r.Retryable && item.requeues+1 < c.retryPolicy.MaxAttempts {
+			item.ID = 0
+			item.requeues++
+			retry.items = append(retry.items, item)
+			retry.reasons = append(retry.reasons, r.Reason)
+			backoff = max(backoff, c.retryPolicy.backoff(item.requeues))
+			continue
+		}
+		c.drop(item.Signal, fmt.Errorf("%w: %s", ErrSignalRejected, r.Reason))
+		dropped++
+	}
+	if dropped > 0 {
+		c.log(LogSubsystemRetry, LogLevelWarn, "signals rejected by the endpoint dropped", "count", dropped)
+	}
+	if len(retry.items) == 0 {
+		return
+	}
+
+	c.unfinished.Add(int64(len(retry.items)))
+	c.requeueMu.Lock()
+	if c.closed.Load() {
+		// No backoff while closing, see requeueRejectedNow
+		c.requeueMu.Unlock()
+		c.requeueRejected(retry)
+		return
+	}
+	if c.requeues == nil {
+		c.requeues = make(map[*rejectedRetry]Timer)
+	}
+	c.requeues[retry] = c.clock.AfterFunc(backoff, func() {
+		c.requeueMu.Lock()
+		_, ok := c.requeues[retry]
+		delete(c.requeues, retry)
+		c.requeueMu.Unlock()
+		if ok {
+			c.requeueRejected(retry)
+		}
+	})
+	c.requeueMu.Unlock()
+}
+
+// Enqueues the rejected signals again, and starts workers delivering them.
+func (c *Client) requeueRejected(retry *rejectedRetry) {
+	var requeued, dropped int
+	for i, item := range retry.items {
+		if err := c.tryEnqueue(item); err != nil {
+			c.drop(item.Signal, fmt.Errorf("%w: %s, enqueueing it again failed: %w", ErrSignalRejected, retry.reasons[i], err))
+			dropped++
+			continue
+		}
+		requeued++
+	}
+
+	if dropped > 0 {
+		c.log(LogSubsystemRetry, LogLevelWarn, "signals rejected by the endpoint dropped", "count", dropped)
+	}
+	if requeued > 0 {
+		c.log(LogSubsystemRetry, LogLevelInfo, "signals rejected by the endpoint enqueued again", "count", requeued)
+		c.queueLengthChanged()
+		c.startWorkers()
+	}
+	c.finish(len(retry.items))
+}
+
+// Enqueues the rejected signals waiting for their backoff right away, so
+// that Close delivers them without waiting. Must be called after the
+// client has been marked as closed.
+func (c *Client) requeueRejectedNow() {
+	c.requeueMu.Lock()
+	requeues := c.requeues
+	c.requeues = nil
+	c.requeueMu.Unlock()
+
+	for retry, timer := range requeues {
+		timer.Stop()
+		c.requeueRejected(retry)
+	}
+}
@@ -0,0 +1,257 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_handleRejectedSignals(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		var types []string
+		var rejected []RejectedSignal
+		for i, signal := range signals {
+			types = append(types, signal.Type)
+			switch signal.Type {
+			case "TestNamespace.invalid":
+				rejected = append(rejected, RejectedSignal{Index: i, Reason: "invalid"})
+			case "TestNamespace.busy":
+				rejected = append(rejected, RejectedSignal{Index: i, Reason: "busy", Retryable: true})
+			}
+		}
+		mu.Lock()
+		batches = append(batches, types)
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"rejectedSignals": rejected})
+	}))
+	defer server.Close()
+
+	var dropped []string
+	var dropErrors []error
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+		WithHooks(Hooks{OnDrop: func(signal SignalBody, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped = append(dropped, signal.Type)
+			dropErrors = append(dropErrors, err)
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for _, signalType := range []string{"TestNamespace.ok", "TestNamespace.invalid", "TestNamespace.busy"} {
+		if err := c.SendSignal(context.Background(), signalType, nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// The busy signal is sent again until the retry policy's attempts are
+	// used up
+	wantBatches := [][]string{
+		{"TestNamespace.ok", "TestNamespace.invalid", "TestNamespace.busy"},
+		{"TestNamespace.busy"},
+		{"TestNamespace.busy"},
+	}
+	if !reflect.DeepEqual(batches, wantBatches) {
+		t.Errorf("batches = %v, want %v", batches, wantBatches)
+	}
+	if want := []string{"TestNamespace.invalid", "TestNamespace.busy"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped signals = %v, want %v", dropped, want)
+	}
+	for _, err := range dropErrors {
+		if !errors.Is(err, ErrSignalRejected) {
+			t.Errorf("drop error = %v, want ErrSignalRejected", err)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Delivered != 1 || stats.Dropped != 2 {
+		t.Errorf("Client.Stats() = %+v, want 1 delivered and 2 dropped", stats)
+	}
+}
+
+func TestClient_OnDrop_queueFull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var dropped []string
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithQueueSize(1),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+		WithHooks(Hooks{OnDrop: func(signal SignalBody, err error) {
+			if !errors.Is(err, ErrQueueFull) {
+				t.Errorf("drop error = %v, want ErrQueueFull", err)
+			}
+			dropped = append(dropped, signal.Type)
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	_ = c.SendSignal(context.Background(), "TestNamespace.first", nil)
+	_ = c.SendSignal(context.Background(), "TestNamespace.second", nil)
+	if want := []string{"TestNamespace.second"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped signals = %v, want %v", dropped, want)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+}
+
+func TestClient_handleRejectedSignals_diskQueue(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		rejected := []RejectedSignal{{Index: 0, Reason: "busy", Retryable: true}}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"rejectedSignals": rejected})
+	}))
+	defer server.Close()
+
+	store, err := NewDiskQueueStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithQueueStore(store),
+		WithRetryPolicy(policy),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	start := time.Now()
+	if err := c.SendSignal(context.Background(), "TestNamespace.busy", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	// The attempt count survives the round trip through the store
+	if got := requests.Load(); got != 3 {
+		t.Errorf("%d requests, want 3", got)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("signal enqueued again after %s, want backoff of 30ms in total", elapsed)
+	}
+	if stats := c.Stats(); stats.Dropped != 1 {
+		t.Errorf("Client.Stats().Dropped = %d, want 1", stats.Dropped)
+	}
+}
+
+func TestClient_handleRejectedSignals_backoffDoesNotBlock(t *testing.T) {
+	var mu sync.Mutex
+	var batches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		batches = append(batches, signals[0].Type)
+		mu.Unlock()
+		if signals[0].Type == "TestNamespace.busy" {
+			rejected := []RejectedSignal{{Index: 0, Reason: "busy", Retryable: true}}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"rejectedSignals": rejected})
+		}
+	}))
+	defer server.Close()
+
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithClock(clock),
+		WithWorkers(1),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour, MaxBackoff: time.Hour}),
+		deliverImmediately,
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	received := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), batches...)
+	}
+
+	// Other signals are delivered while the rejected one waits
+	if err := c.SendSignal(context.Background(), "TestNamespace.busy", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	waitFor(t, func() bool { return len(received()) == 1 })
+	if err := c.SendSignal(context.Background(), "TestNamespace.ok", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	waitFor(t, func() bool { return len(received()) == 2 })
+
+	// Close doesn't wait for the backoff
+	var undelivered *UndeliveredError
+	if err := c.Close(context.Background()); !errors.As(err, &undelivered) || undelivered.Count != 1 {
+		t.Errorf("Client.Close() error = %v, want 1 undelivered signal", err)
+	}
+	want := []string{"TestNamespace.busy", "TestNamespace.ok", "TestNamespace.busy"}
+	if got := received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+}
+
+func TestClient_SendSignalSync_rejected(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			rejected := []RejectedSignal{{Index: 0, Reason: "busy", Retryable: true}}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"rejectedSignals": rejected})
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	result, err := c.SendSignalSync(context.Background(), "TestNamespace.busy", nil)
+	if err != nil || len(result.RejectedSignals) != 1 {
+		t.Fatalf("Client.SendSignalSync() = %+v, %v, want rejected signal", result, err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("%d requests, want rejected signal sent again", got)
+	}
+	if stats := c.Stats(); stats.Dropped != 0 || stats.Delivered != 1 {
+		t.Errorf("Client.Stats() = %+v, want 1 delivered", stats)
+	}
+}
@@ -0,0 +1,185 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// How long fetching the remote config may take.
+	remoteConfigTimeout = 10 * time.Second
+
+	// Maximum size of the remote config document.
+	maxRemoteConfigSize = 64 << 10
+)
+
+// RemoteConfig is the JSON document fetched by clients configured via
+// WithRemoteConfig, e.g.
+//
+//	{"disabled": false, "sampleRate": 0.5, "sampleRates": {"KubectlGS.*": 0.01}}
+type RemoteConfig struct {
+	// Disables the client while set, see Client.Disable.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Fraction of signals sent, overriding WithSampleRate if set.
+	SampleRate *float64 `json:"sampleRate,omitempty"`
+
+	// Fraction of signals sent per signal type, overriding SampleRate.
+	// Keys are signal types, or prefixes followed by "*" like in Route;
+	// exact types take precedence over prefixes, longer prefixes over
+	// shorter ones.
+	SampleRates map[string]float64 `json:"sampleRates,omitempty"`
+}
+
+// Returns an error if the config contains invalid values.
+func (rc *RemoteConfig) validate() error {
+	if rc.SampleRate != nil && (*rc.SampleRate < 0 || *rc.SampleRate > 1) {
+		return fmt.Errorf("sample rate %v out of range [0, 1]", *rc.SampleRate)
+	}
+	for pattern, rate := range rc.SampleRates {
+		if pattern == "" || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+			return fmt.Errorf("invalid sample rate pattern %q", pattern)
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sample rate %v of %s out of range [0, 1]", rate, pattern)
+		}
+	}
+	return nil
+}
+
+// Returns the sample rate of the signal type, and whether the config
+// specifies one.
+func (rc *RemoteConfig) sampleRate(signalType string) (float64, bool) {
+	if rate, ok := rc.SampleRates[signalType]; ok {
+		return rate, true
+	}
+	var rate float64
+	longest := -1
+	for pattern, r := range rc.SampleRates {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && len(prefix) > longest && strings.HasPrefix(signalType, prefix) {
+			rate, longest = r, len(prefix)
+		}
+	}
+	if longest >= 0 {
+		return rate, true
+	}
+	if rc.SampleRate != nil {
+		return *rc.SampleRate, true
+	}
+	return 0, false
+}
+
+// State of the remote config.
+type remoteConfig struct {
+	url      string
+	interval time.Duration
+
+	// Last valid config fetched, nil if none yet
+	current atomic.Pointer[RemoteConfig]
+
+	mu sync.Mutex
+	// Whether the remote config disabled the client
+	engaged bool
+	timer   Timer
+}
+
+// WithRemoteConfig makes the client fetch a RemoteConfig document from the
+// URL, in the background on creation and then at the interval, so that a
+// release flooding the ingest API can be throttled or disabled from the
+// server side. Sample rates apply to signals sent via SendSignal and
+// SendStringSignal. If fetching fails or the document is invalid, the
+// previous config is kept. Like the kill switch (see
+// WithKillSwitchInterval), the remote config doesn't enable clients
+// disabled via Disable.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithRemoteConfig(url string, interval time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.remoteConfig.url = url
+		c.remoteConfig.interval = interval
+	}
+}
+
+// Fetches and applies the remote config, and schedules the next fetch.
+func (c *Client) checkRemoteConfig() {
+	r := &c.remoteConfig
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c.closed.Load() {
+		return
+	}
+
+	if rc, err := c.fetchRemoteConfig(); err != nil {
+		c.log(LogSubsystemConfig, LogLevelWarn, "fetching remote config failed", "url", r.url, "error", err)
+	} else {
+		r.current.Store(rc)
+		switch {
+		case rc.Disabled && c.Enabled():
+			r.engaged = true
+			c.log(LogSubsystemConfig, LogLevelWarn, "telemetry disabled via remote config")
+			c.Disable()
+		case !rc.Disabled && r.engaged:
+			r.engaged = false
+			c.log(LogSubsystemConfig, LogLevelInfo, "telemetry enabled again via remote config")
+			c.Enable()
+		}
+	}
+
+	if r.interval > 0 {
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		r.timer = c.clock.AfterFunc(r.interval, c.checkRemoteConfig)
+	}
+}
+
+// Returns the remote config document.
+func (c *Client) fetchRemoteConfig() (*RemoteConfig, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteConfigTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.remoteConfig.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxRemoteConfigSize))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, &ResponseError{StatusCode: response.StatusCode, Body: string(body)}
+	}
+
+	rc := &RemoteConfig{}
+	if err := json.Unmarshal(body, rc); err != nil {
+		return nil, err
+	}
+	if err := rc.validate(); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Stops fetching the remote config.
+func (c *Client) stopRemoteConfig() {
+	r := &c.remoteConfig
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+}
@@ -0,0 +1,86 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_RemoteConfig(t *testing.T) {
+	var document atomic.Value
+	document.Store(`{"sampleRates": {"TestNamespace.dropped*": 0}}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(document.Load().(string)))
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id",
+		WithRemoteConfig(server.URL, 0),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); c.remoteConfig.current.Load() == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("remote config not fetched on creation")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, signalType := range []string{"TestNamespace.droppedTest", "TestNamespace.keptTest"} {
+		if err := c.SendSignal(context.Background(), signalType, nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+	if n := c.store.Len(); n != 1 {
+		t.Errorf("queued signals = %d, want 1 not sampled out", n)
+	}
+
+	document.Store(`{"disabled": true}`)
+	c.checkRemoteConfig()
+	if c.Enabled() {
+		t.Error("Client.Enabled() = true with remote config disabling it")
+	}
+
+	document.Store(`{"sampleRate": 2}`)
+	c.checkRemoteConfig()
+	if !c.remoteConfig.current.Load().Disabled {
+		t.Error("invalid remote config replaced the previous one")
+	}
+
+	document.Store(`{}`)
+	c.checkRemoteConfig()
+	if !c.Enabled() {
+		t.Error("Client.Enabled() = false with remote config no longer disabling it")
+	}
+}
+
+func TestRemoteConfig_sampleRate(t *testing.T) {
+	half := 0.5
+	rc := &RemoteConfig{
+		SampleRate: &half,
+		SampleRates: map[string]float64{
+			"A.*":       0.1,
+			"A.B.*":     0.2,
+			"A.B.exact": 0.3,
+		},
+	}
+	tests := map[string]float64{
+		"A.x":       0.1,
+		"A.B.x":     0.2,
+		"A.B.exact": 0.3,
+		"C.x":       0.5,
+	}
+	for signalType, want := range tests {
+		if got, ok := rc.sampleRate(signalType); !ok || got != want {
+			t.Errorf("RemoteConfig.sampleRate(%s) = %v, %v, want %v", signalType, got, ok, want)
+		}
+	}
+	if _, ok := (&RemoteConfig{}).sampleRate("A.x"); ok {
+		t.Error("RemoteConfig.sampleRate() of empty config returned a rate")
+	}
+}
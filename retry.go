@@ -0,0 +1,211 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed deliveries are retried.
+//
+// Only failures that may go away by themselves are retried: unreachable
+// endpoints and timeouts, as well as the HTTP statuses 429, 502, 503 and
+// 504. Other error statuses (e.g. 400, 401, 413) are permanent and are
+// reported via the OnError hook right away.
+type RetryPolicy struct {
+	// Maximum number of attempts per delivery, including the first one.
+	// Values below 1 are treated as 1, meaning no retries.
+	MaxAttempts int
+
+	// Time to wait before the first retry. The wait time doubles with every
+	// further retry, up to MaxBackoff. If the endpoint requests a longer
+	// delay via the Retry-After header, that delay is used instead.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used unless configured otherwise.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+}
+
+// WithRetryPolicy specifies how failed deliveries are retried. Defaults to
+// DefaultRetryPolicy.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithRetryPolicy(policy RetryPolicy) func(*Client) {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// Submits the delivery to the active endpoint, retrying retryable failures
+// according to the retry policy. Returns the result and error of the last
+// attempt.
+func (c *Client) submit(ctx context.Context, d delivery) (IngestResult, error) {
+	if c.dryRun {
+		return c.dryRunSubmit(d), nil
+	}
+	if c.hooks.OnDelivery == nil {
+		result, err := c.submitAttempts(ctx, d, &DeliveryResult{})
+		if !isCanceled(ctx, err) {
+			c.recordDeliveryResult(err, d.count-len(result.RejectedSignals))
+		}
+		return result, err
+	}
+
+	start := c.clock.Now()
+	report := DeliveryResult{
+		Signals:    d.count,
+		Bytes:      len(d.body),
+		Compressed: d.compressed,
+	}
+	result, err := c.submitAttempts(ctx, d, &report)
+	report.Duration = c.clock.Now().Sub(start)
+	report.StatusCode = result.StatusCode
+	report.RequestID = result.RequestID
+	report.Err = err
+	if !isCanceled(ctx, err) {
+		c.recordDeliveryResult(err, d.count-len(result.RejectedSignals))
+	}
+	c.hooks.OnDelivery(report)
+
+	return result, err
+}
+
+// Makes the attempts to submit the delivery for submit, recording their
+// number and the endpoint of the last one in the report.
+func (c *Client) submitAttempts(ctx context.Context, d delivery, report *DeliveryResult) (IngestResult, error) {
+	backoff := c.retryPolicy.InitialBackoff
+
+	// Built once, unless failing over to another endpoint
+	var request *http.Request
+	var requestEndpoint string
+
+	for attempt := 1; ; attempt++ {
+		if c.bandwidth != nil {
+			if err := c.bandwidth.wait(ctx, len(d.body)); err != nil {
+				return IngestResult{}, err
+			}
+		}
+
+		endpoint := c.activeEndpoint()
+		if request == nil || endpoint != requestEndpoint {
+			var err error
+			request, err = c.newRequest(ctx, endpoint, d)
+			if err != nil {
+				return IngestResult{}, err
+			}
+			requestEndpoint = endpoint
+		}
+
+		// Not part of the request template, as it may change between attempts
+		token, err := c.authTokenValue(ctx, d)
+		if err != nil {
+			return IngestResult{}, err
+		}
+		d.token = token
+
+		c.log(LogSubsystemRetry, LogLevelDebug, "delivering signals", "count", d.count, "endpoint", endpoint, "attempt", attempt)
+		report.Attempts = attempt
+		report.Endpoint = endpoint
+		result, err := c.post(ctx, request, d)
+		if isCanceled(ctx, err) {
+			// Says nothing about the endpoint
+			return result, err
+		}
+		c.reportEndpointResult(endpoint, isReachable(err))
+
+		if err == nil || attempt >= c.retryPolicy.MaxAttempts || !isRetryable(err) {
+			return result, err
+		}
+
+		wait := backoff
+		var responseErr *ResponseError
+		if errors.As(err, &responseErr) && responseErr.RetryAfter > wait {
+			wait = responseErr.RetryAfter
+		}
+
+		timer := c.clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, fmt.Errorf("%w before retrying: %w", ctx.Err(), err)
+		case <-timer.C():
+		}
+
+		c.stats.recordRetry()
+		backoff *= 2
+		if backoff > c.retryPolicy.MaxBackoff {
+			backoff = c.retryPolicy.MaxBackoff
+		}
+	}
+}
+
+// Returns the time to wait before the retry following the given number of
+// retries, doubling the initial backoff with every retry like
+// submitAttempts.
+func (p RetryPolicy) backoff(retries int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < retries; i++ {
+		backoff *= 2
+		if backoff > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// Returns whether the delivery failed because its context is done, i.e.
+// the caller gave up on it, rather than because of the endpoint.
+func isCanceled(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil &&
+		(errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// Returns whether the error (as returned by post) is a temporary failure
+// that may be resolved by retrying.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrUnreachable) {
+		return true
+	}
+
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		switch responseErr.StatusCode {
+		case http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+	}
+
+	return false
+}
+
+// Parses the value of a Retry-After header, which is either a number of
+// seconds or an HTTP date, relative to now. Returns 0 if the value is
+// empty or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,257 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_isRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unreachable", err: fmt.Errorf("%w: connection refused", ErrUnreachable), want: true},
+		{name: "canceled", err: fmt.Errorf("%w: %w", ErrUnreachable, context.Canceled), want: false},
+		{name: "429", err: &ResponseError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "502", err: &ResponseError{StatusCode: http.StatusBadGateway}, want: true},
+		{name: "503", err: &ResponseError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "504", err: &ResponseError{StatusCode: http.StatusGatewayTimeout}, want: true},
+		{name: "400", err: &ResponseError{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "401", err: &ResponseError{StatusCode: http.StatusUnauthorized}, want: false},
+		{name: "413", err: &ResponseError{StatusCode: http.StatusRequestEntityTooLarge}, want: false},
+		{name: "other error", err: errors.New("something else"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got := parseRetryAfter("3", now); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(\"3\") = %s, want 3s", got)
+	}
+	if got := parseRetryAfter("", now); got != 0 {
+		t.Errorf("parseRetryAfter(\"\") = %s, want 0", got)
+	}
+	if got := parseRetryAfter("soon", now); got != 0 {
+		t.Errorf("parseRetryAfter(\"soon\") = %s, want 0", got)
+	}
+	date := now.Add(time.Hour).Format(http.TimeFormat)
+	if got := parseRetryAfter(date, now); got != time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %s, want 1h", date, got)
+	}
+	if got := parseRetryAfter(date, now.Add(2*time.Hour)); got != 0 {
+		t.Errorf("parseRetryAfter(%q) in the past = %s, want 0", date, got)
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "success after retryable failures",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK},
+			wantRequests: 3,
+		},
+		{
+			name:         "retries used up",
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			wantRequests: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "permanent failure",
+			statuses:     []int{http.StatusUnauthorized, http.StatusOK},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[requests])
+				requests++
+			}))
+			defer server.Close()
+
+			var hookErr error
+			c, err := NewClient("my-app-id",
+				WithEndpoint(server.URL),
+				WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+				WithHooks(Hooks{OnError: func(err error) { hookErr = err }}),
+			)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			c.deliver(delivery{body: []byte("[]"), count: 1})
+
+			if requests != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, tt.wantRequests)
+			}
+			if (hookErr != nil) != tt.wantErr {
+				t.Errorf("OnError hook got error %v, wantErr %v", hookErr, tt.wantErr)
+			}
+
+			stats := c.Stats()
+			if stats.Retries != tt.wantRequests-1 {
+				t.Errorf("Stats().Retries = %d, want %d", stats.Retries, tt.wantRequests-1)
+			}
+			if (stats.Failures == 1) != tt.wantErr {
+				t.Errorf("Stats().Failures = %d, wantErr %v", stats.Failures, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_RetryReusesRequest(t *testing.T) {
+	var bodies, requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %q, want %q", r.Header.Get("Authorization"), "Bearer token")
+		}
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	body := `[{"type":"TestNamespace.retryTest"}]`
+	if _, err := c.submit(context.Background(), delivery{body: []byte(body), count: 1, token: "token"}); err != nil {
+		t.Fatalf("submit() error = %v", err)
+	}
+
+	if len(bodies) != 3 {
+		t.Fatalf("got %d requests, want 3", len(bodies))
+	}
+	for i := range bodies {
+		if bodies[i] != body {
+			t.Errorf("attempt %d body = %q, want %q", i+1, bodies[i], body)
+		}
+	}
+	if requestIDs[0] == requestIDs[1] || requestIDs[1] == requestIDs[2] {
+		t.Errorf("request IDs %v, want a new ID per attempt", requestIDs)
+	}
+}
+
+func TestClient_CanceledDuringBackoff(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var hookErrors atomic.Int32
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithClock(clock),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}),
+		WithHooks(Hooks{OnError: func(error) { hookErrors.Add(1) }}),
+		deliverImmediately,
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := c.SendSignal(ctx, "TestNamespace.retryTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	waitFor(t, func() bool { return requests.Load() == 1 })
+	cancel()
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	if stats := c.Stats(); stats.Dropped != 1 || stats.Failures != 0 {
+		t.Errorf("Stats() = %+v, want 1 dropped and no failures", stats)
+	}
+	if n := hookErrors.Load(); n != 0 {
+		t.Errorf("OnError called %d times, want 0", n)
+	}
+	if _, err := c.LastError(); err != nil {
+		t.Errorf("LastError() = %v, want nil", err)
+	}
+}
+
+func TestClient_OnDelivery(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	var results []DeliveryResult
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithWorkers(1),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		WithHooks(Hooks{OnDelivery: func(result DeliveryResult) { results = append(results, result) }}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for _, signals := range []int{2, 1} {
+		for i := 0; i < signals; i++ {
+			if err := c.SendSignal(context.Background(), "TestNamespace.deliveryTest", nil); err != nil {
+				t.Fatalf("Client.SendSignal() error = %v", err)
+			}
+		}
+		if err := c.Flush(context.Background()); err != nil {
+			t.Fatalf("Client.Flush() error = %v", err)
+		}
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("OnDelivery called %d times, want 2", len(results))
+	}
+	retried, rejected := results[0], results[1]
+	if retried.Signals != 2 || retried.Attempts != 2 || retried.StatusCode != http.StatusAccepted || retried.Err != nil ||
+		retried.Bytes == 0 || retried.Duration < time.Millisecond || retried.Endpoint != server.URL || retried.RequestID == "" {
+		t.Errorf("result of retried delivery = %+v", retried)
+	}
+	var responseErr *ResponseError
+	if rejected.Signals != 1 || rejected.Attempts != 1 || rejected.StatusCode != http.StatusBadRequest || !errors.As(rejected.Err, &responseErr) {
+		t.Errorf("result of rejected delivery = %+v", rejected)
+	}
+}
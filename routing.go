@@ -0,0 +1,85 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Route directs signals whose type matches the pattern to the client
+// registered under the key in a Manager.
+type Route struct {
+	// Signal type, or a prefix followed by "*", e.g. "KubectlGS.*" for
+	// all types starting with "KubectlGS.". "*" alone matches all types.
+	Pattern string
+
+	// Key of the client in the manager, see Manager.Add.
+	Key string
+}
+
+// Returns whether the signal type matches the route's pattern.
+func (r Route) matches(signalType string) bool {
+	if prefix, ok := strings.CutSuffix(r.Pattern, "*"); ok {
+		return strings.HasPrefix(signalType, prefix)
+	}
+	return signalType == r.Pattern
+}
+
+// SetRoutes replaces the rules used by Send and SendString to pick the
+// client of a signal, so that a binary hosting several products sends
+// each signal to the app of its product. Routes are matched in the given
+// order, the first matching one wins. Returns an error if a pattern is
+// empty or contains "*" other than at the end; the routes are unchanged
+// then. Keys are looked up when signals are sent, so routes may refer to
+// clients added later.
+func (m *Manager) SetRoutes(routes ...Route) error {
+	for _, r := range routes {
+		if r.Pattern == "" || strings.Contains(strings.TrimSuffix(r.Pattern, "*"), "*") {
+			return fmt.Errorf("invalid route pattern %q", r.Pattern)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = append([]Route(nil), routes...)
+	return nil
+}
+
+// Send sends the signal with the client its type is routed to (see
+// SetRoutes), see Client.SendSignal. Returns an error wrapping ErrNoRoute
+// if no route matches, or ErrUnknownAppKey if there's no client for the
+// key of the matching route.
+func (m *Manager) Send(ctx context.Context, signalType string, payload map[string]interface{}) error {
+	client, err := m.route(signalType)
+	if err != nil {
+		return err
+	}
+	return client.SendSignal(ctx, signalType, payload)
+}
+
+// SendString works like Send, but sends the signal via
+// Client.SendStringSignal.
+func (m *Manager) SendString(ctx context.Context, signalType string, payload map[string]string) error {
+	client, err := m.route(signalType)
+	if err != nil {
+		return err
+	}
+	return client.SendStringSignal(ctx, signalType, payload)
+}
+
+// Returns the client the signal type is routed to.
+func (m *Manager) route(signalType string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, r := range m.routes {
+		if !r.matches(signalType) {
+			continue
+		}
+		client, ok := m.clients[r.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s (routed signal %s)", ErrUnknownAppKey, r.Key, signalType)
+		}
+		return client, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoRoute, signalType)
+}
@@ -0,0 +1,99 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestManager_SetRoutes(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, signal := range signals {
+			received[signal.AppID] = append(received[signal.AppID], signal.Type)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(WithEndpoint(server.URL), WithWorkers(1), deliverImmediately)
+	for key, appID := range map[string]string{"kubectl-gs": "app-a", "happa": "app-b", "other": "app-c"} {
+		if _, err := m.Add(key, appID); err != nil {
+			t.Fatalf("Manager.Add() error = %v", err)
+		}
+	}
+
+	if err := m.SetRoutes(Route{Pattern: "KubectlGS.**", Key: "kubectl-gs"}); err == nil {
+		t.Error("Manager.SetRoutes() succeeded for an invalid pattern")
+	}
+	if err := m.Send(context.Background(), "KubectlGS.login", nil); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Manager.Send() without routes error = %v, want ErrNoRoute", err)
+	}
+
+	err := m.SetRoutes(
+		Route{Pattern: "KubectlGS.*", Key: "kubectl-gs"},
+		Route{Pattern: "HappaBackend.special", Key: "other"},
+		Route{Pattern: "HappaBackend.*", Key: "happa"},
+		Route{Pattern: "Removed.*", Key: "removed"},
+	)
+	if err != nil {
+		t.Fatalf("Manager.SetRoutes() error = %v", err)
+	}
+
+	for _, signalType := range []string{"KubectlGS.login", "HappaBackend.special", "HappaBackend.request"} {
+		if err := m.Send(context.Background(), signalType, nil); err != nil {
+			t.Errorf("Manager.Send(%s) error = %v", signalType, err)
+		}
+	}
+	if err := m.SendString(context.Background(), "HappaBackend.stringSignal", map[string]string{"key": "value"}); err != nil {
+		t.Errorf("Manager.SendString() error = %v", err)
+	}
+	if err := m.Send(context.Background(), "Unrouted.signal", nil); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Manager.Send() error = %v, want ErrNoRoute", err)
+	}
+	if err := m.Send(context.Background(), "Removed.signal", nil); !errors.Is(err, ErrUnknownAppKey) {
+		t.Errorf("Manager.Send() error = %v, want ErrUnknownAppKey", err)
+	}
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatalf("Manager.Close() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"app-a": {"KubectlGS.login"},
+		"app-b": {"HappaBackend.request", "HappaBackend.stringSignal"},
+		"app-c": {"HappaBackend.special"},
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("received signals = %v, want %v", received, want)
+	}
+}
+
+func TestRoute_matches(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		signalType string
+		want       bool
+	}{
+		{"KubectlGS.*", "KubectlGS.login", true},
+		{"KubectlGS.*", "KubectlGSX.login", false},
+		{"KubectlGS.login", "KubectlGS.login", true},
+		{"KubectlGS.login", "KubectlGS.logout", false},
+		{"*", "Anything", true},
+	}
+	for _, tt := range tests {
+		if got := (Route{Pattern: tt.pattern}).matches(tt.signalType); got != tt.want {
+			t.Errorf("Route{%s}.matches(%s) = %v, want %v", tt.pattern, tt.signalType, got, tt.want)
+		}
+	}
+}
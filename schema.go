@@ -0,0 +1,201 @@
+package telemetrydeck
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ParameterType is the expected type of a payload value in a SignalSchema.
+type ParameterType string
+
+const (
+	ParameterString ParameterType = "string"
+	ParameterNumber ParameterType = "number"
+	ParameterBool   ParameterType = "bool"
+	// Values of any type
+	ParameterAny ParameterType = "any"
+)
+
+// SignalSchema declares a signal type and the payload keys it carries.
+type SignalSchema struct {
+	Type        string                     `json:"type"`
+	Description string                     `json:"description,omitempty"`
+	Parameters  map[string]ParameterSchema `json:"parameters,omitempty"`
+}
+
+// ParameterSchema declares a payload key of a signal.
+type ParameterSchema struct {
+	Type        ParameterType `json:"type"`
+	Description string        `json:"description,omitempty"`
+
+	// Whether the key must be present in every signal of the type.
+	Required bool `json:"required,omitempty"`
+}
+
+// SchemaRegistry holds the schemas of the signal types sent by an
+// application. Passed to WithSchemaRegistry, signals sent in test mode are
+// validated against it. Its JSON encoding lists the schemas sorted by
+// signal type, e.g. for documentation and dashboard setup. Safe for
+// concurrent use.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas map[string]SignalSchema
+}
+
+// NewSchemaRegistry returns a registry holding the given schemas. Returns
+// an error if a schema is invalid, see Register.
+func NewSchemaRegistry(schemas ...SignalSchema) (*SchemaRegistry, error) {
+	r := &SchemaRegistry{schemas: make(map[string]SignalSchema, len(schemas))}
+	for _, schema := range schemas {
+		if err := r.Register(schema); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds the schema to the registry. Returns an error if the schema
+// has no type, a parameter has an unknown type, or a schema for the type
+// has been registered already.
+func (r *SchemaRegistry) Register(schema SignalSchema) error {
+	if schema.Type == "" {
+		return ErrNoSignalType
+	}
+	for key, parameter := range schema.Parameters {
+		switch parameter.Type {
+		case ParameterString, ParameterNumber, ParameterBool, ParameterAny:
+		default:
+			return fmt.Errorf("parameter %s of signal %s has unknown type %q", key, schema.Type, parameter.Type)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.schemas[schema.Type]; ok {
+		return fmt.Errorf("signal %s registered twice", schema.Type)
+	}
+	r.schemas[schema.Type] = schema
+	return nil
+}
+
+// Lookup returns the schema of the signal type, if registered.
+func (r *SchemaRegistry) Lookup(signalType string) (SignalSchema, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	schema, ok := r.schemas[signalType]
+	return schema, ok
+}
+
+// Schemas returns all registered schemas, sorted by signal type.
+func (r *SchemaRegistry) Schemas() []SignalSchema {
+	r.mu.RLock()
+	schemas := make([]SignalSchema, 0, len(r.schemas))
+	for _, schema := range r.schemas {
+		schemas = append(schemas, schema)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Type < schemas[j].Type })
+	return schemas
+}
+
+// Validate checks the payload against the schema of the signal type.
+// Returns an error wrapping ErrSchemaViolation listing all problems, if
+// the type is not registered, required keys are missing, keys are not
+// declared, or values have the wrong type.
+func (r *SchemaRegistry) Validate(signalType string, payload map[string]interface{}) error {
+	return validateSchema(r, signalType, payload)
+}
+
+func (r *SchemaRegistry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Signals []SignalSchema `json:"signals"`
+	}{r.Schemas()})
+}
+
+func (r *SchemaRegistry) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Signals []SignalSchema `json:"signals"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	registry, err := NewSchemaRegistry(v.Signals...)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas = registry.schemas
+	return nil
+}
+
+// WithSchemaRegistry makes the client validate signals sent in test mode
+// (see WithTestMode) against the registered schemas, so that tests catch
+// signals deviating from their declaration. SendSignal, SendStringSignal
+// and SendSignalSync return an error wrapping ErrSchemaViolation for such
+// signals. Outside test mode, signals are not validated.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSchemaRegistry(registry *SchemaRegistry) func(*Client) {
+	return func(c *Client) {
+		c.schemas = registry
+	}
+}
+
+// Validates the payload against the schema of the signal type, see
+// SchemaRegistry.Validate.
+func validateSchema[V any](r *SchemaRegistry, signalType string, payload map[string]V) error {
+	schema, ok := r.Lookup(signalType)
+	if !ok {
+		return fmt.Errorf("%w: signal %s is not registered", ErrSchemaViolation, signalType)
+	}
+
+	var problems []string
+	for key, parameter := range schema.Parameters {
+		if _, ok := payload[key]; !ok && parameter.Required {
+			problems = append(problems, fmt.Sprintf("required key %s is missing", key))
+		}
+	}
+	for key, value := range payload {
+		parameter, ok := schema.Parameters[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("key %s is not declared", key))
+			continue
+		}
+		if !parameter.Type.matches(value) {
+			problems = append(problems, fmt.Sprintf("value of %s is %T, want %s", key, value, parameter.Type))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	// Sorted, as map iteration order is random
+	sort.Strings(problems)
+	return fmt.Errorf("%w: signal %s: %s", ErrSchemaViolation, signalType, strings.Join(problems, "; "))
+}
+
+// Returns whether the value has the type.
+func (t ParameterType) matches(value interface{}) bool {
+	switch t {
+	case ParameterAny:
+		return true
+	case ParameterString:
+		_, ok := value.(string)
+		return ok
+	case ParameterBool:
+		_, ok := value.(bool)
+		return ok
+	case ParameterNumber:
+		switch value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+			return true
+		}
+	}
+	return false
+}
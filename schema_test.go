@@ -0,0 +1,193 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func testSchemaRegistry(t *testing.T) *SchemaRegistry {
+	r, err := NewSchemaRegistry(
+		SignalSchema{
+			Type:        "TestNamespace.purchase",
+			Description: "A purchase was completed",
+			Parameters: map[string]ParameterSchema{
+				"TestNamespace.product": {Type: ParameterString, Required: true},
+				"TestNamespace.amount":  {Type: ParameterNumber, Required: true},
+				"TestNamespace.gift":    {Type: ParameterBool},
+				"TestNamespace.extra":   {Type: ParameterAny},
+			},
+		},
+		SignalSchema{Type: "TestNamespace.appLaunched"},
+	)
+	if err != nil {
+		t.Fatalf("NewSchemaRegistry() error = %v", err)
+	}
+	return r
+}
+
+func TestSchemaRegistry_Validate(t *testing.T) {
+	r := testSchemaRegistry(t)
+
+	tests := []struct {
+		name       string
+		signalType string
+		payload    map[string]interface{}
+		wantErr    string
+	}{
+		{
+			name:       "valid",
+			signalType: "TestNamespace.purchase",
+			payload: map[string]interface{}{
+				"TestNamespace.product": "book",
+				"TestNamespace.amount":  12.5,
+				"TestNamespace.gift":    true,
+				"TestNamespace.extra":   []string{"x"},
+			},
+		},
+		{
+			name:       "optional keys missing",
+			signalType: "TestNamespace.purchase",
+			payload:    map[string]interface{}{"TestNamespace.product": "book", "TestNamespace.amount": 3},
+		},
+		{
+			name:       "no parameters",
+			signalType: "TestNamespace.appLaunched",
+		},
+		{
+			name:       "unregistered type",
+			signalType: "TestNamespace.unknown",
+			wantErr:    "signal TestNamespace.unknown is not registered",
+		},
+		{
+			name:       "missing required key",
+			signalType: "TestNamespace.purchase",
+			payload:    map[string]interface{}{"TestNamespace.amount": 3},
+			wantErr:    "required key TestNamespace.product is missing",
+		},
+		{
+			name:       "undeclared key",
+			signalType: "TestNamespace.appLaunched",
+			payload:    map[string]interface{}{"TestNamespace.source": "dock"},
+			wantErr:    "key TestNamespace.source is not declared",
+		},
+		{
+			name:       "wrong types",
+			signalType: "TestNamespace.purchase",
+			payload:    map[string]interface{}{"TestNamespace.product": 7, "TestNamespace.amount": "3"},
+			wantErr:    "TestNamespace.purchase: value of TestNamespace.amount is string, want number; value of TestNamespace.product is int, want string",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Validate(tt.signalType, tt.payload)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("SchemaRegistry.Validate() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchemaViolation) {
+				t.Fatalf("SchemaRegistry.Validate() error = %v, want ErrSchemaViolation", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SchemaRegistry.Validate() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchemaRegistry_Register(t *testing.T) {
+	r := testSchemaRegistry(t)
+
+	if err := r.Register(SignalSchema{}); !errors.Is(err, ErrNoSignalType) {
+		t.Errorf("SchemaRegistry.Register() error = %v, want ErrNoSignalType", err)
+	}
+	if err := r.Register(SignalSchema{Type: "TestNamespace.appLaunched"}); err == nil {
+		t.Error("SchemaRegistry.Register() succeeded for a duplicate type")
+	}
+	invalid := SignalSchema{
+		Type:       "TestNamespace.invalid",
+		Parameters: map[string]ParameterSchema{"TestNamespace.date": {Type: "date"}},
+	}
+	if err := r.Register(invalid); err == nil {
+		t.Error("SchemaRegistry.Register() succeeded for an unknown parameter type")
+	}
+	if _, ok := r.Lookup("TestNamespace.invalid"); ok {
+		t.Error("SchemaRegistry.Lookup() found an invalid schema")
+	}
+}
+
+func TestSchemaRegistry_JSON(t *testing.T) {
+	r := testSchemaRegistry(t)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"signals":[{"type":"TestNamespace.appLaunched"},{"type":"TestNamespace.purchase","description":"A purchase was completed"`) {
+		t.Errorf("json.Marshal() = %s, want schemas sorted by type", data)
+	}
+
+	var decoded SchemaRegistry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Schemas(), r.Schemas()) {
+		t.Errorf("decoded schemas = %+v, want %+v", decoded.Schemas(), r.Schemas())
+	}
+
+	invalid := `{"signals":[{"type":"TestNamespace.a"},{"type":"TestNamespace.a"}]}`
+	if err := json.Unmarshal([]byte(invalid), &decoded); err == nil {
+		t.Error("json.Unmarshal() succeeded for duplicate types")
+	}
+}
+
+func TestClient_SchemaRegistry(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	invalid := map[string]interface{}{"TestNamespace.amount": "3"}
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithTestMode(), WithSchemaRegistry(testSchemaRegistry(t)))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.purchase", invalid); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("Client.SendSignal() error = %v, want ErrSchemaViolation", err)
+	}
+	if err := c.SendStringSignal(context.Background(), "TestNamespace.unknown", nil); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("Client.SendStringSignal() error = %v, want ErrSchemaViolation", err)
+	}
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.purchase", invalid); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("Client.SendSignalSync() error = %v, want ErrSchemaViolation", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.appLaunched", nil); err != nil {
+		t.Errorf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+
+	// Outside test mode, signals are sent regardless of the schema
+	c, err = NewClient("my-app-id", WithEndpoint(server.URL), WithSchemaRegistry(testSchemaRegistry(t)))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.purchase", invalid); err != nil {
+		t.Errorf("Client.SendSignal() error = %v", err)
+	}
+}
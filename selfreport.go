@@ -0,0 +1,95 @@
+package telemetrydeck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Type of the signals sent by WithDeliveryReports.
+const DeliveryReportSignalType = "TelemetryDeck.SDK.deliveryReport"
+
+// Payload keys of delivery report signals.
+const (
+	// Number of signals delivered successfully, including earlier
+	// delivery reports.
+	DeliveryReportKeyDelivered = "TelemetryDeck.SDK.delivered"
+	// Number of signals whose delivery failed permanently or after all
+	// retries.
+	DeliveryReportKeyFailed = "TelemetryDeck.SDK.failed"
+	// Number of signals dropped without attempting delivery.
+	DeliveryReportKeyDropped = "TelemetryDeck.SDK.dropped"
+	// Number of requests that were retries of failed requests.
+	DeliveryReportKeyRetries = "TelemetryDeck.SDK.retries"
+	// Seconds covered by the report, since the previous report or the
+	// creation of the client.
+	DeliveryReportKeyPeriod = "TelemetryDeck.SDK.period"
+)
+
+// State of the delivery reports.
+type deliveryReports struct {
+	interval time.Duration
+
+	mu sync.Mutex
+	// Whether signals have been sent since the last report
+	pending bool
+	last    time.Time
+	// Counters at the time of the last report
+	delivered, failed, dropped, retries int
+}
+
+// WithDeliveryReports makes the client send a signal of type
+// TelemetryDeck.SDK.deliveryReport at most once per interval, summarizing
+// how many signals have been delivered, have failed and have been dropped
+// since the previous report, so that the health of the telemetry pipeline
+// is visible in the dashboard. Reports are only sent if signals have been
+// sent since the previous report. When the client is closed, a final
+// report is sent regardless of the interval.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithDeliveryReports(interval time.Duration) func(*Client) {
+	return func(c *Client) {
+		if interval > 0 {
+			c.deliveryReports = &deliveryReports{interval: interval}
+		}
+	}
+}
+
+// Records that a signal has been sent, and sends a delivery report if it's
+// due. If force is set, the report is sent regardless of the interval.
+func (c *Client) checkDeliveryReport(ctx context.Context, signalSent, force bool) {
+	r := c.deliveryReports
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	if signalSent {
+		r.pending = true
+	}
+	now := c.clock.Now()
+	if !r.pending || (!force && now.Sub(r.last) < r.interval) {
+		r.mu.Unlock()
+		return
+	}
+
+	stats := c.Stats()
+	failed := int(c.failedSignals.Load())
+	payload := map[string]interface{}{
+		DeliveryReportKeyDelivered: stats.Delivered - r.delivered,
+		DeliveryReportKeyFailed:    failed - r.failed,
+		DeliveryReportKeyDropped:   stats.Dropped - r.dropped,
+		DeliveryReportKeyRetries:   stats.Retries - r.retries,
+		DeliveryReportKeyPeriod:    int(now.Sub(r.last).Seconds()),
+	}
+	r.pending = false
+	r.last = now
+	r.delivered, r.failed, r.dropped, r.retries = stats.Delivered, failed, stats.Dropped, stats.Retries
+	r.mu.Unlock()
+
+	// The report must not be dropped once the caller's context is done
+	err := c.enqueue(context.WithoutCancel(ctx), c.newSignal(DeliveryReportSignalType, payload), c.authToken)
+	if err != nil {
+		c.log(LogSubsystemSession, LogLevelWarn, "error sending delivery report", "error", err)
+	}
+}
@@ -0,0 +1,144 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock whose time only moves when told to.
+type manualClock struct {
+	systemClock
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestClient_DeliveryReports(t *testing.T) {
+	var mu sync.Mutex
+	var reports []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, signal := range signals {
+			if signal.Type == DeliveryReportSignalType {
+				reports = append(reports, signal.Payload)
+			}
+		}
+	}))
+	defer server.Close()
+
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithClock(clock),
+		WithDeliveryReports(time.Hour),
+		// Delivers signals only when flushing, so that the counters are
+		// known when a report is due
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	send := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := c.SendSignal(context.Background(), "TestNamespace.reportTest", nil); err != nil {
+				t.Fatalf("Client.SendSignal() error = %v", err)
+			}
+		}
+		if err := c.Flush(context.Background()); err != nil {
+			t.Fatalf("Client.Flush() error = %v", err)
+		}
+	}
+
+	// Not due yet
+	send(3)
+	clock.advance(30 * time.Minute)
+	send(1)
+	if len(reports) != 0 {
+		t.Fatalf("%d reports sent before the interval passed, want 0", len(reports))
+	}
+
+	// Due with the next signal, covering the signals delivered so far
+	clock.advance(30 * time.Minute)
+	send(2)
+	if len(reports) != 1 {
+		t.Fatalf("%d reports sent after the interval, want 1", len(reports))
+	}
+	want := map[string]interface{}{
+		DeliveryReportKeyDelivered: 4.0,
+		DeliveryReportKeyFailed:    0.0,
+		DeliveryReportKeyDropped:   0.0,
+		DeliveryReportKeyRetries:   0.0,
+		DeliveryReportKeyPeriod:    3600.0,
+	}
+	for key, value := range want {
+		if reports[0][key] != value {
+			t.Errorf("report %s = %v, want %v", key, reports[0][key], value)
+		}
+	}
+
+	// Final report when closing, covering the last signals and the report
+	clock.advance(time.Minute)
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("%d reports sent after closing, want 2", len(reports))
+	}
+	if got := reports[1][DeliveryReportKeyDelivered]; got != 3.0 {
+		t.Errorf("final report %s = %v, want 3", DeliveryReportKeyDelivered, got)
+	}
+	if got := reports[1][DeliveryReportKeyPeriod]; got != 60.0 {
+		t.Errorf("final report %s = %v, want 60", DeliveryReportKeyPeriod, got)
+	}
+}
+
+func TestClient_DeliveryReportOutlivesContext(t *testing.T) {
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithClock(clock),
+		WithDeliveryReports(time.Hour),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// A request context, done once the request has been handled
+	ctx, cancel := context.WithCancel(context.Background())
+	clock.advance(time.Hour)
+	if err := c.SendSignal(ctx, "TestNamespace.reportTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	cancel()
+
+	batch, err := c.store.DequeueBatch(2)
+	if err != nil || len(batch) != 2 {
+		t.Fatalf("DequeueBatch() = %v, %v, want signal and report", batch, err)
+	}
+	if report := batch[1]; report.Signal.Type != DeliveryReportSignalType || report.ctx.Err() != nil {
+		t.Errorf("report %s with context error %v, want delivery report outliving the context", report.Signal.Type, report.ctx.Err())
+	}
+}
@@ -0,0 +1,177 @@
+package telemetrydeck
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Type of the signal sent at the start of every session if sessions are
+// announced, see WithSessionMaxDuration and WithSessionIdleTimeout.
+const sessionStartedSignalType = "TelemetryDeck.Session.started"
+
+// Payload key of the number of seconds since the start of the session,
+// see WithSessionDuration.
+const SessionDurationKey = "TelemetryDeck.Session.durationInSeconds"
+
+// Time without signals after which TelemetryDeck's mobile SDKs start a new
+// session, for use with WithSessionIdleTimeout.
+const DefaultSessionIdleTimeout = 30 * time.Minute
+
+// WithSessionMaxDuration makes the client start a new session once the
+// current one has lasted for the duration, so that long-running daemons
+// don't show up as a single session spanning weeks. A new session gets a
+// new ID, replacing the one given via WithSessionID, and is announced by a
+// signal of type "TelemetryDeck.Session.started", which is also sent for
+// the first session when the client is created. Sessions are only rotated
+// when signals are sent, not in the background.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSessionMaxDuration(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		if d > 0 {
+			c.sessionMaxDuration = d
+			c.announceSessions = true
+		}
+	}
+}
+
+// WithSessionIdleTimeout specifies the time without signals after which
+// the next signal starts a new session. Pass DefaultSessionIdleTimeout to
+// get session counts comparable with apps using TelemetryDeck's mobile
+// SDKs. Idle sessions are not renewed by default. Like with
+// WithSessionMaxDuration, new sessions get a new ID, replacing the one
+// given via WithSessionID, and are announced by a
+// "TelemetryDeck.Session.started" signal.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSessionIdleTimeout(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		if d > 0 {
+			c.sessionIdleTimeout = d
+			c.announceSessions = true
+		}
+	}
+}
+
+// WithSessionDuration makes the client add the number of whole seconds
+// since the start of the current session to the payload of every signal,
+// under the key SessionDurationKey, so that the time into a session when
+// users do something can be analyzed without bookkeeping in the app.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSessionDuration() func(*Client) {
+	return func(c *Client) {
+		c.sessionDurations = true
+	}
+}
+
+// Returns whether parameters computed by the client are added to the
+// payload of signals, see WithSessionDuration, WithStateFile,
+// WithLaunchCount and WithCalendarParameters.
+func (c *Client) addsParameters() bool {
+	return c.sessionDurations || c.stateFile != "" || c.calendarParameters
+}
+
+// Returns a copy of the payload with the parameters computed by the client
+// added, using the session of the settings. The payload passed to the Send
+// methods is owned by the caller, so it can't be modified.
+func withParameters[V any](c *Client, l *liveConfig, payload map[string]V) map[string]V {
+	result := make(map[string]V, len(payload)+7)
+	for key, v := range payload {
+		result[key] = v
+	}
+	now := c.clock.Now()
+	if c.sessionDurations {
+		seconds := int64(now.Sub(l.sessionStart) / time.Second)
+		result[SessionDurationKey] = parameterValue[V](seconds)
+	}
+	if c.stateFile != "" {
+		result[FirstSessionKey] = parameterValue[V](l.firstSession)
+	}
+	if c.countLaunches {
+		result[LaunchCountKey] = parameterValue[V](c.launchCount)
+	}
+	if c.calendarParameters {
+		addCalendarParameters(result, now.Local())
+	}
+	return result
+}
+
+// Converts an int64 or bool parameter to a payload value, which is a
+// string for payloads of SendStringSignal.
+func parameterValue[V any](parameter interface{}) V {
+	var value V
+	switch v := any(&value).(type) {
+	case *string:
+		switch p := parameter.(type) {
+		case int64:
+			*v = strconv.FormatInt(p, 10)
+		case bool:
+			*v = strconv.FormatBool(p)
+		}
+	case *interface{}:
+		*v = parameter
+	}
+	return value
+}
+
+// Returns whether new sessions are started while the client is running.
+func (c *Client) rotatesSessions() bool {
+	return c.sessionMaxDuration > 0 || c.sessionIdleTimeout > 0
+}
+
+// Returns the settings with the current session, starting a new one if
+// the previous one expired.
+func (c *Client) session() *liveConfig {
+	l := c.live()
+	if !c.rotatesSessions() {
+		return l
+	}
+
+	now := c.clock.Now()
+	if !c.sessionExpired(l, now) {
+		c.lastActivity.Store(now.UnixNano())
+		return l
+	}
+
+	c.reconfigureMu.Lock()
+	l = c.live()
+	started := c.sessionExpired(l, now)
+	if started {
+		next := *l
+		next.sessionID = c.newID()
+		next.sessionStart = now
+		next.firstSession = false
+		next.format = c.newFormat(c, &next)
+		l = &next
+		c.liveConfig.Store(l)
+	}
+	c.lastActivity.Store(now.UnixNano())
+	c.reconfigureMu.Unlock()
+
+	if started && c.announceSessions {
+		c.sendSessionStarted(l)
+	}
+	return l
+}
+
+// Returns whether the session of the settings is over at the time.
+func (c *Client) sessionExpired(l *liveConfig, now time.Time) bool {
+	if c.sessionMaxDuration > 0 && now.Sub(l.sessionStart) >= c.sessionMaxDuration {
+		return true
+	}
+	idle := now.Sub(time.Unix(0, c.lastActivity.Load()))
+	return c.sessionIdleTimeout > 0 && idle >= c.sessionIdleTimeout
+}
+
+// Queues the signal announcing the session of the settings.
+func (c *Client) sendSessionStarted(l *liveConfig) {
+	if c.discardsSignals() {
+		return
+	}
+	signal := c.sessionSignal(l, sessionStartedSignalType, nil)
+	if err := c.sendSignal(context.Background(), signal); err != nil {
+		c.log(LogSubsystemSession, LogLevelWarn, "sending session start failed", "error", err)
+	}
+}
@@ -0,0 +1,152 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_SessionMaxDuration(t *testing.T) {
+	var mu sync.Mutex
+	var received []SignalBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, signals...)
+	}))
+	defer server.Close()
+
+	// Request IDs are generated only on Close, after both sessions started
+	var ids atomic.Int32
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithClock(clock),
+		WithSessionMaxDuration(time.Hour),
+		WithIDGenerator(func() string {
+			return fmt.Sprintf("session-%d", ids.Add(1))
+		}),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	send := func() {
+		t.Helper()
+		if err := c.SendSignal(context.Background(), "TestNamespace.sessionTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	send()
+	clock.advance(59 * time.Minute)
+	send()
+	clock.advance(time.Minute)
+	send()
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+
+	var got []string
+	for _, signal := range received {
+		got = append(got, signal.SessionID+" "+signal.Type)
+	}
+	want := []string{
+		"session-1 TelemetryDeck.Session.started",
+		"session-1 TestNamespace.sessionTest",
+		"session-1 TestNamespace.sessionTest",
+		"session-2 TelemetryDeck.Session.started",
+		"session-2 TestNamespace.sessionTest",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("received signals = %v, want %v", got, want)
+	}
+}
+
+func TestClient_SessionIdleTimeout(t *testing.T) {
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id",
+		WithClock(clock),
+		WithSessionIdleTimeout(DefaultSessionIdleTimeout),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	sessionID := func() string {
+		return c.newSignal("TestNamespace.sessionTest", nil).SessionID
+	}
+
+	first := sessionID()
+	for i := 0; i < 3; i++ {
+		clock.advance(DefaultSessionIdleTimeout - time.Second)
+		if got := sessionID(); got != first {
+			t.Fatalf("session ID = %s after activity, want %s", got, first)
+		}
+	}
+	clock.advance(DefaultSessionIdleTimeout)
+	if got := sessionID(); got == first {
+		t.Error("session not renewed after idle timeout")
+	}
+	if n := c.store.Len(); n != 2 {
+		t.Errorf("queued signals = %d, want 2 session starts announced", n)
+	}
+}
+
+func TestClient_SessionIdleTimeout_disabledByDefault(t *testing.T) {
+	for _, opt := range []func(*Client){WithSessionIdleTimeout(0), func(*Client) {}} {
+		clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+		c, err := NewClient("my-app-id",
+			WithClock(clock),
+			WithSessionID("my-session"),
+			opt,
+			WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+		)
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+
+		clock.advance(24 * time.Hour)
+		if got := c.newSignal("TestNamespace.sessionTest", nil).SessionID; got != "my-session" {
+			t.Errorf("session ID = %s, want my-session", got)
+		}
+		if n := c.store.Len(); n != 0 {
+			t.Errorf("queued signals = %d, want no session start announced", n)
+		}
+	}
+}
+
+func TestClient_SessionDuration(t *testing.T) {
+	clock := &manualClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	c, err := NewClient("my-app-id", WithClock(clock), WithSessionDuration(), WithSessionIdleTimeout(time.Hour))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	clock.advance(90*time.Second + 500*time.Millisecond)
+	payload := map[string]interface{}{"TestNamespace.key": "value"}
+	signal := c.newSignal("TestNamespace.durationTest", payload)
+	if got := signal.Payload[SessionDurationKey]; got != int64(90) {
+		t.Errorf("session duration = %v, want 90", got)
+	}
+	if _, ok := payload[SessionDurationKey]; ok || len(signal.Payload) != 2 {
+		t.Errorf("payload = %v, want copy with session duration added", signal.Payload)
+	}
+
+	clock.advance(2 * time.Hour)
+	signal = c.newStringSignal("TestNamespace.durationTest", nil)
+	if got := signal.stringPayload[SessionDurationKey]; got != "0" {
+		t.Errorf("session duration = %q, want 0 in new session", got)
+	}
+}
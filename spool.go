@@ -0,0 +1,759 @@
+package telemetrydeck
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// File name extension of spool segments
+	spoolSegmentExt = ".wal"
+
+	// File name extension of the files holding the offset up to which the
+	// records of a segment have been delivered
+	spoolAckExt = ".ack"
+
+	// Segments are rotated when reaching this size, unless configured
+	// otherwise via SpoolLimits.MaxSegmentBytes.
+	defaultSpoolSegmentBytes = 1 << 20
+
+	// Size of the record header: payload length and checksum
+	spoolRecordHeaderSize = 8
+
+	// Size of the record metadata at the start of the payload: creation
+	// time, signal count and flags
+	spoolRecordMetaSize = 13
+
+	// Record flag marking gzip-compressed bodies
+	spoolFlagCompressed = 1
+
+	// Record flag marking records whose body is preceded by the IDs of its
+	// signals, see WithSpoolDedupWindow
+	spoolFlagSignalIDs = 2
+)
+
+var spoolChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Returned when reading a spooled record that can't be decrypted, e.g.
+// because the encryption key has changed.
+var errSpoolSegmentCorrupt = errors.New("spooled segment corrupt")
+
+// SpoolLimits bound the disk usage of the spool (see WithSpoolDir). A zero
+// value field means no limit.
+type SpoolLimits struct {
+	// Maximum total size of all spooled segments. When exceeded, the oldest
+	// segments are evicted.
+	MaxBytes int64
+
+	// Maximum age of a spooled segment, measured from its last write.
+	// Older segments are dropped.
+	MaxAge time.Duration
+
+	// Size at which a new segment is started. Request bodies larger than
+	// this are not spooled. Defaults to 1 MiB if zero.
+	MaxSegmentBytes int64
+}
+
+// DefaultSpoolLimits are the spool limits used unless configured otherwise
+// via WithSpoolLimits.
+var DefaultSpoolLimits = SpoolLimits{
+	MaxBytes:        64 << 20,
+	MaxAge:          7 * 24 * time.Hour,
+	MaxSegmentBytes: 1 << 20,
+}
+
+// Directory holding request bodies that could not be delivered, as an
+// append-only log split into segment files. Each record is prefixed with
+// its length and checksum, so that a record torn by a crash is detected
+// and cut off on startup, without losing the records before it. Segment
+// names are the creation time, so that sorting them by name yields the
+// oldest segments first.
+//
+// Delivered records are not removed from their segment. Instead, the
+// offset up to which records have been delivered is kept in a separate
+// file, and the segment is removed once all its records are delivered.
+type spool struct {
+	dir    string
+	limits SpoolLimits
+
+	// Encrypts record bodies, if set
+	aead cipher.AEAD
+
+	// Time within which signals aren't replayed twice, zero if they may
+	// be, and the signals replayed within it by ID, loaded from the replay
+	// journal on first use (see WithSpoolDedupWindow)
+	dedupWindow time.Duration
+	replayed    map[uuid.UUID]time.Time
+
+	// Guards the active segment, acknowledgements and compaction
+	mu         sync.Mutex
+	active     *os.File
+	activeName string
+	activeSize int64
+
+	// Whether a replay is in progress
+	replaying atomic.Bool
+}
+
+// A spool segment file.
+type spoolSegment struct {
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+// A record of a spool segment.
+type spoolRecord struct {
+	end        int64 // offset of the next record in the segment
+	created    time.Time
+	count      int // number of signals in the body
+	compressed bool
+	signalIDs  []uuid.UUID // IDs of the signals, if recorded
+	body       []byte      // encrypted, if encryption is enabled
+}
+
+// WithSpoolDir makes the client persist signals that could not be delivered
+// after all retries, because the endpoint was unreachable or temporarily
+// unavailable, in the given directory. Spooled signals are delivered when
+// the next client using the same directory is created, or after the next
+// successful delivery. The directory is created if it doesn't exist.
+//
+// The disk usage of the spool is bounded by DefaultSpoolLimits, unless
+// configured otherwise via WithSpoolLimits.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSpoolDir(dir string) func(*Client) {
+	return func(c *Client) {
+		c.spoolDir = dir
+	}
+}
+
+// WithSpoolEncryptionKey makes the client encrypt the signals persisted in
+// the spool (see WithSpoolDir) with AES-GCM, using the given key of 16, 24
+// or 32 bytes. Use this if spooled signals may contain sensitive payload
+// values and the spool directory is readable by others.
+//
+// Spooled signals that can't be decrypted with the key, e.g. because it
+// has changed, are dropped.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSpoolEncryptionKey(key []byte) func(*Client) {
+	return func(c *Client) {
+		c.spoolKey = key
+	}
+}
+
+// WithSpoolLimits specifies limits for the disk usage of the spool (see
+// WithSpoolDir). Signals exceeding the limits are dropped and counted in
+// Stats.Dropped.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSpoolLimits(limits SpoolLimits) func(*Client) {
+	return func(c *Client) {
+		c.spoolLimits = limits
+	}
+}
+
+// Opens the spool in the given directory, creating the directory if
+// necessary, and recovers segments torn by a crash. Records are encrypted
+// if a key is given.
+func openSpool(dir string, limits SpoolLimits, key []byte) (*spool, error) {
+	if limits.MaxSegmentBytes <= 0 {
+		limits.MaxSegmentBytes = defaultSpoolSegmentBytes
+	}
+	s := &spool{dir: dir, limits: limits}
+
+	if key != nil {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spool encryption key: %w", err)
+		}
+		s.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating spool directory: %w", err)
+	}
+	if err := s.recover(); err != nil {
+		return nil, fmt.Errorf("recovering spool: %w", err)
+	}
+	return s, nil
+}
+
+// Truncates every segment after its last intact record, and removes
+// acknowledgement files of segments that no longer exist.
+func (s *spool) recover() error {
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	exists := make(map[string]bool, len(segments))
+	for _, seg := range segments {
+		exists[seg.name] = true
+
+		path := filepath.Join(s.dir, seg.name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, valid := decodeSpoolRecords(data); valid < int64(len(data)) {
+			if err := os.Truncate(path, valid); err != nil {
+				return err
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), spoolAckExt)
+		if ok && !exists[name] {
+			os.Remove(filepath.Join(s.dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// Encrypts the body of a record of the segment with the given name, if
+// encryption is enabled. The nonce is prepended to the ciphertext, and the
+// segment name is authenticated so that records can't be moved between
+// segments.
+func (s *spool) seal(name string, body []byte) ([]byte, error) {
+	if s.aead == nil {
+		return body, nil
+	}
+
+	out := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(body)+s.aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(out, out, body, []byte(name)), nil
+}
+
+// Decrypts the body of a record of the segment with the given name, if
+// encryption is enabled. Returns errSpoolSegmentCorrupt if it can't be
+// decrypted.
+func (s *spool) open(name string, data []byte) ([]byte, error) {
+	if s.aead == nil {
+		return data, nil
+	}
+
+	n := s.aead.NonceSize()
+	if len(data) < n {
+		return nil, errSpoolSegmentCorrupt
+	}
+	body, err := s.aead.Open(nil, data[:n], data[n:], []byte(name))
+	if err != nil {
+		return nil, errSpoolSegmentCorrupt
+	}
+	return body, nil
+}
+
+// Appends the body of the delivery to the active segment and syncs it to
+// disk. Returns the signal count of the records dropped to stay within the
+// limits, including the delivery itself if it's too large to be spooled.
+func (s *spool) write(d delivery, now time.Time) (dropped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if int64(len(d.body)) > s.limits.MaxSegmentBytes {
+		return d.count, nil
+	}
+
+	if s.active == nil || s.activeSize >= s.limits.MaxSegmentBytes {
+		if err := s.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	body, err := s.seal(s.activeName, d.body)
+	if err != nil {
+		return 0, err
+	}
+	var signalIDs []uuid.UUID
+	if s.dedupWindow > 0 {
+		signalIDs = make([]uuid.UUID, d.count)
+		for i := range signalIDs {
+			signalIDs[i] = uuid.New()
+		}
+	}
+	record := encodeSpoolRecord(now, d.count, d.compressed, signalIDs, body)
+
+	if _, err := s.active.Write(record); err != nil {
+		// Don't append to a segment that may end with a partial record
+		s.closeActive()
+		return 0, err
+	}
+	if err := s.active.Sync(); err != nil {
+		s.closeActive()
+		return 0, err
+	}
+	s.activeSize += int64(len(record))
+
+	return s.compactLocked(now)
+}
+
+// Starts a new active segment.
+func (s *spool) rotate(now time.Time) error {
+	s.closeActive()
+
+	nanos := now.UnixNano()
+	for {
+		name := fmt.Sprintf("%020d%s", nanos, spoolSegmentExt)
+		f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o600)
+		if os.IsExist(err) {
+			nanos++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		s.active = f
+		s.activeName = name
+		s.activeSize = 0
+		return nil
+	}
+}
+
+// Closes the active segment, so that the next write starts a new one.
+// Must be called with s.mu held.
+func (s *spool) closeActive() {
+	if s.active != nil {
+		s.active.Close()
+		s.active = nil
+		s.activeName = ""
+	}
+}
+
+// Closes the active segment, so that all existing segments can be replayed
+// without being written to concurrently.
+func (s *spool) sealActive() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeActive()
+}
+
+// Closes the active segment and returns the segments, oldest first. The
+// segments are listed while holding s.mu, so that none of them is written
+// to anymore; signals spooled meanwhile go to a new segment.
+func (s *spool) sealedSegments() ([]spoolSegment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeActive()
+	return s.segments()
+}
+
+// Drops expired segments, then evicts the oldest segments until the total
+// size is within the limit. Returns the signal count of the undelivered
+// records dropped.
+func (s *spool) compact(now time.Time) (dropped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked(now)
+}
+
+func (s *spool) compactLocked(now time.Time) (dropped int, err error) {
+	segments, err := s.segments()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	kept := segments[:0]
+	for _, seg := range segments {
+		if s.limits.MaxAge > 0 && now.Sub(seg.modTime) > s.limits.MaxAge {
+			n, err := s.remove(seg)
+			dropped += n
+			if err != nil {
+				return dropped, err
+			}
+			continue
+		}
+		total += seg.size
+		kept = append(kept, seg)
+	}
+
+	for _, seg := range kept {
+		if s.limits.MaxBytes <= 0 || total <= s.limits.MaxBytes {
+			break
+		}
+		n, err := s.remove(seg)
+		dropped += n
+		if err != nil {
+			return dropped, err
+		}
+		total -= seg.size
+	}
+
+	return dropped, nil
+}
+
+// Returns the spool segments, oldest first. Other files are ignored.
+func (s *spool) segments() ([]spoolSegment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]spoolSegment, 0, len(entries))
+	for _, entry := range entries {
+		if !isSpoolSegmentName(entry.Name()) || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// Removed concurrently
+			continue
+		}
+		segments = append(segments, spoolSegment{
+			name:    entry.Name(),
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		})
+	}
+
+	sort.Slice(segments, func(i, j int) bool { return segments[i].name < segments[j].name })
+	return segments, nil
+}
+
+// Reports whether the file name is that of a segment, i.e. a creation
+// time in Unix nanoseconds followed by the segment extension.
+func isSpoolSegmentName(name string) bool {
+	base, ok := strings.CutSuffix(name, spoolSegmentExt)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseInt(base, 10, 64)
+	return err == nil
+}
+
+// Returns the records of the segment that have not been delivered yet.
+// A torn record at the end of the segment is ignored.
+func (s *spool) pending(seg spoolSegment) ([]spoolRecord, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, seg.name))
+	if err != nil {
+		return nil, err
+	}
+
+	offset := s.ackOffset(seg.name)
+	records, _ := decodeSpoolRecords(data)
+	for i, record := range records {
+		if record.end > offset {
+			return records[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+// Returns the offset up to which the records of the segment have been
+// delivered.
+func (s *spool) ackOffset(name string) int64 {
+	data, err := os.ReadFile(filepath.Join(s.dir, name+spoolAckExt))
+	if err != nil {
+		return 0
+	}
+	offset, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return offset
+}
+
+// Records that the records of the segment have been delivered up to the
+// given offset. The offset is written to a temporary file first and
+// renamed, so that it's never torn. Nothing is recorded if the segment has
+// been removed in the meantime.
+func (s *spool) ack(name string, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(filepath.Join(s.dir, name)); os.IsNotExist(err) {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(strconv.FormatInt(offset, 10)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name+spoolAckExt)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Removes the segment and its acknowledgement file. Returns the signal
+// count of the undelivered records removed. Segments removed concurrently
+// are not an error. Must be called with s.mu held.
+func (s *spool) remove(seg spoolSegment) (int, error) {
+	if seg.name == s.activeName {
+		s.closeActive()
+	}
+
+	count := 0
+	if records, err := s.pending(seg); err == nil {
+		for _, record := range records {
+			count += record.count
+		}
+	}
+
+	err := os.Remove(filepath.Join(s.dir, seg.name))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	os.Remove(filepath.Join(s.dir, seg.name+spoolAckExt))
+	return count, nil
+}
+
+// Removes the segment after all its records have been delivered. Returns
+// the signal count of records removed without being delivered, which
+// should be none.
+func (s *spool) removeDelivered(seg spoolSegment) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(seg)
+}
+
+// Returns the encoded record: payload length and CRC-32C checksum of the
+// payload, followed by the payload consisting of the creation time, signal
+// count, flags, the signal IDs if any, and the body. There must be either
+// no signal IDs or one per signal.
+func encodeSpoolRecord(created time.Time, count int, compressed bool, signalIDs []uuid.UUID, body []byte) []byte {
+	idsSize := len(signalIDs) * len(uuid.UUID{})
+	payloadSize := spoolRecordMetaSize + idsSize + len(body)
+	record := make([]byte, spoolRecordHeaderSize+payloadSize)
+
+	payload := record[spoolRecordHeaderSize:]
+	binary.LittleEndian.PutUint64(payload[0:8], uint64(created.UnixNano()))
+	binary.LittleEndian.PutUint32(payload[8:12], uint32(count))
+	if compressed {
+		payload[12] |= spoolFlagCompressed
+	}
+	if len(signalIDs) > 0 {
+		payload[12] |= spoolFlagSignalIDs
+		for i, id := range signalIDs {
+			copy(payload[spoolRecordMetaSize+i*len(id):], id[:])
+		}
+	}
+	copy(payload[spoolRecordMetaSize+idsSize:], body)
+
+	binary.LittleEndian.PutUint32(record[0:4], uint32(payloadSize))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(payload, spoolChecksumTable))
+	return record
+}
+
+// Decodes the records of a segment, stopping at the first record that is
+// incomplete or doesn't match its checksum. Returns the decoded records and
+// the size of the valid data.
+func decodeSpoolRecords(data []byte) (records []spoolRecord, valid int64) {
+	for offset := 0; ; {
+		rest := data[offset:]
+		if len(rest) < spoolRecordHeaderSize {
+			return records, int64(offset)
+		}
+
+		size := int(binary.LittleEndian.Uint32(rest[0:4]))
+		checksum := binary.LittleEndian.Uint32(rest[4:8])
+		if size < spoolRecordMetaSize || size > len(rest)-spoolRecordHeaderSize {
+			return records, int64(offset)
+		}
+		payload := rest[spoolRecordHeaderSize : spoolRecordHeaderSize+size]
+		if crc32.Checksum(payload, spoolChecksumTable) != checksum {
+			return records, int64(offset)
+		}
+
+		record := spoolRecord{
+			created:    time.Unix(0, int64(binary.LittleEndian.Uint64(payload[0:8]))),
+			count:      int(binary.LittleEndian.Uint32(payload[8:12])),
+			compressed: payload[12]&spoolFlagCompressed != 0,
+			body:       payload[spoolRecordMetaSize:],
+		}
+		if payload[12]&spoolFlagSignalIDs != 0 {
+			idsSize := record.count * len(uuid.UUID{})
+			if idsSize > len(record.body) {
+				return records, int64(offset)
+			}
+			record.signalIDs = make([]uuid.UUID, record.count)
+			for i := range record.signalIDs {
+				copy(record.signalIDs[i][:], record.body[i*len(uuid.UUID{}):])
+			}
+			record.body = record.body[idsSize:]
+		}
+
+		offset += spoolRecordHeaderSize + size
+		record.end = int64(offset)
+		records = append(records, record)
+	}
+}
+
+// Persists the delivery in the spool, if one is configured. Returns false
+// if the delivery could not be spooled.
+func (c *Client) spoolDelivery(d delivery) bool {
+	if c.spool == nil {
+		return false
+	}
+
+	dropped, err := c.spool.write(d, c.clock.Now())
+	c.stats.recordDrops(dropped)
+	if err != nil {
+		c.log(LogSubsystemSpool, LogLevelError, "error spooling signals", "count", d.count, "error", err)
+		return false
+	}
+	return true
+}
+
+// Starts delivering the spooled signals in the background, unless a replay
+// is already in progress or the client discards signals, see Disable.
+func (c *Client) startReplay() {
+	if c.spool == nil || c.closed.Load() || c.discardsSignals() {
+		return
+	}
+	if !c.spool.replaying.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer c.spool.replaying.Store(false)
+		c.replaySpool()
+	}()
+}
+
+// Delivers the spooled signals, oldest first, removing segments once all
+// their records are delivered. Stops at the first delivery failing
+// temporarily, leaving the remaining records for the next replay.
+func (c *Client) replaySpool() {
+	dropped, err := c.spool.compact(c.clock.Now())
+	c.stats.recordDrops(dropped)
+	if err != nil {
+		c.log(LogSubsystemSpool, LogLevelError, "error compacting spool", "error", err)
+		return
+	}
+
+	segments, err := c.spool.sealedSegments()
+	if err != nil {
+		c.log(LogSubsystemSpool, LogLevelError, "error reading spool", "error", err)
+		return
+	}
+
+	for _, seg := range segments {
+		if !c.replaySegment(seg) {
+			return
+		}
+	}
+}
+
+// Delivers the decrypted body of the spooled record, unless its signals
+// have been delivered before a crash (see WithSpoolDedupWindow). Returns
+// false if replaying should stop, leaving the record spooled, e.g. because
+// the client has been disabled meanwhile.
+func (c *Client) replayRecord(record spoolRecord, body []byte) bool {
+	if c.discardsSignals() {
+		return false
+	}
+	dedup := c.spool.dedupWindow > 0 && len(record.signalIDs) > 0
+	if dedup {
+		delivered, err := c.spool.wereReplayed(record.signalIDs, c.clock.Now())
+		if err != nil {
+			c.log(LogSubsystemSpool, LogLevelError, "error reading replay journal", "error", err)
+			return false
+		}
+		if delivered {
+			c.log(LogSubsystemSpool, LogLevelInfo, "skipping spooled signals delivered before", "count", record.count)
+			return true
+		}
+	}
+
+	// Journaled before sending, so that the signals aren't sent again if
+	// the process crashes before the record is acknowledged
+	if dedup {
+		if err := c.spool.recordReplayed(record.signalIDs, c.clock.Now()); err != nil {
+			c.log(LogSubsystemSpool, LogLevelError, "error updating replay journal", "error", err)
+			return false
+		}
+	}
+
+	d := delivery{body: body, count: record.count, compressed: record.compressed}
+	_, err := c.submit(context.Background(), d)
+	if err != nil && dedup {
+		if err := c.spool.forgetReplayed(record.signalIDs); err != nil {
+			c.log(LogSubsystemSpool, LogLevelError, "error updating replay journal", "error", err)
+		}
+	}
+	if err != nil && isRetryable(err) {
+		return false
+	}
+	if err != nil {
+		c.reportFailure(err, record.count)
+	}
+	return true
+}
+
+// Delivers the pending records of the segment. Returns false if replaying
+// should stop.
+func (c *Client) replaySegment(seg spoolSegment) bool {
+	records, err := c.spool.pending(seg)
+	if os.IsNotExist(err) {
+		return true
+	}
+	if err != nil {
+		c.log(LogSubsystemSpool, LogLevelError, "error reading spooled signals", "segment", seg.name, "error", err)
+		return false
+	}
+
+	for _, record := range records {
+		body, err := c.spool.open(seg.name, record.body)
+		if err != nil {
+			c.log(LogSubsystemSpool, LogLevelWarn, "dropping spooled signals", "count", record.count, "error", err)
+			c.stats.recordDrops(record.count)
+		} else if !c.replayRecord(record, body) {
+			return false
+		}
+
+		if err := c.spool.ack(seg.name, record.end); err != nil {
+			c.log(LogSubsystemSpool, LogLevelError, "error acknowledging spooled signals", "segment", seg.name, "error", err)
+			return false
+		}
+	}
+
+	dropped, err := c.spool.removeDelivered(seg)
+	if dropped > 0 {
+		c.log(LogSubsystemSpool, LogLevelWarn, "dropped undelivered spooled signals", "segment", seg.name, "count", dropped)
+		c.stats.recordDrops(dropped)
+	}
+	if err != nil {
+		c.log(LogSubsystemSpool, LogLevelError, "error removing spooled signals", "segment", seg.name, "error", err)
+		return false
+	}
+	return true
+}
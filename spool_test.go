@@ -0,0 +1,421 @@
+package telemetrydeck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_isSpoolSegmentName(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want bool
+	}{
+		{name: "segment", file: "00000000000000000042.wal", want: true},
+		{name: "acknowledgement", file: "00000000000000000042.wal.ack", want: false},
+		{name: "temporary file", file: ".tmp-123456", want: false},
+		{name: "other file", file: "README.wal", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSpoolSegmentName(tt.file); got != tt.want {
+				t.Errorf("isSpoolSegmentName(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decodeSpoolRecords(t *testing.T) {
+	created := time.Unix(0, 42)
+	first := encodeSpoolRecord(created, 3, true, nil, []byte("first"))
+	second := encodeSpoolRecord(created, 1, false, nil, []byte("second"))
+	data := append(append([]byte{}, first...), second...)
+
+	records, valid := decodeSpoolRecords(data)
+	if len(records) != 2 || valid != int64(len(data)) {
+		t.Fatalf("decodeSpoolRecords() = %d records, %d valid bytes, want 2, %d", len(records), valid, len(data))
+	}
+	want := spoolRecord{end: int64(len(first)), created: created, count: 3, compressed: true, body: []byte("first")}
+	if !reflect.DeepEqual(records[0], want) {
+		t.Errorf("decodeSpoolRecords()[0] = %+v, want %+v", records[0], want)
+	}
+
+	// Torn or corrupt second record
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	for name, data := range map[string][]byte{
+		"torn":    data[:len(data)-3],
+		"corrupt": corrupt,
+	} {
+		records, valid := decodeSpoolRecords(data)
+		if len(records) != 1 || valid != int64(len(first)) {
+			t.Errorf("decodeSpoolRecords(%s) = %d records, %d valid bytes, want 1, %d", name, len(records), valid, len(first))
+		}
+	}
+}
+
+func Test_spool_recover(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, SpoolLimits{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.write(delivery{body: []byte("body"), count: 1}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.sealActive()
+
+	// Simulate a crash in the middle of writing the third record
+	segments, err := s.segments()
+	if err != nil || len(segments) != 1 {
+		t.Fatalf("segments() = %v, %v, want one segment", segments, err)
+	}
+	path := filepath.Join(dir, segments[0].name)
+	if err := os.Truncate(path, segments[0].size-2); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = openSpool(dir, SpoolLimits{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := s.pending(segments[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Errorf("%d records recovered, want 2", len(records))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != records[len(records)-1].end {
+		t.Errorf("segment size after recovery = %d, want %d", info.Size(), records[len(records)-1].end)
+	}
+
+	// Acknowledged records are not pending anymore
+	if err := s.ack(segments[0].name, records[0].end); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := s.pending(segments[0]); len(records) != 1 {
+		t.Errorf("%d records pending after acknowledgement, want 1", len(records))
+	}
+}
+
+func Test_spool_compact(t *testing.T) {
+	now := time.Now()
+	body := make([]byte, 100)
+
+	tests := []struct {
+		name        string
+		limits      SpoolLimits
+		ages        []time.Duration // of the segments written, oldest first
+		wantDropped int
+		wantKept    int
+	}{
+		{
+			name:     "no limits",
+			ages:     []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour},
+			wantKept: 3,
+		},
+		{
+			name:        "expired",
+			limits:      SpoolLimits{MaxAge: 90 * time.Minute},
+			ages:        []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour},
+			wantDropped: 2,
+			wantKept:    1,
+		},
+		{
+			name:        "total size",
+			limits:      SpoolLimits{MaxBytes: 250},
+			ages:        []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour},
+			wantDropped: 1,
+			wantKept:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Write one segment per record without limits, then compact
+			// with the limits under test
+			dir := t.TempDir()
+			s, err := openSpool(dir, SpoolLimits{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, age := range tt.ages {
+				if _, err := s.write(delivery{body: body, count: 1}, now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+				name := s.activeName
+				s.sealActive()
+				if err := os.Chtimes(filepath.Join(dir, name), now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s.limits = tt.limits
+			dropped, err := s.compact(now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped != tt.wantDropped {
+				t.Errorf("compact() = %d, want %d", dropped, tt.wantDropped)
+			}
+
+			segments, err := s.segments()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(segments) != tt.wantKept {
+				t.Fatalf("%d segments kept, want %d", len(segments), tt.wantKept)
+			}
+			// The newest segment must survive
+			if tt.wantKept > 0 {
+				newest := fmt.Sprintf("%020d%s", now.Add(-tt.ages[len(tt.ages)-1]).UnixNano(), spoolSegmentExt)
+				if got := segments[len(segments)-1].name; got != newest {
+					t.Errorf("newest segment = %s, want %s", got, newest)
+				}
+			}
+		})
+	}
+}
+
+func Test_spool_write(t *testing.T) {
+	s, err := openSpool(t.TempDir(), SpoolLimits{MaxSegmentBytes: 100}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Too large to be spooled
+	dropped, err := s.write(delivery{body: make([]byte, 101), count: 2}, time.Now())
+	if err != nil || dropped != 2 {
+		t.Errorf("write() = %d, %v, want 2, nil", dropped, err)
+	}
+
+	// Segments are rotated when full
+	for i := 0; i < 4; i++ {
+		if _, err := s.write(delivery{body: make([]byte, 40), count: 1}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segments, err := s.segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 2 {
+		t.Errorf("%d segments, want 2", len(segments))
+	}
+}
+
+func Test_spool_sealedSegments(t *testing.T) {
+	s, err := openSpool(t.TempDir(), SpoolLimits{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, err := s.write(delivery{body: []byte("first"), count: 1}, now); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.sealedSegments()
+	if err != nil || len(sealed) != 1 {
+		t.Fatalf("sealedSegments() = %v, %v, want one segment", sealed, err)
+	}
+
+	// Signals spooled while replaying don't go to a sealed segment
+	if _, err := s.write(delivery{body: []byte("second"), count: 2}, now); err != nil {
+		t.Fatal(err)
+	}
+	if s.activeName == sealed[0].name {
+		t.Fatalf("write() appended to sealed segment %s", sealed[0].name)
+	}
+
+	// Undelivered records are reported when removing a segment
+	if dropped, err := s.removeDelivered(sealed[0]); err != nil || dropped != 1 {
+		t.Errorf("removeDelivered() = %d, %v, want 1 undelivered signal", dropped, err)
+	}
+	segments, err := s.segments()
+	if err != nil || len(segments) != 1 || segments[0].name != s.activeName {
+		t.Fatalf("segments() = %v, %v, want active segment only", segments, err)
+	}
+	if records, err := s.pending(segments[0]); err != nil || len(records) != 1 || records[0].count != 2 {
+		t.Errorf("pending() = %+v, %v, want second record", records, err)
+	}
+}
+
+func Test_spool_encryption(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{1}, 32)
+	body := []byte(`[{"type":"secret"}]`)
+
+	s, err := openSpool(dir, SpoolLimits{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.write(delivery{body: body, count: 1}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.sealActive()
+	segments, err := s.segments()
+	if err != nil || len(segments) != 1 {
+		t.Fatalf("segments() = %v, %v, want one segment", segments, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, segments[0].name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Errorf("spooled segment contains plaintext: %q", data)
+	}
+
+	records, err := s.pending(segments[0])
+	if err != nil || len(records) != 1 {
+		t.Fatalf("pending() = %v, %v, want one record", records, err)
+	}
+	got, err := s.open(segments[0].name, records[0].body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("open() = %q, want %q", got, body)
+	}
+
+	// A different key can't decrypt the segment
+	other, err := openSpool(dir, SpoolLimits{}, bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.open(segments[0].name, records[0].body); !errors.Is(err, errSpoolSegmentCorrupt) {
+		t.Errorf("open() with other key error = %v, want %v", err, errSpoolSegmentCorrupt)
+	}
+
+	if _, err := openSpool(dir, SpoolLimits{}, []byte("short")); err == nil {
+		t.Error("openSpool() with invalid key error = nil, want error")
+	}
+}
+
+func TestClient_Spool(t *testing.T) {
+	dir := t.TempDir()
+
+	var available atomic.Bool
+	var bodies atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.Copy(io.Discard, r.Body)
+		bodies.Add(1)
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 1}
+	client, err := NewClient("app", WithEndpoint(server.URL), WithRetryPolicy(policy), WithSpoolDir(dir), deliverImmediately)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendSignal(context.Background(), "test", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		segments, _ := client.spool.segments()
+		return len(segments) == 1
+	})
+	if got := client.Stats().Failures; got != 0 {
+		t.Errorf("Stats().Failures = %d, want 0 for spooled signals", got)
+	}
+
+	// A new client replays the spool once the endpoint is available again
+	available.Store(true)
+	client, err = NewClient("app", WithEndpoint(server.URL), WithRetryPolicy(policy), WithSpoolDir(dir), deliverImmediately)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		segments, _ := client.spool.segments()
+		return len(segments) == 0
+	})
+	if got := bodies.Load(); got != 1 {
+		t.Errorf("%d bodies received, want 1", got)
+	}
+}
+
+func TestClient_SpoolDisabled(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, SpoolLimits{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.write(delivery{body: []byte("[]"), count: 1}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.sealActive()
+
+	var bodies atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		bodies.Add(1)
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvDisabled, "1")
+	client, err := NewClient("app", WithEndpoint(server.URL), WithSpoolDir(dir), deliverImmediately)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.spool.replaying.Load() {
+		t.Error("spool replayed by disabled client")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := bodies.Load(); got != 0 {
+		t.Fatalf("%d bodies received from disabled client, want 0", got)
+	}
+
+	// Spooled signals are kept until the client is enabled
+	client.Enable()
+	waitFor(t, func() bool {
+		segments, _ := client.spool.segments()
+		return len(segments) == 0
+	})
+	if got := bodies.Load(); got != 1 {
+		t.Errorf("%d bodies received after Enable(), want 1", got)
+	}
+}
+
+func TestNewClient_SpoolDirError(t *testing.T) {
+	file := t.TempDir() + "/file"
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient("app", WithSpoolDir(file+"/spool")); err == nil {
+		t.Error("NewClient() error = nil, want error for invalid spool directory")
+	}
+}
+
+// Polls the condition until it's true, failing the test after a while.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
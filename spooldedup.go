@@ -0,0 +1,140 @@
+package telemetrydeck
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Name of the file in the spool directory listing the IDs of the spooled
+// signals sent by a replay, see WithSpoolDedupWindow.
+const spoolJournalName = "replay.journal"
+
+// WithSpoolDedupWindow makes the client assign an ID to every signal it
+// spools (see WithSpoolDir), and journal the IDs of spooled signals before
+// sending them when replaying the spool, for the duration of the window.
+// If the process crashes after spooled signals have been sent, but before
+// their delivery has been recorded in the spool, the next client within
+// the window doesn't send them again, so that they aren't counted twice.
+// This prefers losing signals over counting them twice: signals whose
+// request was in flight when the process crashed are not sent again.
+// Disabled by default. Signals spooled without the option enabled are
+// replayed as usual.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSpoolDedupWindow(window time.Duration) func(*Client) {
+	return func(c *Client) {
+		if window >= 0 {
+			c.spoolDedupWindow = window
+		}
+	}
+}
+
+// Returns whether all signals with the IDs have been sent by a replay
+// within the window.
+func (s *spool) wereReplayed(signalIDs []uuid.UUID, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadJournal(now); err != nil {
+		return false, err
+	}
+	for _, id := range signalIDs {
+		if _, ok := s.replayed[id]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Records that the signals with the IDs are sent by a replay, before
+// sending them.
+func (s *spool) recordReplayed(signalIDs []uuid.UUID, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadJournal(now); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, id := range signalIDs {
+		fmt.Fprintf(&buf, "%s\t%d\n", id, now.UnixNano())
+		s.replayed[id] = now
+	}
+	return appendFile(filepath.Join(s.dir, spoolJournalName), buf.Bytes())
+}
+
+// Removes the signals with the IDs from the journal after sending them
+// failed, so that they are sent again by the next replay.
+func (s *spool) forgetReplayed(signalIDs []uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range signalIDs {
+		delete(s.replayed, id)
+	}
+	return s.writeJournal()
+}
+
+// Reads the journal on first use, and forgets the signals replayed before
+// the window, rewriting the journal without them. Must be called with s.mu
+// held.
+func (s *spool) loadJournal(now time.Time) error {
+	path := filepath.Join(s.dir, spoolJournalName)
+	if s.replayed == nil {
+		s.replayed = map[uuid.UUID]time.Time{}
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			id, replayed, _ := strings.Cut(scanner.Text(), "\t")
+			parsed, err := uuid.Parse(id)
+			nanos, nanosErr := strconv.ParseInt(replayed, 10, 64)
+			if err != nil || nanosErr != nil {
+				// Torn by a crash while appending
+				continue
+			}
+			s.replayed[parsed] = time.Unix(0, nanos)
+		}
+	}
+
+	var expired bool
+	for id, replayed := range s.replayed {
+		if now.Sub(replayed) >= s.dedupWindow {
+			delete(s.replayed, id)
+			expired = true
+		}
+	}
+	if !expired {
+		return nil
+	}
+	return s.writeJournal()
+}
+
+// Replaces the journal by the signals in s.replayed, removing it if there
+// are none. Must be called with s.mu held.
+func (s *spool) writeJournal() error {
+	path := filepath.Join(s.dir, spoolJournalName)
+	if len(s.replayed) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	for id, replayed := range s.replayed {
+		fmt.Fprintf(&buf, "%s\t%d\n", id, replayed.UnixNano())
+	}
+	return writeFileAtomically(path, buf.Bytes())
+}
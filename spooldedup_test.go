@@ -0,0 +1,215 @@
+package telemetrydeck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_decodeSpoolRecords_signalIDs(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	data := encodeSpoolRecord(time.Unix(0, 42), 2, true, ids, []byte("body"))
+
+	records, valid := decodeSpoolRecords(data)
+	if len(records) != 1 || valid != int64(len(data)) {
+		t.Fatalf("decodeSpoolRecords() = %d records, %d valid bytes, want 1, %d", len(records), valid, len(data))
+	}
+	want := spoolRecord{end: int64(len(data)), created: time.Unix(0, 42), count: 2, compressed: true, signalIDs: ids, body: []byte("body")}
+	if !reflect.DeepEqual(records[0], want) {
+		t.Errorf("decodeSpoolRecords()[0] = %+v, want %+v", records[0], want)
+	}
+}
+
+func Test_spool_replayJournal(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *spool {
+		t.Helper()
+		s, err := openSpool(dir, SpoolLimits{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.dedupWindow = time.Hour
+		return s
+	}
+	now := time.Now()
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+
+	s := open()
+	if replayed, err := s.wereReplayed(ids, now); err != nil || replayed {
+		t.Errorf("wereReplayed() before delivery = %v, %v, want false, nil", replayed, err)
+	}
+	if err := s.recordReplayed(ids, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// The journal survives a crash
+	s = open()
+	if replayed, err := s.wereReplayed(ids, now.Add(time.Minute)); err != nil || !replayed {
+		t.Errorf("wereReplayed() within window = %v, %v, want true, nil", replayed, err)
+	}
+	if replayed, err := s.wereReplayed(append(ids, uuid.New()), now); err != nil || replayed {
+		t.Errorf("wereReplayed() with other signal = %v, %v, want false, nil", replayed, err)
+	}
+	if replayed, err := s.wereReplayed(ids, now.Add(time.Hour)); err != nil || replayed {
+		t.Errorf("wereReplayed() after window = %v, %v, want false, nil", replayed, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, spoolJournalName)); !os.IsNotExist(err) {
+		t.Errorf("journal exists after window, stat error = %v", err)
+	}
+
+	// Signals whose delivery failed are forgotten
+	if err := s.recordReplayed(ids, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.forgetReplayed(ids[:1]); err != nil {
+		t.Fatal(err)
+	}
+	s = open()
+	if replayed, err := s.wereReplayed(ids[:1], now); err != nil || replayed {
+		t.Errorf("wereReplayed() after forgetReplayed() = %v, %v, want false, nil", replayed, err)
+	}
+	if replayed, err := s.wereReplayed(ids[1:], now); err != nil || !replayed {
+		t.Errorf("wereReplayed() of other signal = %v, %v, want true, nil", replayed, err)
+	}
+}
+
+func TestClient_SpoolDedupWindow(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		withIDs    bool
+		delivered  bool
+		wantBodies int32
+	}{
+		{name: "replayed", withIDs: true, wantBodies: 1},
+		{name: "delivered before crash", withIDs: true, delivered: true, wantBodies: 0},
+		{name: "spooled without IDs", wantBodies: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := openSpool(dir, SpoolLimits{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.withIDs {
+				s.dedupWindow = time.Hour
+			}
+			if _, err := s.write(delivery{body: []byte(`[]`), count: 1}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			s.sealActive()
+			segments, _ := s.segments()
+			records, _ := s.pending(segments[0])
+			if got := len(records[0].signalIDs); tt.withIDs && got != 1 {
+				t.Fatalf("%d signal IDs spooled, want 1", got)
+			}
+			if tt.delivered {
+				// Crashed after journaling the signal, before acknowledging it
+				if err := s.recordReplayed(records[0].signalIDs, time.Now()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var bodies atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+				bodies.Add(1)
+			}))
+			defer server.Close()
+
+			client, err := NewClient("app", WithEndpoint(server.URL), WithSpoolDir(dir), WithSpoolDedupWindow(time.Hour), deliverImmediately)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close(context.Background())
+			waitFor(t, func() bool {
+				segments, _ := client.spool.segments()
+				return len(segments) == 0
+			})
+			if got := bodies.Load(); got != tt.wantBodies {
+				t.Errorf("%d bodies received, want %d", got, tt.wantBodies)
+			}
+
+			// Delivered signals are remembered within the window
+			if tt.withIDs {
+				replayed, err := client.spool.wereReplayed(records[0].signalIDs, time.Now())
+				if err != nil || !replayed {
+					t.Errorf("wereReplayed() after replay = %v, %v, want true, nil", replayed, err)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_SpoolDedupWindow_crashAfterSend(t *testing.T) {
+	dir := t.TempDir()
+	s, err := openSpool(dir, SpoolLimits{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.dedupWindow = time.Hour
+	if _, err := s.write(delivery{body: []byte(`[]`), count: 1}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.sealActive()
+
+	// The first client crashes while the server handles its request: the
+	// spool is copied in the state it has on disk at that moment
+	crashed := t.TempDir()
+	release := make(chan struct{})
+	var bodies atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if bodies.Add(1) == 1 {
+			entries, _ := os.ReadDir(dir)
+			for _, entry := range entries {
+				data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+				if err == nil {
+					err = os.WriteFile(filepath.Join(crashed, entry.Name()), data, 0o600)
+				}
+				if err != nil {
+					t.Error(err)
+				}
+			}
+			<-release
+		}
+	}))
+	defer server.Close()
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	defer unblock()
+
+	first, err := NewClient("app", WithEndpoint(server.URL), WithSpoolDir(dir), WithSpoolDedupWindow(time.Hour), deliverImmediately)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return bodies.Load() == 1 })
+
+	client, err := NewClient("app", WithEndpoint(server.URL), WithSpoolDir(crashed), WithSpoolDedupWindow(time.Hour), deliverImmediately)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		segments, _ := client.spool.segments()
+		return len(segments) == 0
+	})
+	if got := bodies.Load(); got != 1 {
+		t.Errorf("%d bodies received, want signals sent before the crash not to be sent again", got)
+	}
+
+	// Let the first client finish before its spool is removed
+	unblock()
+	waitFor(t, func() bool {
+		segments, _ := first.spool.segments()
+		return len(segments) == 0
+	})
+}
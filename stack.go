@@ -0,0 +1,101 @@
+package telemetrydeck
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Maximum number of frames captured by CaptureStack.
+const maxStackFrames = 64
+
+// StackFrame is a frame of a captured stack trace.
+type StackFrame struct {
+	// Fully qualified function name, e.g. "main.(*server).handle"
+	Function string
+	File     string
+	Line     int
+}
+
+// String returns the function name followed by the file base name and
+// line, e.g. "main.(*server).handle (server.go:42)".
+func (f StackFrame) String() string {
+	return f.Function + " (" + filepath.Base(f.File) + ":" + strconv.Itoa(f.Line) + ")"
+}
+
+// StackTrace is a stack trace captured by CaptureStack, innermost frame
+// first.
+type StackTrace []StackFrame
+
+// CaptureStack captures the stack of the calling goroutine, for crash and
+// error signals. Frames of the Go runtime are trimmed. skip is the number
+// of additional frames to skip, 0 identifying the caller of CaptureStack.
+func CaptureStack(skip int) StackTrace {
+	var pcs [maxStackFrames]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	return stackTraceFromPCs(pcs[:n])
+}
+
+// Returns the stack trace of the program counters, as returned by
+// runtime.Callers, without frames of the Go runtime.
+func stackTraceFromPCs(pcs []uintptr) StackTrace {
+	if len(pcs) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(pcs)
+
+	var stack StackTrace
+	for {
+		frame, more := frames.Next()
+		if !isRuntimeFunction(frame.Function) {
+			stack = append(stack, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			return stack
+		}
+	}
+}
+
+// Reports whether the function belongs to the Go runtime, like
+// runtime.goexit or runtime.gopanic.
+func isRuntimeFunction(name string) bool {
+	return strings.HasPrefix(name, "runtime.")
+}
+
+// TopFrame returns a short human-readable description of the innermost
+// frame, e.g. "main.(*server).handle (server.go:42)", or an empty string
+// if the stack trace is empty.
+func (s StackTrace) TopFrame() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0].String()
+}
+
+// Fingerprint returns a hash of the functions of the stack trace, for
+// grouping signals of the same crash or error. Line numbers are not
+// included, so that the fingerprint is stable across unrelated code
+// changes.
+func (s StackTrace) Fingerprint() string {
+	h := sha256.New()
+	for _, frame := range s {
+		h.Write([]byte(frame.Function))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// String returns the frames of the stack trace, one per line.
+func (s StackTrace) String() string {
+	var b strings.Builder
+	for i, frame := range s {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.String())
+	}
+	return b.String()
+}
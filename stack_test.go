@@ -0,0 +1,57 @@
+package telemetrydeck
+
+import (
+	"strings"
+	"testing"
+)
+
+//go:noinline
+func captureStackHelper() StackTrace {
+	return CaptureStack(0)
+}
+
+func TestCaptureStack(t *testing.T) {
+	stack := captureStackHelper()
+	if len(stack) < 2 {
+		t.Fatalf("CaptureStack() = %v, want at least 2 frames", stack)
+	}
+	if !strings.HasSuffix(stack[0].Function, ".captureStackHelper") {
+		t.Errorf("CaptureStack()[0] = %v, want captureStackHelper", stack[0])
+	}
+	if !strings.HasSuffix(stack[1].Function, ".TestCaptureStack") {
+		t.Errorf("CaptureStack()[1] = %v, want TestCaptureStack", stack[1])
+	}
+	for _, frame := range stack {
+		if strings.HasPrefix(frame.Function, "runtime.") {
+			t.Errorf("CaptureStack() contains runtime frame %v", frame)
+		}
+	}
+
+	if top := stack.TopFrame(); !strings.HasPrefix(top, stack[0].Function+" (stack_test.go:") {
+		t.Errorf("TopFrame() = %q, want function and file", top)
+	}
+
+	// Skipping frames
+	if skipped := CaptureStack(1); len(skipped) == 0 || skipped[0].Function == stack[1].Function {
+		t.Errorf("CaptureStack(1)[0] = %v, want caller of the test", skipped.TopFrame())
+	}
+}
+
+func TestStackTrace_Fingerprint(t *testing.T) {
+	a := StackTrace{{Function: "main.a", File: "a.go", Line: 1}, {Function: "main.main", File: "main.go", Line: 10}}
+	moved := StackTrace{{Function: "main.a", File: "a.go", Line: 5}, {Function: "main.main", File: "main.go", Line: 12}}
+	other := StackTrace{{Function: "main.b", File: "b.go", Line: 1}, {Function: "main.main", File: "main.go", Line: 10}}
+
+	if a.Fingerprint() != moved.Fingerprint() {
+		t.Errorf("Fingerprint() differs for changed line numbers: %s, %s", a.Fingerprint(), moved.Fingerprint())
+	}
+	if a.Fingerprint() == other.Fingerprint() {
+		t.Errorf("Fingerprint() = %s for different functions", a.Fingerprint())
+	}
+	if len(a.Fingerprint()) != 16 {
+		t.Errorf("Fingerprint() = %q, want 16 hex digits", a.Fingerprint())
+	}
+	if got := StackTrace(nil).TopFrame(); got != "" {
+		t.Errorf("TopFrame() of empty stack = %q, want empty", got)
+	}
+}
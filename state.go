@@ -0,0 +1,158 @@
+package telemetrydeck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Payload key telling whether the signal was sent in the first session of
+// the user with the app, see WithStateFile.
+const FirstSessionKey = "TelemetryDeck.Session.isFirstSession"
+
+// Payload key of the number of times the app has been launched, see
+// WithLaunchCount.
+const LaunchCountKey = "TelemetryDeck.App.launchCount"
+
+// Serializes updates of state files by the clients of the process.
+var stateMu sync.Mutex
+
+// Contents of a state file, see WithStateFile.
+type stateFileData struct {
+	Apps map[string]*appState `json:"apps"`
+}
+
+// State persisted per app.
+type appState struct {
+	// Start of the first session of the app
+	FirstSeen time.Time `json:"firstSeen"`
+
+	// Number of clients created for the app, see WithLaunchCount
+	Launches int64 `json:"launches,omitempty"`
+
+	// Dates signals were last sent via SendDaily, by signal type
+	LastSent map[string]string `json:"lastSent,omitempty"`
+}
+
+// WithStateFile makes the client persist state across runs of the program
+// in a JSON file at the path, e.g. in the user's configuration directory,
+// which is created if it doesn't exist. Several clients, also of different
+// apps, may share the file, as long as they run in the same process. With
+// a state file, the payload of every signal includes FirstSessionKey,
+// telling whether it was sent in the first session of the app on the
+// machine, so that activation and retention can be told apart. SendDaily
+// requires a state file. NewClient returns an error if the file can't be
+// read or written.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithStateFile(path string) func(*Client) {
+	return func(c *Client) {
+		c.stateFile = path
+	}
+}
+
+// WithLaunchCount makes the client count the launches of the app, i.e.
+// the clients created for it, in the state file, and add the number,
+// including the current launch, to the payload of every signal under the
+// key LaunchCountKey, so that behavior on the first launch can be compared
+// to later ones. NewClient returns an error wrapping ErrNoStateFile if the
+// client has no state file (see WithStateFile).
+//
+// To be used as an option parameter in the NewClient() func.
+func WithLaunchCount() func(*Client) {
+	return func(c *Client) {
+		c.countLaunches = true
+	}
+}
+
+// Updates the state of the client's app in the state file via f.
+func (c *Client) updateState(f func(s *appState)) error {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	var data stateFileData
+	content, err := os.ReadFile(c.stateFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(content, &data); err != nil {
+			return fmt.Errorf("invalid state file %s: %w", c.stateFile, err)
+		}
+	}
+	if data.Apps == nil {
+		data.Apps = map[string]*appState{}
+	}
+	s := data.Apps[c.appID]
+	if s == nil {
+		s = &appState{}
+		data.Apps[c.appID] = s
+	}
+	f(s)
+
+	content, err = json.MarshalIndent(&data, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(c.stateFile, content)
+}
+
+// Writes the file via a temporary file that is renamed, so that it's
+// never torn.
+func writeFileAtomically(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Records the start of the first session of the app and, if enabled, the
+// launch in the state file, if any. Returns whether the current session is
+// the first one.
+func (c *Client) initState() (bool, error) {
+	if c.stateFile == "" {
+		if c.countLaunches {
+			return false, fmt.Errorf("%w: WithLaunchCount requires WithStateFile", ErrNoStateFile)
+		}
+		return false, nil
+	}
+	var first bool
+	err := c.updateState(func(s *appState) {
+		if s.FirstSeen.IsZero() {
+			first = true
+			s.FirstSeen = c.clock.Now().UTC()
+		}
+		if c.countLaunches {
+			s.Launches++
+			c.launchCount = s.Launches
+		}
+	})
+	if err != nil {
+		return false, fmt.Errorf("error updating state file: %w", err)
+	}
+	return first, nil
+}
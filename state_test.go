@@ -0,0 +1,65 @@
+package telemetrydeck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClient_StateFile_firstSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry", "state.json")
+	firstSession := func(appID string) interface{} {
+		t.Helper()
+		c, err := NewClient(appID, WithStateFile(path))
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		if got := c.newStringSignal("TestNamespace.stateTest", nil).stringPayload[FirstSessionKey]; got == "" {
+			t.Errorf("string payload lacks %s", FirstSessionKey)
+		}
+		return c.newSignal("TestNamespace.stateTest", nil).Payload[FirstSessionKey]
+	}
+
+	if got := firstSession("app-a"); got != true {
+		t.Errorf("first session = %v, want true", got)
+	}
+	if got := firstSession("app-a"); got != false {
+		t.Errorf("first session = %v in second run, want false", got)
+	}
+	if got := firstSession("app-b"); got != true {
+		t.Errorf("first session = %v for other app, want true", got)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient("app-a", WithStateFile(path)); err == nil {
+		t.Error("NewClient() with invalid state file returned no error")
+	}
+}
+
+func TestClient_LaunchCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	for want := int64(1); want <= 3; want++ {
+		c, err := NewClient("my-app-id", WithStateFile(path), WithLaunchCount())
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		if got := c.newSignal("TestNamespace.launchTest", nil).Payload[LaunchCountKey]; got != want {
+			t.Errorf("launch count = %v, want %d", got, want)
+		}
+	}
+
+	c, err := NewClient("other-app-id", WithStateFile(path), WithLaunchCount())
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if got := c.newStringSignal("TestNamespace.launchTest", nil).stringPayload[LaunchCountKey]; got != "1" {
+		t.Errorf("launch count = %q for other app, want 1", got)
+	}
+
+	if _, err := NewClient("my-app-id", WithLaunchCount()); !errors.Is(err, ErrNoStateFile) {
+		t.Errorf("NewClient() error = %v, want ErrNoStateFile", err)
+	}
+}
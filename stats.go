@@ -0,0 +1,182 @@
+package telemetrydeck
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Number of most recent request latencies used to compute percentiles.
+const latencyWindowSize = 1024
+
+// Stats holds statistics about the signal deliveries of a client.
+type Stats struct {
+	// Number of requests sent to the ingest endpoint, including failed ones.
+	Requests int
+
+	// Number of requests that were retries of failed requests.
+	Retries int
+
+	// Number of deliveries that failed permanently or after all retries.
+	Failures int
+
+	// Number of signals delivered successfully.
+	Delivered int
+
+	// Number of signals dropped without attempting delivery, because the
+	// queue was full (see WithQueueSize and WithMaxQueueBytes), or evicted
+	// from the spool (see WithSpoolLimits).
+	Dropped int
+
+	// Number of signals currently waiting in the queue.
+	Queued int
+
+	// Highest number of signals waiting in the queue so far.
+	PeakQueued int
+
+	// Size of the signals currently waiting for delivery.
+	PendingBytes int64
+
+	// Delivery latency percentiles over the most recent requests.
+	Latency LatencyStats
+}
+
+// LatencyStats holds percentiles of the time it took to send requests to
+// the ingest endpoint and receive the response, over a rolling window of
+// the most recent requests.
+type LatencyStats struct {
+	// Number of requests the percentiles are based on.
+	Samples int
+
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Collects delivery statistics. Safe for concurrent use.
+type statsCollector struct {
+	mu        sync.Mutex
+	requests  int
+	retries   int
+	failures  int
+	delivered int
+	dropped   int
+
+	// Receives the counters as well, if set
+	metrics Metrics
+
+	// Ring buffer of the most recent latencies
+	latencies [latencyWindowSize]time.Duration
+	next      int
+	samples   int
+}
+
+// Records a request that took the given time.
+func (s *statsCollector) recordRequest(latency time.Duration) {
+	s.add(MetricRequests, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	s.latencies[s.next] = latency
+	s.next = (s.next + 1) % latencyWindowSize
+	if s.samples < latencyWindowSize {
+		s.samples++
+	}
+}
+
+// Records that a failed request is going to be retried.
+func (s *statsCollector) recordRetry() {
+	s.add(MetricRetries, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries++
+}
+
+// Records a delivery that failed permanently or after all retries.
+func (s *statsCollector) recordFailure() {
+	s.add(MetricFailures, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+}
+
+// Records that n signals have been delivered successfully.
+func (s *statsCollector) recordDelivered(n int) {
+	s.add(MetricDelivered, int64(n))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered += n
+}
+
+// Records a dropped signal.
+func (s *statsCollector) recordDrop() {
+	s.recordDrops(1)
+}
+
+// Records n dropped signals.
+func (s *statsCollector) recordDrops(n int) {
+	if n == 0 {
+		return
+	}
+	s.add(MetricDropped, int64(n))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped += n
+}
+
+// Increments the counter of the metrics, if set.
+func (s *statsCollector) add(name string, delta int64) {
+	if s.metrics != nil {
+		s.metrics.Add(name, delta)
+	}
+}
+
+func (s *statsCollector) snapshot() Stats {
+	s.mu.Lock()
+	sorted := make([]time.Duration, s.samples)
+	copy(sorted, s.latencies[:s.samples])
+	stats := Stats{
+		Requests:  s.requests,
+		Retries:   s.retries,
+		Failures:  s.failures,
+		Delivered: s.delivered,
+		Dropped:   s.dropped,
+	}
+	s.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return stats
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.Latency = LatencyStats{
+		Samples: len(sorted),
+		P50:     percentile(sorted, 50),
+		P90:     percentile(sorted, 90),
+		P99:     percentile(sorted, 99),
+		Max:     sorted[len(sorted)-1],
+	}
+
+	return stats
+}
+
+// Returns the p-th percentile (nearest rank) of the sorted, non-empty slice.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Stats returns statistics about the signal deliveries of the client.
+func (c *Client) Stats() Stats {
+	stats := c.stats.snapshot()
+	stats.Queued = c.store.Len()
+	stats.PeakQueued = int(c.peakQueued.Load())
+	stats.PendingBytes = c.pendingBytes.Load()
+	return stats
+}
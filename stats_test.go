@@ -0,0 +1,81 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_statsCollector(t *testing.T) {
+	var s statsCollector
+
+	if got := s.snapshot(); got.Requests != 0 || got.Latency.Samples != 0 {
+		t.Errorf("empty snapshot() = %+v", got)
+	}
+
+	for i := 1; i <= 100; i++ {
+		s.recordRequest(time.Duration(i) * time.Millisecond)
+	}
+
+	got := s.snapshot()
+	want := Stats{
+		Requests: 100,
+		Latency: LatencyStats{
+			Samples: 100,
+			P50:     50 * time.Millisecond,
+			P90:     90 * time.Millisecond,
+			P99:     99 * time.Millisecond,
+			Max:     100 * time.Millisecond,
+		},
+	}
+	if got != want {
+		t.Errorf("snapshot() = %+v, want %+v", got, want)
+	}
+}
+
+func Test_statsCollector_RollingWindow(t *testing.T) {
+	var s statsCollector
+
+	for i := 0; i < latencyWindowSize; i++ {
+		s.recordRequest(time.Second)
+	}
+	for i := 0; i < latencyWindowSize; i++ {
+		s.recordRequest(time.Millisecond)
+	}
+
+	got := s.snapshot()
+	if got.Requests != 2*latencyWindowSize {
+		t.Errorf("Requests = %d, want %d", got.Requests, 2*latencyWindowSize)
+	}
+	if got.Latency.Samples != latencyWindowSize || got.Latency.Max != time.Millisecond {
+		t.Errorf("Latency = %+v, want only recent samples", got.Latency)
+	}
+}
+
+func TestClient_Stats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var hookDuration time.Duration
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithHooks(Hooks{OnResult: func(result IngestResult) { hookDuration = result.Duration }}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.statsTest", nil); err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+
+	stats := c.Stats()
+	if stats.Requests != 1 || stats.Latency.Samples != 1 {
+		t.Errorf("Client.Stats() = %+v, want one request", stats)
+	}
+	if hookDuration <= 0 || hookDuration != stats.Latency.Max {
+		t.Errorf("OnResult hook got duration %s, want %s", hookDuration, stats.Latency.Max)
+	}
+}
@@ -0,0 +1,101 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// QueuedSignal is a signal waiting for delivery in a QueueStore.
+type QueuedSignal struct {
+	// ID of the signal, unique within the store. Assigned by stores that
+	// need it to acknowledge signals, zero otherwise.
+	ID uint64
+
+	Signal SignalBody
+
+	// Bearer token the signal is delivered with. Stores may drop it, in
+	// which case a new token is requested on delivery.
+	Token string
+
+	// Estimated size of the encoded signal, zero if unknown.
+	size int
+
+	// Number of times the signal has been enqueued again after the
+	// endpoint rejected it temporarily. Kept by stores persisting signals
+	// via MarshalJSON.
+	requeues int
+
+	// Context passed when sending the signal, bounding its delivery. Not
+	// kept by persistent stores.
+	ctx context.Context
+}
+
+// Encoded form of a QueuedSignal, including the payload of signals sent
+// via SendStringSignal.
+type storedSignal struct {
+	ID            uint64            `json:"id"`
+	Signal        SignalBody        `json:"signal"`
+	StringPayload map[string]string `json:"stringPayload,omitempty"`
+	Requeues      int               `json:"requeues,omitempty"`
+}
+
+// MarshalJSON encodes the signal for stores persisting it. The token is not
+// encoded, so that it doesn't end up on disk.
+func (s QueuedSignal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(storedSignal{
+		ID:            s.ID,
+		Signal:        s.Signal,
+		StringPayload: s.Signal.stringPayload,
+		Requeues:      s.requeues,
+	})
+}
+
+// UnmarshalJSON decodes a signal encoded by MarshalJSON.
+func (s *QueuedSignal) UnmarshalJSON(data []byte) error {
+	var stored storedSignal
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	stored.Signal.stringPayload = stored.StringPayload
+	*s = QueuedSignal{ID: stored.ID, Signal: stored.Signal, requeues: stored.Requeues}
+	return nil
+}
+
+// QueueStore holds signals waiting for delivery. By default, signals are
+// kept in a fixed-size in-memory queue; other stores allow to back the
+// pipeline with a database or a shared local daemon, so that queued signals
+// survive restarts.
+//
+// Implementations must be safe for concurrent use. Signals are dequeued by
+// several workers at once.
+type QueueStore interface {
+	// Enqueue adds the signal to the store. It returns ErrQueueFull if the
+	// store has no room for it.
+	Enqueue(signal QueuedSignal) error
+
+	// DequeueBatch removes up to max signals from the store, oldest first,
+	// and returns them. It returns an empty batch if the store is empty.
+	// Dequeued signals must not be returned again by this store instance,
+	// but should be returned again after a restart unless acknowledged.
+	DequeueBatch(max int) ([]QueuedSignal, error)
+
+	// Ack is called with a dequeued batch once it has been delivered, or
+	// given up on, so that the store can discard it.
+	Ack(signals []QueuedSignal) error
+
+	// Len returns the number of signals in the store that have not been
+	// dequeued yet.
+	Len() int
+}
+
+// WithQueueStore allows to keep signals waiting for delivery in a store
+// other than the default in-memory queue, e.g. one returned by
+// NewDiskQueueStore. Signals left in the store by a previous client are
+// delivered when the client is created. The QueueFullDropOldest policy only
+// applies to the default queue; other stores reject signals when full.
+// To be used as an option parameter in the NewClient() func.
+func WithQueueStore(store QueueStore) func(*Client) {
+	return func(c *Client) {
+		c.store = store
+	}
+}
@@ -0,0 +1,203 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// QueueStore recording acknowledged signals, and failing if told to.
+type testStore struct {
+	*ringQueue
+
+	mu    sync.Mutex
+	acked []QueuedSignal
+	err   error
+}
+
+func (s *testStore) Enqueue(signal QueuedSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.ringQueue.Enqueue(signal)
+}
+
+func (s *testStore) Ack(signals []QueuedSignal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked = append(s.acked, signals...)
+	return nil
+}
+
+func TestClient_QueueStore(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		received.Add(int32(len(signals)))
+	}))
+	defer server.Close()
+
+	// Signals left in the store are delivered when the client is created
+	store := &testStore{ringQueue: newRingQueue(100)}
+	store.Enqueue(QueuedSignal{Signal: SignalBody{Type: "left"}})
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithQueueStore(store), WithQueueFullPolicy(QueueFullDropOldest))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.queue != nil {
+		t.Error("client uses the default queue, want custom store")
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.storeTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	if got := received.Load(); got != 2 {
+		t.Errorf("server received %d signals, want 2", got)
+	}
+	if len(store.acked) != 2 {
+		t.Errorf("%d signals acknowledged, want 2", len(store.acked))
+	}
+	if stats := c.Stats(); stats.Queued != 0 || stats.PendingBytes != 0 {
+		t.Errorf("Stats() = %+v, want empty queue", stats)
+	}
+
+	// Store errors are returned, and count as drops
+	errStore := errors.New("store unavailable")
+	store.err = errStore
+	if err := c.SendSignal(context.Background(), "TestNamespace.storeTest", nil); !errors.Is(err, errStore) {
+		t.Errorf("Client.SendSignal() error = %v, want %v", err, errStore)
+	}
+	if got := c.Stats().Dropped; got != 1 {
+		t.Errorf("Stats().Dropped = %d, want 1", got)
+	}
+}
+
+func TestClient_DiskQueueStore(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %q, want token from the token source", r.Header.Get("Authorization"))
+		}
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		received.Add(int32(len(signals)))
+	}))
+	defer server.Close()
+
+	store, err := NewDiskQueueStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithQueueStore(store), WithAuthToken("token"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.diskStoreTest", map[string]interface{}{"i": i}); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	if got := received.Load(); got != 20 {
+		t.Errorf("server received %d signals, want 20", got)
+	}
+}
+
+func TestClient_DiskQueueStore_CorruptRecord(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		received.Add(int32(len(signals)))
+	}))
+	defer server.Close()
+
+	// Left by a previous client: an undecodable signal before a valid one
+	dir := t.TempDir()
+	store, err := NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Enqueue(QueuedSignal{Signal: SignalBody{Type: "TestNamespace.diskStoreTest"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, store.activeName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(encodeSpoolRecord(time.Now(), 1, false, nil, []byte("{")), data...)
+	if err := os.WriteFile(filepath.Join(dir, store.activeName), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = NewDiskQueueStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithQueueStore(store))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	if got := received.Load(); got != 1 {
+		t.Errorf("server received %d signals, want 1", got)
+	}
+	if got := c.Stats().Dropped; got != 1 {
+		t.Errorf("Stats().Dropped = %d, want 1", got)
+	}
+}
+
+func TestQueuedSignal_JSON(t *testing.T) {
+	signal := QueuedSignal{
+		ID:     42,
+		Signal: SignalBody{Type: "TestNamespace.jsonTest", stringPayload: map[string]string{"key": "value"}},
+		Token:  "secret",
+	}
+	data, err := json.Marshal(signal)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("json.Marshal() = %s, want token not to be encoded", data)
+	}
+
+	var got QueuedSignal
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if got.ID != 42 || got.Signal.Type != signal.Signal.Type || got.Signal.stringPayload["key"] != "value" || got.Token != "" {
+		t.Errorf("json.Unmarshal() = %+v, want %+v without token", got, signal)
+	}
+}
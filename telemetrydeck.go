@@ -45,24 +45,27 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const (
-	// The TelemetryDeck Ingest v2 API endpoint we use
-	endpoint = "https://nom.telemetrydeck.com/v2/"
+	// The TelemetryDeck Ingest v2 API endpoint we use by default
+	DefaultEndpoint = "https://nom.telemetrydeck.com/v2/"
 
 	version = "telemetrydeck-go/0.0.1" // TODO: set this version via linker flags
 )
@@ -70,16 +73,69 @@ const (
 var (
 	ErrNoAppID      = errors.New("no app ID specified")
 	ErrNoSignalType = errors.New("no signal type specified")
+	ErrUnreachable  = errors.New("endpoint unreachable")
+	ErrInvalidAppID = errors.New("app ID is not a valid UUID")
+	ErrQueueFull    = errors.New("signal queue is full")
+	ErrClientClosed = errors.New("client is closed")
+	ErrReservedKey  = errors.New("payload key is reserved")
+
+	ErrUnsupportedAPIVersion = errors.New("unsupported ingest API version")
+	ErrSignalRejected        = errors.New("signal rejected by the endpoint")
+	ErrUnknownAppKey         = errors.New("no client for app key")
+	ErrNoRoute               = errors.New("no route matches the signal type")
+	ErrClientDisabled        = errors.New("client is disabled")
+	ErrNoStateFile           = errors.New("no state file configured")
+	ErrUnknownEnvironment    = errors.New("no app ID for environment")
+
+	ErrPolicyViolation = errors.New("signal violates the signal policy")
+	ErrSchemaViolation = errors.New("signal doesn't match its schema")
+)
+
+const (
+	// Maximum number of bytes of a response body we read.
+	maxResponseBodySize = 4096
+
+	// How long the round trip done by WithValidateOnCreate may take.
+	validateTimeout = 10 * time.Second
 )
 
+// ResponseError is returned when the ingest endpoint responded with an
+// HTTP error status, e.g. because the app ID was not accepted, or with a
+// redirect that was not followed.
+type ResponseError struct {
+	StatusCode int
+	Body       string
+
+	// Value of the X-Request-ID header sent with the request.
+	RequestID string
+
+	// Delay requested by the endpoint via the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *ResponseError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected response status %d (request ID %s)", e.StatusCode, e.RequestID)
+	}
+	return fmt.Sprintf("unexpected response status %d (request ID %s): %s", e.StatusCode, e.RequestID, e.Body)
+}
+
 // Client represents a TelemetryDeck client, configured to represent
 // one distinct user interacting with one distinct application.
 type Client struct {
 	// The HTTP client we use to submit our data to the TelemetryDeck API.
 	httpClient *http.Client
 
-	// Logger used to log errors.
-	logger *log.Logger
+	// Loggers given via options, the minimum level of messages to log,
+	// also per subsystem, and the loggers messages of the subsystems are
+	// passed to (see initLogging).
+	logger             *log.Logger
+	slogLogger         *slog.Logger
+	logLevel           LogLevel
+	logLevelSet        bool
+	subsystemLogLevels map[LogSubsystem]LogLevel
+	logSinks           map[LogSubsystem]*slog.Logger
+	logDedup           logDeduplicator
 
 	appID      string
 	endpoint   string
@@ -87,7 +143,203 @@ type Client struct {
 	userID     string
 	userIDHash string
 	sessionID  string
+	newID      func() string
 	testMode   bool
+	dryRun     bool
+
+	// App IDs by environment, and the active environment, see
+	// WithEnvironmentAppIDs
+	environmentAppIDs map[string]string
+	environment       string
+
+	// Durations after which, or after being idle for which, a new session
+	// is started, zero if never, and whether new sessions are announced
+	sessionMaxDuration time.Duration
+	sessionIdleTimeout time.Duration
+	announceSessions   bool
+
+	// Whether the session duration and calendar parameters are added to
+	// every signal
+	sessionDurations   bool
+	calendarParameters bool
+
+	// Whether payload values are normalized, and the length after which
+	// they are truncated, zero if never
+	normalizePayload bool
+	maxValueLength   int
+
+	// Payload key whose value is sent as floatValue, see WithFloatValueKey
+	floatValueKey string
+
+	// Throttles the signals of TrackFlagEvaluation
+	flagEvaluations flagEvaluations
+
+	// File persisting state across runs, see WithStateFile, and the
+	// launch count read from it, see WithLaunchCount
+	stateFile     string
+	countLaunches bool
+	launchCount   int64
+
+	// Time of the last signal in Unix nanoseconds, see WithSessionIdleTimeout
+	lastActivity atomic.Int64
+
+	// Whether signals are discarded, see Default, and whether the client
+	// has been disabled, see Disable.
+	noop   bool
+	toggle atomic.Int32
+
+	// Disables the client via the environment, see WithKillSwitchInterval.
+	killSwitch killSwitch
+
+	// Throttles or disables the client remotely, see WithRemoteConfig.
+	remoteConfig remoteConfig
+
+	// Static bearer token, or a function returning one, to send
+	// in the Authorization header.
+	authToken     string
+	authTokenFunc func(ctx context.Context) (string, error)
+
+	// Whether to validate the configuration in NewClient, and whether
+	// to include a test-mode round trip to the endpoint.
+	validateOnCreate  bool
+	validateRoundTrip bool
+
+	// Version of the Ingest API, and the constructor of the format signals
+	// are encoded in for it.
+	apiVersion APIVersion
+	newFormat  func(c *Client, l *liveConfig) ingestFormat
+
+	// Settings that may change while the client is running, see
+	// Reconfigure. The fields they are initialized from are not used once
+	// NewClient returns.
+	liveConfig    atomic.Pointer[liveConfig]
+	reconfigureMu sync.Mutex
+	sampleRate    float64
+
+	// Whether payload keys are encoded in sorted order, and how values
+	// are encoded.
+	sortPayloadKeys   bool
+	payloadTypePolicy PayloadTypePolicy
+
+	// Whether payload fields take precedence over standard fields, and
+	// whether reserved payload keys are rejected
+	overrideDefaultKeys bool
+	strictPayloadKeys   bool
+
+	// Whether request bodies are gzip-compressed.
+	compression bool
+
+	// Settings for the HTTP transport.
+	transport transportConfig
+
+	// How failed deliveries are retried.
+	retryPolicy RetryPolicy
+
+	// Policy enforced on signal types and payload keys, and the schemas
+	// signals are validated against in test mode, if any.
+	signalPolicy *SignalPolicy
+	schemas      *SchemaRegistry
+
+	// Periodic delivery report signals, if enabled.
+	deliveryReports *deliveryReports
+
+	// Callbacks invoked during delivery, and the channel delivery errors
+	// are sent to.
+	hooks        Hooks
+	errorChannel chan<- error
+
+	// Limits the bytes sent per second, if set.
+	bandwidthLimit int
+	bandwidth      *bandwidthLimiter
+
+	clock Clock
+
+	// Writes request and response dumps, if set.
+	debugDump *debugDumper
+
+	// Delivery statistics, and where they are reported.
+	stats   statsCollector
+	metrics Metrics
+
+	// Number of signals whose delivery failed, see Close, and whether the
+	// client has been closed.
+	failedSignals atomic.Int64
+	closed        atomic.Bool
+
+	// Most recent delivery error and when it occurred, when the most
+	// recent successful delivery completed, and whether the most recent
+	// delivery failed, see CheckHealth and LastError.
+	lastDeliveryMu     sync.Mutex
+	lastDeliveryErr    error
+	lastErrorTime      time.Time
+	lastSuccessTime    time.Time
+	lastDeliveryFailed bool
+
+	// Signals waiting for delivery, and the workers delivering them. The
+	// queue is only set if the default in-memory store is used.
+	store           QueueStore
+	storeFailed     atomic.Bool
+	queue           *ringQueue
+	queueSize       int
+	queueFullPolicy QueueFullPolicy
+	workers         atomic.Int32
+	maxWorkers      int
+	workerPool      *workerPool
+	maxBatchSize    int
+	maxRequestBytes int
+
+	// Queue lengths at which the OnBackpressure hook is called, and whether
+	// the high watermark has been reached.
+	highWatermark int
+	lowWatermark  int
+	backpressure  atomic.Bool
+
+	// Highest number of queued signals so far
+	peakQueued atomic.Int64
+
+	// Signals rejected by the endpoint waiting for their backoff to be
+	// enqueued again, see handleRejectedSignals.
+	requeueMu sync.Mutex
+	requeues  map[*rejectedRetry]Timer
+
+	// Number of signals enqueued but not delivered (or failed) yet, and
+	// the channel closed when it drops to zero (see Flush).
+	unfinished atomic.Int64
+	idleMu     sync.Mutex
+	idle       chan struct{}
+
+	// Closed when signals are dequeued, to wake up blocked producers.
+	spaceMu sync.Mutex
+	space   chan struct{}
+
+	// When to deliver queued signals, and whether a timer is armed to
+	// deliver them once the oldest is too old.
+	flushTriggers   FlushTriggers
+	flushTimerMu    sync.Mutex
+	flushTimerArmed bool
+
+	// Size of signals waiting for delivery, and the limit for it.
+	pendingBytes  atomic.Int64
+	maxQueueBytes int64
+
+	// Endpoints to fail over to when the primary endpoint is unreachable.
+	fallbackEndpoints []string
+	failoverThreshold int
+	failover          failoverState
+
+	// Signals that could not be delivered are persisted here, if set.
+	spoolDir    string
+	spoolLimits SpoolLimits
+	spoolKey    []byte
+	spool       *spool
+
+	// Resolves the paths of persistence features without explicit paths
+	pathResolver PathResolver
+	defaultPaths defaultPaths
+
+	// Time within which spooled records aren't replayed twice, see
+	// WithSpoolDedupWindow
+	spoolDedupWindow time.Duration
 }
 
 type SignalBody struct {
@@ -97,25 +349,36 @@ type SignalBody struct {
 	IsTestMode bool                   `json:"isTestMode"`
 	Type       string                 `json:"type"`
 	Payload    map[string]interface{} `json:"payload"`
+	FloatValue *float64               `json:"floatValue,omitempty"`
+
+	// Payload of signals sent via SendStringSignal, used instead of Payload.
+	stringPayload map[string]string
 }
 
 // NewClient instantiates a new client to send data to TelemetryDeck, and
 // also starts a new session. The appID is the only required parameter.
 // Any number of optional parameters can be passed using the With...() functions.
 func NewClient(appID string, options ...func(*Client)) (*Client, error) {
-	if appID == "" {
-		return nil, ErrNoAppID
-	}
-
 	// Create client with defaults
-	defaultUid := generateUserId()
 	client := &Client{
 		appID:      appID,
-		endpoint:   endpoint,
-		sessionID:  uuid.New().String(),
-		userID:     defaultUid,
-		userIDHash: hashUserId(defaultUid, ""),
-		httpClient: &http.Client{},
+		endpoint:   DefaultEndpoint,
+		apiVersion: APIVersion2,
+		sampleRate: 1,
+		newID:      newUUID,
+
+		queueSize:         defaultQueueSize,
+		maxWorkers:        defaultWorkers,
+		maxBatchSize:      defaultMaxBatchSize,
+		maxRequestBytes:   defaultMaxRequestBytes,
+		flushTriggers:     DefaultFlushTriggers,
+		retryPolicy:       DefaultRetryPolicy,
+		failoverThreshold: defaultFailoverThreshold,
+		spoolLimits:       DefaultSpoolLimits,
+		logDedup:          logDeduplicator{window: DefaultLogDeduplicationWindow},
+		flagEvaluations:   flagEvaluations{interval: DefaultFlagEvaluationInterval},
+		metrics:           defaultMetrics(),
+		clock:             systemClock{},
 	}
 
 	// Apply options overriding defaults
@@ -123,6 +386,111 @@ func NewClient(appID string, options ...func(*Client)) (*Client, error) {
 		o(client)
 	}
 
+	if err := client.resolveEnvironmentAppID(); err != nil {
+		return nil, err
+	}
+	if client.appID == "" {
+		return nil, ErrNoAppID
+	}
+
+	// Only fingerprint the machine if no user ID was given
+	if client.userID == "" {
+		client.userID = machineUserID()
+	}
+	client.userIDHash = hashUserId(client.userID, client.hashSalt)
+	if client.sessionID == "" {
+		client.sessionID = client.newID()
+	}
+
+	if err := client.resolveDefaultPaths(); err != nil {
+		return nil, err
+	}
+	client.stats.metrics = client.metrics
+	if client.store == nil {
+		client.queue = newRingQueue(client.queueSize)
+		client.store = client.queue
+	}
+	client.initLogging()
+	if client.deliveryReports != nil {
+		client.deliveryReports.last = client.clock.Now()
+	}
+	client.initWatermarks()
+	if client.bandwidthLimit > 0 {
+		client.bandwidth = newBandwidthLimiter(client.bandwidthLimit, client.clock)
+	}
+	newFormat, ok := ingestFormats[client.apiVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedAPIVersion, client.apiVersion)
+	}
+	client.newFormat = newFormat
+	firstSession, err := client.initState()
+	if err != nil {
+		return nil, err
+	}
+	live := &liveConfig{
+		sampleRate:      client.sampleRate,
+		maxBatchSize:    client.maxBatchSize,
+		maxRequestBytes: client.maxRequestBytes,
+		userID:          client.userID,
+		hashSalt:        client.hashSalt,
+		userIDHash:      client.userIDHash,
+		sessionID:       client.sessionID,
+		sessionStart:    client.clock.Now(),
+		firstSession:    firstSession,
+	}
+	live.format = newFormat(client, live)
+	client.endpoint = live.format.endpoint(client.endpoint)
+	live.endpoint = client.endpoint
+	if len(client.fallbackEndpoints) > 0 {
+		fallbacks := make([]string, len(client.fallbackEndpoints))
+		for i, endpoint := range client.fallbackEndpoints {
+			fallbacks[i] = live.format.endpoint(endpoint)
+		}
+		client.fallbackEndpoints = fallbacks
+	}
+	client.liveConfig.Store(live)
+	client.lastActivity.Store(live.sessionStart.UnixNano())
+
+	// Clients of a Manager share its HTTP client
+	if client.httpClient == nil {
+		client.httpClient = client.transport.newHTTPClient()
+	}
+
+	if client.validateOnCreate {
+		if err := client.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if client.spoolDir != "" {
+		spool, err := openSpool(client.spoolDir, client.spoolLimits, client.spoolKey)
+		if err != nil {
+			return nil, err
+		}
+		spool.dedupWindow = client.spoolDedupWindow
+		client.spool = spool
+	}
+
+	// Before replaying the spool, so that nothing is sent if disabled
+	client.checkKillSwitch()
+	client.startReplay()
+	if client.remoteConfig.url != "" {
+		go client.checkRemoteConfig()
+	}
+
+	// Deliver signals left in a persistent store by a previous client
+	if n := client.store.Len(); n > 0 && client.queue == nil {
+		client.unfinished.Add(int64(n))
+		client.startWorkers()
+	}
+	if client.announceSessions {
+		client.sendSessionStarted(live)
+	}
+
+	if client.hooks.OnStart != nil {
+		client.hooks.OnStart()
+	}
+
 	return client, nil
 }
 
@@ -137,7 +505,8 @@ func WithEndpoint(endpoint string) func(*Client) {
 
 // WithLogger specifies a logger to use for logging errors
 // caught during sending telemetry signals. If not given,
-// these errors will be ignored.
+// these errors will be ignored. Which messages are logged
+// is controlled via WithLogLevel.
 //
 // To be used as an option parameter in the NewClient() func.
 func WithLogger(logger *log.Logger) func(*Client) {
@@ -157,9 +526,6 @@ func WithLogger(logger *log.Logger) func(*Client) {
 func WithHashSalt(salt string) func(*Client) {
 	return func(c *Client) {
 		c.hashSalt = salt
-
-		// Re-hash the user ID with the new salt
-		c.userIDHash = hashUserId(c.userID, c.hashSalt)
 	}
 }
 
@@ -174,13 +540,13 @@ func WithHashSalt(salt string) func(*Client) {
 func WithUserID(userID string) func(*Client) {
 	return func(c *Client) {
 		c.userID = userID
-		c.userIDHash = hashUserId(userID, c.hashSalt)
 	}
 }
 
 // WithSessionID specifies a session identifier. This should be the same value for
 // the same session/user combination. If not given, a UUID will be
-// generated at the creation of the client.
+// generated at the creation of the client. The ID is replaced when a new
+// session is started, see WithSessionMaxDuration and WithSessionIdleTimeout.
 //
 // To be used as an option parameter in the NewClient() func.
 func WithSessionID(sessionID string) func(*Client) {
@@ -189,16 +555,99 @@ func WithSessionID(sessionID string) func(*Client) {
 	}
 }
 
+// WithIDGenerator replaces the generator of the session ID (unless given
+// via WithSessionID) and the request IDs sent in the X-Request-ID header,
+// which are random UUIDs by default. This is mainly useful to get
+// deterministic identifiers in tests. The function must be safe for
+// concurrent use.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithIDGenerator(f func() string) func(*Client) {
+	return func(c *Client) {
+		if f != nil {
+			c.newID = f
+		}
+	}
+}
+
+// Generates a random UUID.
+func newUUID() string {
+	return uuid.New().String()
+}
+
 // WithTestMode activates test mode.
 //
 // When set, data will be sent with isTestMode=true, to avoid
 // polluting production data. Also, errors will be logged that
-// would otherwise be silently ignored.
+// would otherwise be silently ignored, and payload keys are
+// sent in sorted order (see WithSortedPayloadKeys).
+//
+// So that signals show up in the dashboard within seconds while iterating
+// on them, SendSignal and SendStringSignal deliver signals right away,
+// bypassing the queue, and return once the request completed. Request
+// bodies are logged with LogLevelInfo.
 //
 // To be used as an option parameter in the NewClient() func.
 func WithTestMode() func(*Client) {
 	return func(c *Client) {
 		c.testMode = true
+		c.sortPayloadKeys = true
+	}
+}
+
+// WithSortedPayloadKeys makes the client encode payload keys in sorted
+// order, so that request bodies are deterministic. This makes byte-level
+// assertions and golden-file tests of requests possible, at the cost of
+// some performance. Enabled automatically by WithTestMode and
+// WithDebugDump.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithSortedPayloadKeys() func(*Client) {
+	return func(c *Client) {
+		c.sortPayloadKeys = true
+	}
+}
+
+// WithAuthToken specifies a bearer token to send in the Authorization
+// header of every request. This is only needed for self-hosted or
+// proxied ingest endpoints requiring authentication.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithAuthToken(token string) func(*Client) {
+	return func(c *Client) {
+		c.authToken = token
+		c.authTokenFunc = nil
+	}
+}
+
+// WithAuthTokenFunc specifies a function providing the bearer token to
+// send in the Authorization header. The function is called for every
+// request, including retries and deliveries of queued or spooled signals,
+// which allows rotating tokens without recreating the client. An error
+// returned by the function fails the delivery without retrying: it is
+// passed to the OnError hook, or returned from SendSignalSync and Ping.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithAuthTokenFunc(f func(ctx context.Context) (string, error)) func(*Client) {
+	return func(c *Client) {
+		c.authToken = ""
+		c.authTokenFunc = f
+	}
+}
+
+// WithValidateOnCreate makes NewClient check that the app ID is a
+// well-formed UUID, returning ErrInvalidAppID otherwise. If roundTrip is
+// true, NewClient additionally sends a test-mode signal (see Client.Ping)
+// and returns an error if it is not accepted.
+//
+// This is meant to fail fast during development, instead of silently
+// emitting signals that the backend rejects.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithValidateOnCreate(roundTrip bool) func(*Client) {
+	return func(c *Client) {
+		c.validateOnCreate = true
+		c.validateRoundTrip = roundTrip
 	}
 }
 
@@ -211,6 +660,11 @@ func hashUserId(id, salt string) string {
 	return fmt.Sprintf("%x", bs)
 }
 
+// Returns the user identifier generated by generateUserId. It is computed
+// on first use only and cached for the lifetime of the process, as
+// enumerating network interfaces can be slow.
+var machineUserID = sync.OnceValue(generateUserId)
+
 // Returns a pseudo-unique user identifier based on machine, OS
 // and OS user details.
 func generateUserId() (id string) {
@@ -263,81 +717,392 @@ func generateUserId() (id string) {
 //
 // The payload is a map of key-value pairs, containing the data you want to send.
 //
-// Errors that occur during submission of the request to TelemetryDeck are not
+// The signal is added to a queue and encoded and delivered in the background, so
+// SendSignal doesn't block (see WithQueueSize and WithWorkers). If the queue is full,
+// the signal is dropped and ErrQueueFull is returned, unless configured otherwise
+// via WithQueueFullPolicy. The payload must not be modified after passing it to
+// SendSignal. After the client has been closed, ErrClientClosed is returned.
+//
+// The context also bounds the delivery: if it's done before the signal has been
+// delivered, the signal is dropped (see Hooks.OnDrop), and a request is canceled
+// once the contexts of all signals it delivers are done. This doesn't count as a
+// failed delivery, nor does it affect failover to fallback endpoints. To send
+// signals outliving the context, e.g. the one of an HTTP request, pass
+// context.WithoutCancel(ctx).
+//
+// Errors that occur during encoding and submission of the request to TelemetryDeck are not
 // returned. Instead they are printed if the client has been configured with a logger
 // (see WithLogger).
 func (c *Client) SendSignal(ctx context.Context, signalType string, payload map[string]interface{}) error {
+	if c.skipsSignal(signalType) {
+		return nil
+	}
+	signalType, payload, err := checkSignal(c, signalType, payload)
+	if err != nil {
+		return err
+	}
+
+	return c.sendSignal(ctx, c.newSignal(signalType, payload))
+}
+
+// SendStringSignal works like SendSignal, but takes a payload consisting
+// of string values only. This avoids the overhead of encoding arbitrary
+// values, which makes it the faster choice for the common case of simple
+// key-value pairs.
+func (c *Client) SendStringSignal(ctx context.Context, signalType string, payload map[string]string) error {
+	if c.skipsSignal(signalType) {
+		return nil
+	}
+	signalType, payload, err := checkSignal(c, signalType, payload)
+	if err != nil {
+		return err
+	}
+
+	return c.sendSignal(ctx, c.newStringSignal(signalType, payload))
+}
+
+// Returns whether a signal of the type passed to one of the Send methods
+// is silently discarded, because the client is disabled or the signal is
+// sampled out.
+func (c *Client) skipsSignal(signalType string) bool {
+	return c.discardsSignals() || c.sampledOut(signalType)
+}
+
+// Checks the signal type and payload passed to one of the Send methods,
+// applying the signal policy, the strict payload key check, the schema
+// registry, the payload normalization and the maximum value length as
+// configured. Returns the type and payload to send.
+func checkSignal[V any](c *Client, signalType string, payload map[string]V) (string, map[string]V, error) {
 	if signalType == "" {
-		return ErrNoSignalType
+		return "", nil, ErrNoSignalType
+	}
+	if c.signalPolicy != nil {
+		var err error
+		if signalType, payload, err = applySignalPolicy(c.signalPolicy, signalType, payload); err != nil {
+			return "", nil, err
+		}
+	}
+	if c.strictPayloadKeys {
+		if err := checkReservedKeys(payload); err != nil {
+			return "", nil, err
+		}
+	}
+	if c.schemas != nil && c.testMode {
+		if err := validateSchema(c.schemas, signalType, payload); err != nil {
+			return "", nil, err
+		}
+	}
+	if c.normalizePayload {
+		payload = normalizeValues(payload)
+	}
+	if c.maxValueLength > 0 {
+		payload = truncateValues(payload, c.maxValueLength)
+	}
+	return signalType, payload, nil
+}
+
+// Adds the signal to the queue. It is encoded by the worker delivering it.
+func (c *Client) sendSignal(ctx context.Context, signal SignalBody) error {
+	if c.closed.Load() {
+		return ErrClientClosed
+	}
+	c.checkDefaultKeys(&signal)
+
+	if c.testMode {
+		c.deliverNow(ctx, signal, c.authToken)
+		return nil
+	}
+	if err := c.enqueue(ctx, signal, c.authToken); err != nil {
+		return err
+	}
+	c.checkDeliveryReport(ctx, true, false)
+	return nil
+}
+
+// Checks the client configuration as requested via WithValidateOnCreate.
+func (c *Client) validate() error {
+	if err := uuid.Validate(c.appID); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAppID, err)
+	}
+
+	if c.validateRoundTrip {
+		ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+		defer cancel()
+		if err := c.Ping(ctx); err != nil {
+			return fmt.Errorf("error validating client: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SendSignalSync sends a signal to the TelemetryDeck backend like SendSignal,
+// but waits for the request to complete. It returns the parsed response of
+// the ingest endpoint, and an error if the signal could not be delivered
+// (see Client.Ping for the types of errors returned), or ErrClientClosed
+// after the client has been closed. Like SendSignal, it does nothing while
+// the client is disabled or if the signal is sampled out. If the endpoint
+// rejects the signal (see IngestResult.RejectedSignals), it is enqueued
+// again or dropped like a signal sent via SendSignal.
+func (c *Client) SendSignalSync(ctx context.Context, signalType string, payload map[string]interface{}) (IngestResult, error) {
+	if c.skipsSignal(signalType) {
+		return IngestResult{}, nil
+	}
+	signalType, payload, err := checkSignal(c, signalType, payload)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	if c.closed.Load() {
+		return IngestResult{}, ErrClientClosed
+	}
+
+	signal := c.newSignal(signalType, payload)
+	d, err := c.newDelivery([]SignalBody{signal}, c.authToken)
+	if err != nil {
+		return IngestResult{}, err
+	}
+	defer d.release()
+
+	result, err := c.submit(ctx, d)
+	if err == nil && len(result.RejectedSignals) > 0 {
+		c.handleRejectedSignals([]QueuedSignal{{Signal: signal, Token: c.authToken}}, result.RejectedSignals)
+	}
+	return result, err
+}
+
+// Returns a signal of the given type in the current session. The standard
+// fields are not part of the payload, but added when the signal is
+// encoded.
+func (c *Client) newSignal(signalType string, payload map[string]interface{}) SignalBody {
+	l := c.session()
+	if c.addsParameters() {
+		payload = withParameters(c, l, payload)
 	}
+	signal := c.sessionSignal(l, signalType, payload)
+	if c.floatValueKey != "" {
+		setFloatValue(c, &signal, payload)
+	}
+	return signal
+}
 
-	if payload == nil {
-		payload = make(map[string]interface{})
+// Returns a signal of the given type with a payload of strings, see
+// SendStringSignal.
+func (c *Client) newStringSignal(signalType string, payload map[string]string) SignalBody {
+	l := c.session()
+	if c.addsParameters() {
+		payload = withParameters(c, l, payload)
 	}
-	// Inject standard fields into the payload
-	payload["TelemetryDeck.Device.operatingSystem"] = runtime.GOOS
-	payload["TelemetryDeck.Device.architecture"] = runtime.GOARCH
-	payload["TelemetryDeck.SDK.nameAndVersion"] = version
+	signal := c.sessionSignal(l, signalType, nil)
+	signal.stringPayload = payload
+	if c.floatValueKey != "" {
+		setFloatValue(c, &signal, payload)
+	}
+	return signal
+}
 
-	signal := &SignalBody{
+// Returns a signal of the given type in the session of the settings.
+func (c *Client) sessionSignal(l *liveConfig, signalType string, payload map[string]interface{}) SignalBody {
+	return SignalBody{
 		AppID:      c.appID,
-		ClientUser: c.userIDHash,
-		SessionID:  c.sessionID,
+		ClientUser: l.userIDHash,
+		SessionID:  l.sessionID,
 		IsTestMode: c.testMode,
 		Type:       signalType,
 		Payload:    payload,
 	}
+}
 
-	// Body must be an array of signals. We only send one signal at a time.
-	signals := []SignalBody{*signal}
+// A request body ready to be submitted to the ingest endpoint.
+type delivery struct {
+	body  []byte
+	count int    // number of signals in body
+	token string // bearer token, if any
 
-	body, err := json.Marshal(signals)
-	if err != nil {
-		return err
+	// Whether the body is gzip-compressed
+	compressed bool
+
+	// Pooled buffer holding the body, if any.
+	buf *bytes.Buffer
+}
+
+// Returns the buffer holding the body to the pool. The delivery must not
+// be used afterwards.
+func (d *delivery) release() {
+	if d.buf != nil {
+		putBuffer(d.buf)
+		d.buf = nil
+		d.body = nil
 	}
+}
 
-	request, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewBuffer(body))
-	if err != nil {
-		return err
+// Submits the delivery to the currently active endpoint. Errors are
+// not returned, but passed to the OnError hook and logged if the client
+// has been configured with a logger. Deliveries failing temporarily are
+// spooled, if a spool is configured.
+func (c *Client) deliver(d delivery) {
+	_, err := c.submit(context.Background(), d)
+	c.handleDeliveryError(d, err)
+}
+
+// Handles the outcome of submitting the delivery, as described for deliver.
+func (c *Client) handleDeliveryError(d delivery, err error) {
+	if err == nil {
+		c.startReplay()
+		return
+	}
+	if isRetryable(err) && c.spoolDelivery(d) {
+		return
 	}
-	request.Header.Set("Content-Type", "application/json; charset=utf-8")
 
-	go func() {
-		response, err := c.httpClient.Do(request)
-		if err != nil {
-			if c.logger != nil {
-				c.logger.Printf("error submitting HTTP request: %s", err)
-			}
+	c.reportFailure(err, d.count)
+
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		args := []interface{}{"count", d.count, "status", responseErr.StatusCode, "requestID", responseErr.RequestID}
+		if c.testMode {
+			args = append(args, "requestBody", string(d.body), "responseBody", responseErr.Body)
 		}
-		if response == nil {
-			if c.logger != nil {
-				c.logger.Printf("warning - telemetrydeck.Client.SendSignal resulted in no response")
-			}
-			return
+		c.log(LogSubsystemTransport, LogLevelError, "signals rejected", args...)
+		return
+	}
+	c.log(LogSubsystemTransport, LogLevelError, "error submitting HTTP request", "count", d.count, "error", err)
+}
+
+// Records a delivery of the given number of signals that failed
+// permanently, and passes the error to the OnError hook.
+func (c *Client) reportFailure(err error, signals int) {
+	c.stats.recordFailure()
+	c.failedSignals.Add(int64(signals))
+	if c.hooks.OnError != nil {
+		c.hooks.OnError(err)
+	}
+	if c.errorChannel != nil {
+		select {
+		case c.errorChannel <- err:
+		default:
 		}
-		if response.Body != nil {
-			defer response.Body.Close()
+	}
+}
+
+// Returns a request submitting the delivery to the endpoint. It serves as
+// a template for all attempts to submit the delivery (see post), so that
+// it's only built once.
+func (c *Client) newRequest(ctx context.Context, endpoint string, d delivery) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(d.body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if d.compressed {
+		request.Header.Set("Content-Encoding", "gzip")
+	}
+	return request, nil
+}
+
+// Submits the delivery using a copy of the request template (see
+// newRequest) with its own request ID and the bearer token of the
+// delivery (see authTokenValue), and returns the parsed response.
+// Returns an error wrapping ErrUnreachable if the endpoint could not be
+// reached, or a *ResponseError if it responded with an error status.
+//
+// If a response was received, the OnResult hook is called. Connection-level
+// events are reported to the OnTrace hook.
+func (c *Client) post(ctx context.Context, template *http.Request, d delivery) (IngestResult, error) {
+	requestID := c.newID()
+	ctx = c.withTrace(ctx, requestID)
+
+	request := template.Clone(ctx)
+	body, err := template.GetBody()
+	if err != nil {
+		return IngestResult{}, err
+	}
+	request.Body = body
+	request.Header.Set("X-Request-ID", requestID)
+	if d.token != "" {
+		request.Header.Set("Authorization", "Bearer "+d.token)
+	}
+	if c.debugDump != nil {
+		c.debugDump.dumpRequest(request)
+	}
+
+	start := c.clock.Now()
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		c.stats.recordRequest(c.clock.Now().Sub(start))
+		if c.debugDump != nil {
+			c.debugDump.dumpError(requestID, err)
 		}
+		return IngestResult{RequestID: requestID}, fmt.Errorf("%w (request ID %s): %w", ErrUnreachable, requestID, err)
+	}
+	defer response.Body.Close()
 
-		if response.StatusCode >= 400 && c.testMode && c.logger != nil {
-			c.logger.Printf("response status: %d", response.StatusCode)
-			c.logger.Printf("request body: %s", body)
-			bodyBytes, err := io.ReadAll(response.Body)
-			if err == nil {
-				c.logger.Printf("response body: %s", string(bodyBytes))
-			}
+	bodyBytes, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseBodySize))
+	// Drain the rest of the body, so the connection can be reused.
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	duration := c.clock.Now().Sub(start)
+	c.stats.recordRequest(duration)
+	if c.debugDump != nil {
+		c.debugDump.dumpResponse(requestID, response, bodyBytes)
+	}
+
+	result := parseIngestResponse(response.StatusCode, bodyBytes, d.count)
+	result.RequestID = requestID
+	result.Duration = duration
+	c.log(LogSubsystemTransport, LogLevelInfo, "ingest result", "count", result.Sent, "status", result.StatusCode, "duration", duration,
+		"accepted", result.Accepted, "rejected", result.Rejected, "requestID", requestID)
+	if c.hooks.OnResult != nil {
+		c.hooks.OnResult(result)
+	}
+
+	// Redirects only arrive here if the redirect policy didn't follow them
+	if response.StatusCode >= 300 {
+		return result, &ResponseError{
+			StatusCode: response.StatusCode,
+			Body:       string(bodyBytes),
+			RequestID:  requestID,
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), c.clock.Now()),
 		}
-	}()
+	}
 
-	return nil
+	return result, nil
+}
+
+// Returns whether the error (as returned by post) indicates that the
+// endpoint was reached and is operational.
+func isReachable(err error) bool {
+	if err == nil {
+		return true
+	}
+	var responseErr *ResponseError
+	return errors.As(err, &responseErr) && responseErr.StatusCode < 500
+}
+
+// Returns the bearer token to send in the Authorization header of a
+// request submitting the delivery, if any. The token function is called
+// for every request, so that signals that waited in the queue or spool,
+// or are retried, aren't sent with a token that expired meanwhile.
+func (c *Client) authTokenValue(ctx context.Context, d delivery) (string, error) {
+	if c.authTokenFunc != nil {
+		token, err := c.authTokenFunc(ctx)
+		if err != nil {
+			return "", fmt.Errorf("error getting auth token: %w", err)
+		}
+		return token, nil
+	}
+	if d.token != "" {
+		return d.token, nil
+	}
+	// Stores not keeping tokens leave it to the client to provide one
+	return c.authToken, nil
 }
 
 // Returns the user ID set in the client (unhashed).
 func (c *Client) UserID() string {
-	return c.userID
+	return c.live().userID
 }
 
 // Returns the user ID hash set in the client.
 func (c *Client) UserIDHash() string {
-	return c.userIDHash
+	return c.live().userIDHash
 }
@@ -1,11 +1,20 @@
 package telemetrydeck
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestClient_SendSignal(t *testing.T) {
@@ -64,6 +73,214 @@ func TestClient_SendSignal(t *testing.T) {
 	}
 }
 
+func TestClient_SendStringSignal(t *testing.T) {
+	received := make(chan []SignalBody, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Error(err)
+		}
+		received <- signals
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), deliverImmediately)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if err := c.SendStringSignal(context.Background(), "", nil); !errors.Is(err, ErrNoSignalType) {
+		t.Errorf("Client.SendStringSignal() without type: error = %v, want ErrNoSignalType", err)
+	}
+
+	payload := map[string]string{"TestNamespace.command": "create"}
+	if err := c.SendStringSignal(context.Background(), "TestNamespace.stringTest", payload); err != nil {
+		t.Fatalf("Client.SendStringSignal() error = %v", err)
+	}
+
+	signals := <-received
+	if len(signals) != 1 || signals[0].Type != "TestNamespace.stringTest" || signals[0].Payload["TestNamespace.command"] != "create" {
+		t.Errorf("server received %+v", signals)
+	}
+}
+
+func TestClient_AuthToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(*Client)
+		want   string
+	}{
+		{
+			name: "no token",
+			want: "",
+		},
+		{
+			name:   "static token",
+			option: WithAuthToken("static-token"),
+			want:   "Bearer static-token",
+		},
+		{
+			name: "token func",
+			option: WithAuthTokenFunc(func(ctx context.Context) (string, error) {
+				return "rotated-token", nil
+			}),
+			want: "Bearer rotated-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got <- r.Header.Get("Authorization")
+			}))
+			defer server.Close()
+
+			options := []func(*Client){WithEndpoint(server.URL), deliverImmediately}
+			if tt.option != nil {
+				options = append(options, tt.option)
+			}
+			c, err := NewClient("my-app-id", options...)
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if err := c.SendSignal(context.Background(), "TestNamespace.authTest", nil); err != nil {
+				t.Fatalf("Client.SendSignal() error = %v", err)
+			}
+			if header := <-got; header != tt.want {
+				t.Errorf("Authorization header = %q, want %q", header, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_AuthTokenFunc_perRequest(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("Authorization"))
+		if len(headers) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var calls atomic.Int32
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithWorkers(1),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		WithAuthTokenFunc(func(ctx context.Context) (string, error) {
+			return fmt.Sprintf("token-%d", calls.Add(1)), nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// Queued signals get the token when delivered, and retries a new one
+	if err := c.SendSignal(context.Background(), "TestNamespace.authTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("token func called %d times before delivery, want 0", calls.Load())
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	want := []string{"Bearer token-1", "Bearer token-2"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("Authorization headers = %q, want %q", headers, want)
+	}
+
+	// Failing to get a token fails the delivery
+	tokenErr := errors.New("token unavailable")
+	c, err = NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithAuthTokenFunc(func(ctx context.Context) (string, error) {
+			return "", tokenErr
+		}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.authTest", nil); !errors.Is(err, tokenErr) {
+		t.Errorf("Client.SendSignalSync() error = %v, want %v", err, tokenErr)
+	}
+	if len(headers) != 2 {
+		t.Errorf("server got %d requests, want 2", len(headers))
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		for _, signal := range signals {
+			if signal.SessionID != "id-1" {
+				t.Errorf("SessionID = %q, want id-1", signal.SessionID)
+			}
+		}
+	}))
+	defer server.Close()
+
+	var n atomic.Int32
+	generator := func() string {
+		return fmt.Sprintf("id-%d", n.Add(1))
+	}
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithIDGenerator(generator), WithWorkers(1))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.SendSignal(context.Background(), "TestNamespace.idTest", nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+		if err := c.Flush(context.Background()); err != nil {
+			t.Fatalf("Client.Flush() error = %v", err)
+		}
+	}
+	if len(requestIDs) != 2 || requestIDs[0] != "id-2" || requestIDs[1] != "id-3" {
+		t.Errorf("request IDs = %q, want [id-2 id-3]", requestIDs)
+	}
+
+	// An explicit session ID is not replaced
+	c, err = NewClient("my-app-id", WithIDGenerator(generator), WithSessionID("session"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.sessionID != "session" {
+		t.Errorf("sessionID = %q, want session", c.sessionID)
+	}
+}
+
+func TestNewClient_ValidateOnCreate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	validAppID := "11111111-2222-3333-4444-555555555555"
+
+	if _, err := NewClient("my-app-id", WithValidateOnCreate(false)); !errors.Is(err, ErrInvalidAppID) {
+		t.Errorf("NewClient() with malformed app ID: error = %v, want ErrInvalidAppID", err)
+	}
+
+	if _, err := NewClient(validAppID, WithValidateOnCreate(false)); err != nil {
+		t.Errorf("NewClient() with valid app ID: error = %v", err)
+	}
+
+	var responseErr *ResponseError
+	_, err := NewClient(validAppID, WithEndpoint(server.URL), WithValidateOnCreate(true))
+	if !errors.As(err, &responseErr) {
+		t.Errorf("NewClient() with rejected round trip: error = %v, want ResponseError", err)
+	}
+}
+
 func Test_generateUserId(t *testing.T) {
 	gotId := generateUserId()
 	t.Logf("generateUserId(): %q", gotId)
@@ -72,6 +289,20 @@ func Test_generateUserId(t *testing.T) {
 	}
 }
 
+func Test_machineUserID(t *testing.T) {
+	if got, want := machineUserID(), generateUserId(); got != want {
+		t.Errorf("machineUserID() = %q, want %q", got, want)
+	}
+
+	c, err := NewClient("my-app-id")
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if c.UserID() != machineUserID() {
+		t.Errorf("client.UserID() = %q, want machine user ID", c.UserID())
+	}
+}
+
 func Test_UserIdHashingWithSalt(t *testing.T) {
 	userID := "somebody@example.com"
 	salt := "MySalt"
@@ -92,6 +323,22 @@ func Test_UserIdHashingWithSalt(t *testing.T) {
 	}
 }
 
+func Test_UserIdHashingWithSalt_reversedOptions(t *testing.T) {
+	userID := "somebody@example.com"
+	salt := "MySalt"
+	expectedHash := "c05dc5334d83cca7382bca040f2f6e9de56d57d22814cfc4c39b5a55dbc9ef16" // sha256 -s "somebody@example.comMySalt"
+
+	// Option order must not matter
+	client, err := NewClient("my-app-id", WithHashSalt(salt), WithUserID(userID))
+	if err != nil {
+		t.Fatalf("unexpected error when creating the client: %s", err)
+	}
+
+	if client.userIDHash != expectedHash {
+		t.Errorf("client.userIDHash wasn't the expected value. got %q, expected %q", client.userIDHash, expectedHash)
+	}
+}
+
 func Test_UserIdHashingWithoutSalt(t *testing.T) {
 	userID := "somebody@example.com"
 	salt := ""
@@ -124,3 +371,109 @@ func ExampleNewClient() {
 	}
 	// Output:
 }
+
+// Returns a client that queues signals without ever delivering them,
+// dropping the oldest when the queue is full.
+func newBenchmarkClient(tb testing.TB) *Client {
+	c, err := NewClient("my-app-id",
+		WithQueueFullPolicy(QueueFullDropOldest),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+		WithMetrics(nil),
+	)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+func BenchmarkClient_SendSignal(b *testing.B) {
+	c := newBenchmarkClient(b)
+	ctx := context.Background()
+	payload := benchmarkPayload()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.SendSignal(ctx, "TestNamespace.benchmark", payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_SendSignal_Parallel(b *testing.B) {
+	c := newBenchmarkClient(b)
+	ctx := context.Background()
+	payload := benchmarkPayload()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := c.SendSignal(ctx, "TestNamespace.benchmark", payload); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func Benchmark_hashUserId(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		hashUserId("somebody@example.com", "mySalt")
+	}
+}
+
+// Guards against allocations creeping into SendSignal.
+func TestClient_SendSignalAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations differ with the race detector")
+	}
+
+	c := newBenchmarkClient(t)
+	ctx := context.Background()
+	payload := benchmarkPayload()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := c.SendSignal(ctx, "TestNamespace.allocs", payload); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("SendSignal() allocates %.1f times, want 0", allocs)
+	}
+}
+
+func TestClient_SendSignal_testMode(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithTestMode(), WithSlogLogger(logger))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.testModeTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.SendStringSignal(context.Background(), "TestNamespace.testModeTest", nil); err != nil {
+		t.Fatalf("Client.SendStringSignal() error = %v", err)
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests = %d when the Send methods returned, want 2", n)
+	}
+	var record struct {
+		Msg  string `json:"msg"`
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(&logs).Decode(&record); err != nil {
+		t.Fatalf("invalid log record: %v", err)
+	}
+	if !strings.Contains(record.Body, `"type":"TestNamespace.testModeTest"`) {
+		t.Errorf("logged body = %s, want the request body", record.Body)
+	}
+}
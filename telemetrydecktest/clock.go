@@ -0,0 +1,157 @@
+package telemetrydecktest
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+// Clock is a fake telemetrydeck.Clock whose time only moves when told to,
+// to be passed to telemetrydeck.WithClock. Timers fire when the clock is
+// advanced past their deadline.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{}
+}
+
+// NewClock returns a fake clock set to the given time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now, changed: make(chan struct{})}
+}
+
+var _ telemetrydeck.Clock = (*Clock)(nil)
+
+type fakeTimer struct {
+	clock    *Clock
+	deadline time.Time
+	c        chan time.Time
+	f        func()
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t)
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) NewTimer(d time.Duration) telemetrydeck.Timer {
+	return c.add(d, make(chan time.Time, 1), nil)
+}
+
+// AfterFunc calls f synchronously from Advance or Set, once the clock
+// passes the deadline.
+func (c *Clock) AfterFunc(d time.Duration, f func()) telemetrydeck.Timer {
+	return c.add(d, nil, f)
+}
+
+func (c *Clock) add(d time.Duration, ch chan time.Time, f func()) *fakeTimer {
+	c.mu.Lock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), c: ch, f: f}
+	c.timers = append(c.timers, t)
+	c.notify()
+	c.mu.Unlock()
+
+	if d <= 0 {
+		c.Advance(0)
+	}
+	return t
+}
+
+func (c *Clock) remove(t *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// Wakes up goroutines waiting for timers. Must be called with mu held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Advance moves the clock forward by the duration, firing all timers due
+// by then in order of their deadlines.
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to the given time, firing all timers due by then in
+// order of their deadlines. The clock never moves backwards.
+func (c *Clock) Set(now time.Time) {
+	for {
+		c.mu.Lock()
+		if now.After(c.now) {
+			c.now = now
+		}
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].deadline.Before(c.timers[j].deadline)
+		})
+		if len(c.timers) == 0 || c.timers[0].deadline.After(c.now) {
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.notify()
+		c.mu.Unlock()
+
+		if t.f != nil {
+			t.f()
+		} else {
+			t.c <- t.deadline
+		}
+	}
+}
+
+// Timers returns the number of timers that have not fired or been stopped
+// yet.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitForTimers waits until at least n timers are pending, e.g. until the
+// client waits for a retry backoff, failing the test if that takes longer
+// than the timeout (in real time).
+func (c *Clock) WaitForTimers(tb testing.TB, n int, timeout time.Duration) {
+	tb.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		c.mu.Lock()
+		got, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if got >= n {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			tb.Fatalf("fake clock has %d timers after %s, want %d", got, timeout, n)
+			return
+		}
+	}
+}
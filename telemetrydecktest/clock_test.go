@@ -0,0 +1,89 @@
+package telemetrydecktest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	timer := clock.NewTimer(2 * time.Second)
+	var calls []string
+	clock.AfterFunc(time.Second, func() { calls = append(calls, "first") })
+	stopped := clock.AfterFunc(time.Second, func() { calls = append(calls, "stopped") })
+	if !stopped.Stop() {
+		t.Error("Timer.Stop() = false for pending timer")
+	}
+	if got := clock.Timers(); got != 2 {
+		t.Errorf("Timers() = %d, want 2", got)
+	}
+
+	clock.Advance(time.Second)
+	if len(calls) != 1 || calls[0] != "first" {
+		t.Errorf("AfterFunc() calls = %q, want [first]", calls)
+	}
+	select {
+	case <-timer.C():
+		t.Error("timer fired early")
+	default:
+	}
+
+	clock.Set(start.Add(time.Minute))
+	select {
+	case got := <-timer.C():
+		if !got.Equal(start.Add(2 * time.Second)) {
+			t.Errorf("timer sent %s, want its deadline", got)
+		}
+	default:
+		t.Error("timer didn't fire")
+	}
+	if timer.Stop() {
+		t.Error("Timer.Stop() = true for fired timer")
+	}
+
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("Now() after setting the past = %s, want unchanged", got)
+	}
+}
+
+func TestClock_Client(t *testing.T) {
+	server := NewServer(t)
+	server.Respond(Status(http.StatusServiceUnavailable, ""))
+	clock := NewClock(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	c, err := telemetrydeck.NewClient("my-app-id",
+		telemetrydeck.WithEndpoint(server.URL),
+		telemetrydeck.WithClock(clock),
+		telemetrydeck.WithFlushTriggers(telemetrydeck.FlushTriggers{MaxAge: time.Minute}),
+		telemetrydeck.WithRetryPolicy(telemetrydeck.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour, MaxBackoff: time.Hour}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.clockTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+
+	// Delivered once the signal reached the age limit
+	clock.Advance(59 * time.Second)
+	server.AssertRequests(t, 0)
+	clock.Advance(time.Second)
+	server.WaitForRequests(t, 1, 5*time.Second)
+
+	// Retried after the backoff
+	clock.WaitForTimers(t, 1, 5*time.Second)
+	server.AssertRequests(t, 1)
+	clock.Advance(time.Hour)
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+	server.AssertStatuses(t, http.StatusServiceUnavailable, http.StatusOK)
+	server.AssertSignalTypes(t, "TestNamespace.clockTest")
+}
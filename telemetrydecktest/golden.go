@@ -0,0 +1,131 @@
+// Package telemetrydecktest provides utilities for testing code sending
+// signals via the telemetrydeck package.
+package telemetrydecktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+// Environment variable which, if set to a non-empty value, makes
+// AssertGolden write golden files instead of comparing against them.
+const UpdateGoldenEnv = "TELEMETRYDECK_UPDATE_GOLDEN"
+
+// Placeholders replacing values that differ between runs or machines in
+// normalized signals.
+const (
+	PlaceholderClientUser = "<clientUser>"
+	PlaceholderSessionID  = "<sessionID>"
+	PlaceholderUUID       = "<uuid>"
+	PlaceholderTimestamp  = "<timestamp>"
+	PlaceholderDefault    = "<default>"
+)
+
+var uuidPattern = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// Payload keys added by the client whose values depend on the machine or
+// the SDK version.
+var defaultPayloadKeys = []string{
+	"TelemetryDeck.Device.operatingSystem",
+	"TelemetryDeck.Device.architecture",
+	"TelemetryDeck.SDK.nameAndVersion",
+}
+
+// Normalize returns a copy of the signal with all values that differ
+// between runs or machines replaced by placeholders, so that it can be
+// compared against a golden file:
+//
+//   - the client user and session ID
+//   - UUIDs and RFC 3339 timestamps in payload values
+//   - the values of the payload fields describing the machine and SDK
+//     version added by the client
+func Normalize(signal telemetrydeck.SignalBody) telemetrydeck.SignalBody {
+	if signal.ClientUser != "" {
+		signal.ClientUser = PlaceholderClientUser
+	}
+	if signal.SessionID != "" {
+		signal.SessionID = PlaceholderSessionID
+	}
+
+	payload := make(map[string]interface{}, len(signal.Payload))
+	for key, value := range signal.Payload {
+		if s, ok := value.(string); ok {
+			value = normalizeString(s)
+		}
+		payload[key] = value
+	}
+	for _, key := range defaultPayloadKeys {
+		if _, ok := payload[key]; ok {
+			payload[key] = PlaceholderDefault
+		}
+	}
+	signal.Payload = payload
+
+	return signal
+}
+
+// Replaces a timestamp or UUIDs in the string with placeholders.
+func normalizeString(s string) string {
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return PlaceholderTimestamp
+	}
+	return uuidPattern.ReplaceAllString(s, PlaceholderUUID)
+}
+
+// MarshalGolden returns the normalized signals (see Normalize) as indented
+// JSON with sorted keys, as stored in golden files.
+func MarshalGolden(signals ...telemetrydeck.SignalBody) ([]byte, error) {
+	normalized := make([]telemetrydeck.SignalBody, len(signals))
+	for i, signal := range signals {
+		normalized[i] = Normalize(signal)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(normalized); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AssertGolden compares the normalized signals (see Normalize) against the
+// golden file at the given path, failing the test if they differ. If the
+// environment variable TELEMETRYDECK_UPDATE_GOLDEN is set, the golden file
+// is written instead.
+func AssertGolden(tb testing.TB, path string, signals ...telemetrydeck.SignalBody) {
+	tb.Helper()
+
+	got, err := MarshalGolden(signals...)
+	if err != nil {
+		tb.Fatalf("encoding signals: %v", err)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("creating golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("reading golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		tb.Errorf("signals differ from golden file %s (set %s=1 to update it):\ngot:\n%s\nwant:\n%s",
+			path, UpdateGoldenEnv, strings.TrimSpace(string(got)), strings.TrimSpace(string(want)))
+	}
+}
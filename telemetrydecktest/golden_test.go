@@ -0,0 +1,98 @@
+package telemetrydecktest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+func TestNormalize(t *testing.T) {
+	signal := telemetrydeck.SignalBody{
+		AppID:      "my-app-id",
+		ClientUser: "0123abcd",
+		SessionID:  "a2b8f9d0-3e4c-4b5a-9c6d-7e8f9a0b1c2d",
+		Type:       "TestNamespace.normalizeTest",
+		Payload: map[string]interface{}{
+			"TestNamespace.startedAt":              "2024-05-01T12:00:00.123Z",
+			"TestNamespace.request":                "request a2b8f9d0-3e4c-4b5a-9c6d-7e8f9a0b1c2d failed",
+			"TestNamespace.count":                  3,
+			"TelemetryDeck.SDK.nameAndVersion":     "telemetrydeck-go/1.2.3",
+			"TelemetryDeck.Device.architecture":    "arm64",
+			"TelemetryDeck.Device.operatingSystem": "darwin",
+		},
+	}
+
+	got := Normalize(signal)
+	want := map[string]interface{}{
+		"TestNamespace.startedAt":              PlaceholderTimestamp,
+		"TestNamespace.request":                "request " + PlaceholderUUID + " failed",
+		"TestNamespace.count":                  3,
+		"TelemetryDeck.SDK.nameAndVersion":     PlaceholderDefault,
+		"TelemetryDeck.Device.architecture":    PlaceholderDefault,
+		"TelemetryDeck.Device.operatingSystem": PlaceholderDefault,
+	}
+	if got.ClientUser != PlaceholderClientUser || got.SessionID != PlaceholderSessionID {
+		t.Errorf("Normalize() = %+v, want placeholders for client user and session ID", got)
+	}
+	if fmt.Sprint(got.Payload) != fmt.Sprint(want) {
+		t.Errorf("Normalize().Payload = %v, want %v", got.Payload, want)
+	}
+	if signal.ClientUser != "0123abcd" || signal.Payload["TestNamespace.count"] != 3 {
+		t.Error("Normalize() modified its argument")
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	var mu sync.Mutex
+	var received []telemetrydeck.SignalBody
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []telemetrydeck.SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("decoding body: %v", err)
+		}
+		mu.Lock()
+		received = append(received, signals...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	c, err := telemetrydeck.NewClient("my-app-id", telemetrydeck.WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	payload := map[string]interface{}{"TestNamespace.command": "create cluster", "TestNamespace.dryRun": true}
+	if err := c.SendSignal(context.Background(), "TestNamespace.goldenTest", payload); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	AssertGolden(t, filepath.Join("testdata", "signals.golden"), received...)
+
+	// Differences are reported
+	t.Setenv(UpdateGoldenEnv, "")
+	recorder := &recordingTB{TB: t}
+	received[0].Type = "TestNamespace.otherTest"
+	AssertGolden(recorder, filepath.Join("testdata", "signals.golden"), received...)
+	if !recorder.failed {
+		t.Error("AssertGolden() didn't fail for different signals")
+	}
+}
+
+// Records failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	failed bool
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.failed = true
+}
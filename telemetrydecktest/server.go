@@ -0,0 +1,301 @@
+package telemetrydecktest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+// Response is a response the Server sends for a request.
+type Response struct {
+	// HTTP status, http.StatusOK if zero.
+	Status int
+
+	// Response body.
+	Body string
+
+	// Delay sent in the Retry-After header, rounded up to whole seconds.
+	// The header is omitted if zero.
+	RetryAfter time.Duration
+
+	// Additional headers to send.
+	Header http.Header
+
+	// Time to wait before responding, e.g. to test timeouts. Waiting ends
+	// early if the request is canceled.
+	Delay time.Duration
+}
+
+// Status returns a response with the given status and body.
+func Status(status int, body string) Response {
+	return Response{Status: status, Body: body}
+}
+
+// RateLimited returns a 429 response asking to retry after the given delay.
+func RateLimited(retryAfter time.Duration) Response {
+	return Response{Status: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// Request is a request received by the Server.
+type Request struct {
+	Header  http.Header
+	Signals []telemetrydeck.SignalBody
+
+	// Response sent for the request.
+	Response Response
+
+	// Time the request was received.
+	Time time.Time
+}
+
+// Accepted returns whether the request was answered with a 2xx status.
+func (r Request) Accepted() bool {
+	status := r.Response.status()
+	return status >= 200 && status < 300
+}
+
+func (r Response) status() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
+
+// Server is a fake TelemetryDeck ingest endpoint recording the signals it
+// receives. By default it accepts all requests; use Respond to script
+// failures, e.g. to test retries:
+//
+//	server := telemetrydecktest.NewServer(t)
+//	server.Respond(
+//		telemetrydecktest.RateLimited(time.Second),
+//		telemetrydecktest.Status(http.StatusBadRequest, "invalid signal"),
+//	)
+//	client, _ := telemetrydeck.NewClient(appID, telemetrydeck.WithEndpoint(server.URL))
+//
+// Requests with a body that isn't a gzip-compressed or plain JSON array of
+// signals fail the test and are answered with 400.
+type Server struct {
+	// URL of the endpoint, to be passed to telemetrydeck.WithEndpoint.
+	URL string
+
+	tb     testing.TB
+	server *httptest.Server
+
+	mu       sync.Mutex
+	script   []Response
+	fallback Response
+	requests []Request
+	received chan struct{}
+}
+
+// NewServer starts a Server, which is closed when the test finishes.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	s := &Server{tb: tb, received: make(chan struct{})}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Close shuts down the server, waiting for pending requests to finish.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Respond scripts the responses to the next requests, in order. Further
+// requests are answered with the response set by RespondAlways, if any,
+// or 200.
+func (s *Server) Respond(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.script = append(s.script, responses...)
+}
+
+// RespondAlways sets the response sent once all scripted responses have
+// been used.
+func (s *Server) RespondAlways(response Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = response
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	signals, err := decodeSignals(r)
+	if err != nil {
+		s.tb.Errorf("fake ingest server: invalid request body: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	response := s.fallback
+	if len(s.script) > 0 {
+		response = s.script[0]
+		s.script = s.script[1:]
+	}
+	s.requests = append(s.requests, Request{
+		Header:   r.Header.Clone(),
+		Signals:  signals,
+		Response: response,
+		Time:     time.Now(),
+	})
+	close(s.received)
+	s.received = make(chan struct{})
+	s.mu.Unlock()
+
+	if response.Delay > 0 {
+		timer := time.NewTimer(response.Delay)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if response.RetryAfter > 0 {
+		seconds := (response.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+	w.WriteHeader(response.status())
+	io.WriteString(w, response.Body)
+}
+
+// Decodes the signals in the request body, which may be gzip-compressed.
+func decodeSignals(r *http.Request) ([]telemetrydeck.SignalBody, error) {
+	body := r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var signals []telemetrydeck.SignalBody
+	if err := json.NewDecoder(body).Decode(&signals); err != nil {
+		return nil, err
+	}
+	return signals, nil
+}
+
+// Requests returns all requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Signals returns the signals of all accepted requests (see
+// Request.Accepted), i.e. the signals the client successfully delivered.
+func (s *Server) Signals() []telemetrydeck.SignalBody {
+	var signals []telemetrydeck.SignalBody
+	for _, request := range s.Requests() {
+		if request.Accepted() {
+			signals = append(signals, request.Signals...)
+		}
+	}
+	return signals
+}
+
+// WaitForRequests waits until the server received at least n requests,
+// failing the test if that takes longer than the timeout.
+func (s *Server) WaitForRequests(tb testing.TB, n int, timeout time.Duration) {
+	tb.Helper()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		got, received := len(s.requests), s.received
+		s.mu.Unlock()
+		if got >= n {
+			return
+		}
+
+		select {
+		case <-received:
+		case <-timer.C:
+			tb.Fatalf("fake ingest server received %d requests within %s, want %d", got, timeout, n)
+			return
+		}
+	}
+}
+
+// AssertRequests fails the test unless the server received exactly n
+// requests.
+func (s *Server) AssertRequests(tb testing.TB, n int) {
+	tb.Helper()
+	if got := len(s.Requests()); got != n {
+		tb.Errorf("fake ingest server received %d requests, want %d", got, n)
+	}
+}
+
+// AssertStatuses fails the test unless the server answered the requests it
+// received with exactly the given statuses, in order.
+func (s *Server) AssertStatuses(tb testing.TB, statuses ...int) {
+	tb.Helper()
+	requests := s.Requests()
+	got := make([]int, len(requests))
+	for i, request := range requests {
+		got[i] = request.Response.status()
+	}
+	if fmt.Sprint(got) != fmt.Sprint(statuses) {
+		tb.Errorf("fake ingest server sent statuses %v, want %v", got, statuses)
+	}
+}
+
+// AssertSignalTypes fails the test unless the accepted signals (see
+// Signals) have exactly the given types, in order.
+func (s *Server) AssertSignalTypes(tb testing.TB, types ...string) {
+	tb.Helper()
+	signals := s.Signals()
+	got := make([]string, len(signals))
+	for i, signal := range signals {
+		got[i] = signal.Type
+	}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", types) {
+		tb.Errorf("fake ingest server accepted signals %q, want %q", got, types)
+	}
+}
+
+// AssertHeader fails the test unless all requests received so far carry
+// the header with the given value.
+func (s *Server) AssertHeader(tb testing.TB, key, value string) {
+	tb.Helper()
+	for i, request := range s.Requests() {
+		if got := request.Header.Get(key); got != value {
+			tb.Errorf("request %d: header %s = %q, want %q", i, key, got, value)
+		}
+	}
+}
+
+// AssertMinInterval fails the test unless each request followed the
+// previous one by at least the given duration, e.g. to check that the
+// client backed off as requested by Retry-After.
+func (s *Server) AssertMinInterval(tb testing.TB, interval time.Duration) {
+	tb.Helper()
+	requests := s.Requests()
+	for i := 1; i < len(requests); i++ {
+		if got := requests[i].Time.Sub(requests[i-1].Time); got < interval {
+			tb.Errorf("request %d followed request %d after %s, want at least %s", i, i-1, got, interval)
+		}
+	}
+}
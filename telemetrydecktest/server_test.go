@@ -0,0 +1,92 @@
+package telemetrydecktest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/giantswarm/telemetrydeck-go"
+)
+
+func TestServer_Retries(t *testing.T) {
+	server := NewServer(t)
+	server.Respond(
+		Status(http.StatusServiceUnavailable, "maintenance"),
+		RateLimited(time.Second),
+	)
+
+	c, err := telemetrydeck.NewClient("my-app-id",
+		telemetrydeck.WithEndpoint(server.URL),
+		telemetrydeck.WithCompression(),
+		telemetrydeck.WithAuthToken("token"),
+		telemetrydeck.WithRetryPolicy(telemetrydeck.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.retryTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	server.AssertRequests(t, 3)
+	server.AssertStatuses(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	server.AssertSignalTypes(t, "TestNamespace.retryTest")
+	server.AssertHeader(t, "Authorization", "Bearer token")
+
+	requests := server.Requests()
+	if got := requests[2].Time.Sub(requests[1].Time); got < time.Second {
+		t.Errorf("client retried after %s, want Retry-After of 1s", got)
+	}
+	recorder := &recordingTB{TB: t}
+	server.AssertMinInterval(recorder, time.Second)
+	if !recorder.failed {
+		t.Error("AssertMinInterval() didn't fail for the quick first retry")
+	}
+}
+
+func TestServer_Rejected(t *testing.T) {
+	server := NewServer(t)
+	server.RespondAlways(Status(http.StatusBadRequest, "invalid signal"))
+
+	errs := make(chan error, 1)
+	c, err := telemetrydeck.NewClient("my-app-id",
+		telemetrydeck.WithEndpoint(server.URL),
+		telemetrydeck.WithHooks(telemetrydeck.Hooks{OnError: func(err error) { errs <- err }}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.SendSignal(context.Background(), "TestNamespace.rejectTest", nil); err != nil {
+		t.Fatalf("Client.SendSignal() error = %v", err)
+	}
+	server.WaitForRequests(t, 1, 5*time.Second)
+
+	select {
+	case err := <-errs:
+		var responseErr *telemetrydeck.ResponseError
+		if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusBadRequest || responseErr.Body != "invalid signal" {
+			t.Errorf("OnError() error = %v, want 400 with body", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError() not called")
+	}
+
+	server.AssertRequests(t, 1)
+	if got := len(server.Requests()[0].Signals); got != 1 {
+		t.Errorf("request has %d signals, want 1", got)
+	}
+	if got := server.Signals(); len(got) != 0 {
+		t.Errorf("Signals() = %v, want none accepted", got)
+	}
+
+	recorder := &recordingTB{TB: t}
+	server.AssertSignalTypes(recorder, "TestNamespace.rejectTest")
+	if !recorder.failed {
+		t.Error("AssertSignalTypes() didn't fail for rejected signal")
+	}
+}
@@ -0,0 +1,69 @@
+package telemetrydeck
+
+import "context"
+
+// States of a client toggled via Enable and Disable.
+const (
+	toggleEnabled int32 = iota
+	// New signals are discarded, queued ones delivered
+	toggleDraining
+	// New and queued signals are discarded
+	toggleDisabled
+)
+
+// Disable makes the client discard signals instead of sending them, e.g.
+// when the user opted out of telemetry in the application, until Enable is
+// called. The Send methods return nil for discarded signals. Signals
+// queued before are discarded too, and passed to the OnDrop hook with
+// ErrClientDisabled; requests already in progress are completed. Spooled
+// signals (see WithSpoolDir) are kept, but not sent until Enable is called.
+func (c *Client) Disable() {
+	c.toggle.Store(toggleDisabled)
+
+	// Workers discard the queued signals
+	if c.store.Len() > 0 {
+		c.startWorkers()
+	}
+}
+
+// DisableAndFlush works like Disable, but delivers the signals queued
+// before, waiting no longer than the context allows, see Flush. Signals
+// still queued when the context is done are discarded.
+func (c *Client) DisableAndFlush(ctx context.Context) error {
+	c.toggle.Store(toggleDraining)
+	err := c.Flush(ctx)
+	if c.toggle.CompareAndSwap(toggleDraining, toggleDisabled) && c.store.Len() > 0 {
+		c.startWorkers()
+	}
+	return err
+}
+
+// Enable makes a client disabled via Disable send signals again, including
+// spooled ones.
+func (c *Client) Enable() {
+	c.toggle.Store(toggleEnabled)
+	c.startReplay()
+}
+
+// Enabled returns whether the client sends signals, i.e. it hasn't been
+// disabled via Disable.
+func (c *Client) Enabled() bool {
+	return c.toggle.Load() == toggleEnabled
+}
+
+// Returns whether signals passed to the Send methods are discarded.
+func (c *Client) discardsSignals() bool {
+	return c.noop || c.toggle.Load() != toggleEnabled
+}
+
+// Drops the batch of dequeued signals if the client has been disabled.
+// Returns whether it did.
+func (c *Client) discardBatch(batch []QueuedSignal) bool {
+	if c.toggle.Load() != toggleDisabled {
+		return false
+	}
+	for _, item := range batch {
+		c.drop(item.Signal, ErrClientDisabled)
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package telemetrydeck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient_Disable(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var signals []SignalBody
+		if err := json.NewDecoder(r.Body).Decode(&signals); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, signal := range signals {
+			received = append(received, signal.Type)
+		}
+	}))
+	defer server.Close()
+
+	var dropped []string
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithFlushTriggers(FlushTriggers{MaxAge: time.Hour}),
+		WithHooks(Hooks{OnDrop: func(signal SignalBody, err error) {
+			if !errors.Is(err, ErrClientDisabled) {
+				t.Errorf("drop error = %v, want ErrClientDisabled", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			dropped = append(dropped, signal.Type)
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	send := func(signalType string) {
+		t.Helper()
+		if err := c.SendSignal(context.Background(), signalType, nil); err != nil {
+			t.Fatalf("Client.SendSignal() error = %v", err)
+		}
+	}
+
+	send("TestNamespace.queued")
+	c.Disable()
+	if c.Enabled() {
+		t.Error("Client.Enabled() = true after Disable()")
+	}
+	send("TestNamespace.disabled")
+	if _, err := c.SendSignalSync(context.Background(), "TestNamespace.disabledSync", nil); err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+	if err := c.Flush(context.Background()); err != nil {
+		t.Fatalf("Client.Flush() error = %v", err)
+	}
+
+	c.Enable()
+	send("TestNamespace.enabled")
+	if err := c.DisableAndFlush(context.Background()); err != nil {
+		t.Fatalf("Client.DisableAndFlush() error = %v", err)
+	}
+	send("TestNamespace.disabledAgain")
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("Client.Close() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"TestNamespace.enabled"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("received signals = %v, want %v", received, want)
+	}
+	if want := []string{"TestNamespace.queued"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped signals = %v, want %v", dropped, want)
+	}
+}
@@ -0,0 +1,92 @@
+package telemetrydeck
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"time"
+)
+
+// TraceEventKind identifies a connection-level event during an ingest
+// request.
+type TraceEventKind string
+
+const (
+	TraceDNSStart             TraceEventKind = "DNSStart"
+	TraceDNSDone              TraceEventKind = "DNSDone"
+	TraceConnectStart         TraceEventKind = "ConnectStart"
+	TraceConnectDone          TraceEventKind = "ConnectDone"
+	TraceTLSHandshakeStart    TraceEventKind = "TLSHandshakeStart"
+	TraceTLSHandshakeDone     TraceEventKind = "TLSHandshakeDone"
+	TraceGotConn              TraceEventKind = "GotConn"
+	TraceWroteRequest         TraceEventKind = "WroteRequest"
+	TraceGotFirstResponseByte TraceEventKind = "GotFirstResponseByte"
+)
+
+// TraceEvent describes a connection-level event during an ingest request,
+// as passed to the OnTrace hook.
+type TraceEvent struct {
+	Kind TraceEventKind
+
+	// Value of the X-Request-ID header of the request.
+	RequestID string
+
+	// Time elapsed since the request was started.
+	Elapsed time.Duration
+
+	// Host name (DNS events) or network address (connect events), if any.
+	Addr string
+
+	// For TraceGotConn, whether an idle connection was reused.
+	Reused bool
+
+	// Error reported by the event, if any.
+	Err error
+}
+
+// Returns a context carrying an httptrace.ClientTrace that reports events
+// of the request with the given ID to the OnTrace hook.
+func (c *Client) withTrace(ctx context.Context, requestID string) context.Context {
+	if c.hooks.OnTrace == nil {
+		return ctx
+	}
+
+	start := c.clock.Now()
+	emit := func(event TraceEvent) {
+		event.RequestID = requestID
+		event.Elapsed = c.clock.Now().Sub(start)
+		c.hooks.OnTrace(event)
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			emit(TraceEvent{Kind: TraceDNSStart, Addr: info.Host})
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			emit(TraceEvent{Kind: TraceDNSDone, Err: info.Err})
+		},
+		ConnectStart: func(network, addr string) {
+			emit(TraceEvent{Kind: TraceConnectStart, Addr: addr})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			emit(TraceEvent{Kind: TraceConnectDone, Addr: addr, Err: err})
+		},
+		TLSHandshakeStart: func() {
+			emit(TraceEvent{Kind: TraceTLSHandshakeStart})
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			emit(TraceEvent{Kind: TraceTLSHandshakeDone, Err: err})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			emit(TraceEvent{Kind: TraceGotConn, Addr: info.Conn.RemoteAddr().String(), Reused: info.Reused})
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			emit(TraceEvent{Kind: TraceWroteRequest, Err: info.Err})
+		},
+		GotFirstResponseByte: func() {
+			emit(TraceEvent{Kind: TraceGotFirstResponseByte})
+		},
+	}
+
+	return httptrace.WithClientTrace(ctx, trace)
+}
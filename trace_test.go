@@ -0,0 +1,52 @@
+package telemetrydeck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestClient_OnTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var kinds []TraceEventKind
+	var requestIDs = map[string]bool{}
+	c, err := NewClient("my-app-id",
+		WithEndpoint(server.URL),
+		WithHooks(Hooks{OnTrace: func(event TraceEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			kinds = append(kinds, event.Kind)
+			requestIDs[event.RequestID] = true
+		}}),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	result, err := c.SendSignalSync(context.Background(), "TestNamespace.traceTest", nil)
+	if err != nil {
+		t.Fatalf("Client.SendSignalSync() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []TraceEventKind{TraceConnectStart, TraceConnectDone, TraceGotConn, TraceWroteRequest, TraceGotFirstResponseByte}
+	for _, kind := range want {
+		found := false
+		for _, k := range kinds {
+			found = found || k == kind
+		}
+		if !found {
+			t.Errorf("no %s event in %v", kind, kinds)
+		}
+	}
+	if len(requestIDs) != 1 || !requestIDs[result.RequestID] {
+		t.Errorf("trace events carry request IDs %v, want only %q", requestIDs, result.RequestID)
+	}
+}
@@ -0,0 +1,251 @@
+package telemetrydeck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Signal type of the test-mode signal sent by Ping.
+const pingSignalType = "TelemetryDeck.SDK.ping"
+
+// Settings for the HTTP transport used to submit signals. Zero values
+// mean that the defaults of http.DefaultTransport apply.
+type transportConfig struct {
+	maxConnsPerHost     int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	ipPreference        IPPreference
+	localAddr           net.IP
+	redirectPolicy      RedirectPolicy
+}
+
+// IPPreference specifies which IP protocol versions are used to connect to
+// the ingest endpoint, for use with WithIPPreference.
+type IPPreference int
+
+const (
+	// Use IPv4 or IPv6, whatever works (the default).
+	IPPreferenceDual IPPreference = iota
+	// Only use IPv4.
+	IPPreferenceIPv4
+	// Only use IPv6.
+	IPPreferenceIPv6
+)
+
+// WithMaxConnsPerHost limits the number of connections the client opens
+// to the ingest endpoint, including connections in use and idle ones. By
+// default, there is no limit.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMaxConnsPerHost(n int) func(*Client) {
+	return func(c *Client) {
+		c.transport.maxConnsPerHost = n
+	}
+}
+
+// WithMaxIdleConnsPerHost limits the number of idle connections the client
+// keeps open to the ingest endpoint for reuse. Defaults to
+// http.DefaultMaxIdleConnsPerHost.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMaxIdleConnsPerHost(n int) func(*Client) {
+	return func(c *Client) {
+		c.transport.maxIdleConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout specifies how long an idle connection to the ingest
+// endpoint is kept open for reuse. Defaults to the value used by
+// http.DefaultTransport.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithIdleConnTimeout(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.transport.idleConnTimeout = d
+	}
+}
+
+// WithIPPreference restricts connections to the ingest endpoint to IPv4 or
+// IPv6. This helps in environments with broken egress for one of the
+// protocol versions, where deliveries would otherwise time out.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithIPPreference(preference IPPreference) func(*Client) {
+	return func(c *Client) {
+		c.transport.ipPreference = preference
+	}
+}
+
+// WithLocalAddr specifies the local IP address outgoing connections to the
+// ingest endpoint are made from. On multi-homed hosts, this determines the
+// interface the telemetry traffic leaves through.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithLocalAddr(ip net.IP) func(*Client) {
+	return func(c *Client) {
+		c.transport.localAddr = ip
+	}
+}
+
+// RedirectPolicy specifies how redirect responses of the ingest endpoint
+// are handled, for use with WithRedirectPolicy.
+type RedirectPolicy int
+
+const (
+	// Follow up to 10 redirects, like net/http does (the default).
+	RedirectFollow RedirectPolicy = iota
+	// Don't follow redirects. Redirect responses are treated as errors.
+	RedirectNone
+	// Only follow redirects to the same host.
+	RedirectSameHost
+)
+
+// Maximum number of redirects followed, same as net/http.
+const maxRedirects = 10
+
+// WithRedirectPolicy specifies how redirect responses of the ingest
+// endpoint are handled. Not following redirects helps with proxies
+// answering with captive-portal-style redirects. Redirect responses that
+// are not followed are reported as a *ResponseError.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithRedirectPolicy(policy RedirectPolicy) func(*Client) {
+	return func(c *Client) {
+		c.transport.redirectPolicy = policy
+	}
+}
+
+// Returns the http.Client.CheckRedirect function implementing the policy.
+func (p RedirectPolicy) checkRedirect() func(request *http.Request, via []*http.Request) error {
+	switch p {
+	case RedirectNone:
+		return func(request *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	case RedirectSameHost:
+		return func(request *http.Request, via []*http.Request) error {
+			if request.URL.Host != via[0].URL.Host {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// Returns an HTTP client using a transport with the configured settings.
+func (tc transportConfig) newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport:     tc.newTransport(),
+		CheckRedirect: tc.redirectPolicy.checkRedirect(),
+	}
+}
+
+// Returns an HTTP transport based on http.DefaultTransport, with the
+// configured settings applied.
+func (tc transportConfig) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Same settings as used by http.DefaultTransport
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if tc.localAddr != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: tc.localAddr}
+	}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, tc.ipPreference.network(network), address)
+	}
+
+	if tc.maxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = tc.maxConnsPerHost
+	}
+	if tc.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = tc.maxIdleConnsPerHost
+	}
+	if tc.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = tc.idleConnTimeout
+	}
+
+	return transport
+}
+
+// Returns the network to dial, restricted to the preferred IP protocol
+// version.
+func (p IPPreference) network(network string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch p {
+	case IPPreferenceIPv4:
+		return "tcp4"
+	case IPPreferenceIPv6:
+		return "tcp6"
+	}
+	return network
+}
+
+// Warmup establishes a connection to the active endpoint by sending an
+// empty HEAD request, so that DNS resolution and the TLS handshake have
+// already happened when the first signal is sent. This is mainly useful
+// for short-lived programs like CLIs, where the first signal otherwise
+// risks being cut off at process exit.
+//
+// The connection is kept in the HTTP client's idle pool. Any HTTP status
+// returned by the endpoint is considered a success.
+func (c *Client) Warmup(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, c.activeEndpoint(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Drain the body, so the connection can be reused.
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	return nil
+}
+
+// Ping verifies that the active endpoint is reachable and accepts the
+// client's app ID, by synchronously sending a test-mode signal of type
+// "TelemetryDeck.SDK.ping". This is meant for diagnostics, e.g. to tell
+// users that telemetry is unavailable.
+//
+// An error wrapping ErrUnreachable is returned if the endpoint could not
+// be reached. A *ResponseError is returned if the endpoint rejected the
+// signal.
+func (c *Client) Ping(ctx context.Context) error {
+	signal := c.newSignal(pingSignalType, nil)
+	signal.IsTestMode = true
+
+	d, err := c.newDelivery([]SignalBody{signal}, c.authToken)
+	if err != nil {
+		return err
+	}
+	defer d.release()
+
+	if d.token, err = c.authTokenValue(ctx, d); err != nil {
+		return err
+	}
+
+	request, err := c.newRequest(ctx, c.activeEndpoint(), d)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(ctx, request, d)
+	return err
+}
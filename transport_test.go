@@ -0,0 +1,242 @@
+package telemetrydeck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Warmup(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Errorf("Client.Warmup() error = %v", err)
+	}
+	if method != http.MethodHead {
+		t.Errorf("Client.Warmup() sent %s request, want %s", method, http.MethodHead)
+	}
+
+	server.Close()
+	if err := c.Warmup(context.Background()); err == nil {
+		t.Errorf("Client.Warmup() with unreachable endpoint returned no error")
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		unreachable bool
+		wantStatus  int
+	}{
+		{
+			name:   "accepted",
+			status: http.StatusOK,
+		},
+		{
+			name:       "rejected",
+			status:     http.StatusBadRequest,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "unreachable",
+			unreachable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !strings.Contains(string(body), `"isTestMode":true`) {
+					t.Errorf("ping signal not sent in test mode: %s", body)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			if tt.unreachable {
+				server.Close()
+			}
+
+			c, err := NewClient("my-app-id", WithEndpoint(server.URL))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			err = c.Ping(context.Background())
+
+			var responseErr *ResponseError
+			switch {
+			case tt.unreachable:
+				if !errors.Is(err, ErrUnreachable) {
+					t.Errorf("Client.Ping() error = %v, want ErrUnreachable", err)
+				}
+			case tt.wantStatus != 0:
+				if !errors.As(err, &responseErr) || responseErr.StatusCode != tt.wantStatus {
+					t.Errorf("Client.Ping() error = %v, want ResponseError with status %d", err, tt.wantStatus)
+				}
+			case err != nil:
+				t.Errorf("Client.Ping() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_ConnectionPoolOptions(t *testing.T) {
+	c, err := NewClient("my-app-id",
+		WithMaxConnsPerHost(4),
+		WithMaxIdleConnsPerHost(2),
+		WithIdleConnTimeout(30*time.Second),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	transport, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("unexpected transport type %T", c.httpClient.Transport)
+	}
+	if transport.MaxConnsPerHost != 4 {
+		t.Errorf("MaxConnsPerHost = %d, want 4", transport.MaxConnsPerHost)
+	}
+	if transport.MaxIdleConnsPerHost != 2 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want 2", transport.MaxIdleConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 30*time.Second {
+		t.Errorf("IdleConnTimeout = %s, want 30s", transport.IdleConnTimeout)
+	}
+}
+
+func TestIPPreference_network(t *testing.T) {
+	tests := []struct {
+		preference IPPreference
+		network    string
+		want       string
+	}{
+		{preference: IPPreferenceDual, network: "tcp", want: "tcp"},
+		{preference: IPPreferenceIPv4, network: "tcp", want: "tcp4"},
+		{preference: IPPreferenceIPv6, network: "tcp", want: "tcp6"},
+		{preference: IPPreferenceIPv4, network: "udp", want: "udp"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.preference.network(tt.network); got != tt.want {
+			t.Errorf("IPPreference(%d).network(%q) = %q, want %q", tt.preference, tt.network, got, tt.want)
+		}
+	}
+}
+
+func TestClient_IPPreference(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server listens on 127.0.0.1 only.
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithIPPreference(IPPreferenceIPv4))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Errorf("Client.Warmup() via IPv4 error = %v", err)
+	}
+
+	c, err = NewClient("my-app-id", WithEndpoint(server.URL), WithIPPreference(IPPreferenceIPv6))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Warmup(context.Background()); err == nil {
+		t.Errorf("Client.Warmup() via IPv6 to IPv4 address returned no error")
+	}
+}
+
+func TestClient_LocalAddr(t *testing.T) {
+	var remoteAddr string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+	}))
+	defer server.Close()
+
+	c, err := NewClient("my-app-id", WithEndpoint(server.URL), WithLocalAddr(net.ParseIP("127.0.0.1")))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Fatalf("Client.Warmup() error = %v", err)
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil || host != "127.0.0.1" {
+		t.Errorf("request came from %q, want 127.0.0.1", remoteAddr)
+	}
+
+	// Binding to an address not assigned to this host must fail.
+	c, err = NewClient("my-app-id", WithEndpoint(server.URL), WithLocalAddr(net.ParseIP("192.0.2.1")))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := c.Warmup(context.Background()); err == nil {
+		t.Errorf("Client.Warmup() from unassigned address returned no error")
+	}
+}
+
+func TestClient_RedirectPolicy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/other-host", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/same-host", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		policy     RedirectPolicy
+		path       string
+		wantStatus int
+	}{
+		{name: "follow other host", policy: RedirectFollow, path: "/other-host"},
+		{name: "none", policy: RedirectNone, path: "/same-host", wantStatus: http.StatusTemporaryRedirect},
+		{name: "same host to same host", policy: RedirectSameHost, path: "/same-host"},
+		{name: "same host to other host", policy: RedirectSameHost, path: "/other-host", wantStatus: http.StatusTemporaryRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient("my-app-id", WithEndpoint(server.URL+tt.path), WithRedirectPolicy(tt.policy))
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			_, err = c.SendSignalSync(context.Background(), "TestNamespace.redirectTest", nil)
+
+			var responseErr *ResponseError
+			if tt.wantStatus == 0 && err != nil {
+				t.Errorf("Client.SendSignalSync() error = %v", err)
+			}
+			if tt.wantStatus != 0 && (!errors.As(err, &responseErr) || responseErr.StatusCode != tt.wantStatus) {
+				t.Errorf("Client.SendSignalSync() error = %v, want ResponseError with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
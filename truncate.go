@@ -0,0 +1,55 @@
+package telemetrydeck
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"unicode/utf8"
+)
+
+// Number of hex digits of the hash appended to truncated values.
+const truncatedHashLength = 8
+
+// Smallest maximum value length accepted by WithMaxValueLength, leaving
+// room for some of the value besides the hash suffix.
+const minMaxValueLength = 16
+
+// WithMaxValueLength makes the client truncate payload string values
+// longer than n bytes, e.g. long error messages, so that signals stay
+// small. Truncated values end with "…" and a short hash of the full value,
+// so that different long values can still be told apart, and the same
+// value is always truncated the same way. Values of n smaller than 16 are
+// ignored.
+//
+// To be used as an option parameter in the NewClient() func.
+func WithMaxValueLength(n int) func(*Client) {
+	return func(c *Client) {
+		if n >= minMaxValueLength {
+			c.maxValueLength = n
+		}
+	}
+}
+
+// Returns the payload with string values longer than the maximum length
+// truncated, which is a copy if any value was truncated.
+func truncateValues[V any](payload map[string]V, maxLength int) map[string]V {
+	return replaceStringValues(payload, func(s string) (string, bool) {
+		if len(s) <= maxLength {
+			return s, false
+		}
+		return truncateValue(s, maxLength), true
+	})
+}
+
+// Truncates the value to the maximum length, including the ellipsis and
+// the hash of the full value.
+func truncateValue(s string, maxLength int) string {
+	sum := sha256.Sum256([]byte(s))
+	suffix := "…" + hex.EncodeToString(sum[:])[:truncatedHashLength]
+
+	// Don't cut UTF-8 sequences in half
+	cut := maxLength - len(suffix)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + suffix
+}
@@ -0,0 +1,49 @@
+package telemetrydeck
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateValues(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	payload := map[string]interface{}{
+		"TestNamespace.short":  "short",
+		"TestNamespace.long":   long,
+		"TestNamespace.number": 12345,
+	}
+	got := truncateValues(payload, 20)
+
+	truncated := got["TestNamespace.long"].(string)
+	if len(truncated) != 20 || !strings.HasPrefix(truncated, "aaaaaaaaa…") {
+		t.Errorf("truncated value = %q, want 20 bytes ending with ellipsis and hash", truncated)
+	}
+	if got["TestNamespace.short"] != "short" || got["TestNamespace.number"] != 12345 {
+		t.Errorf("payload = %v, want other values unchanged", got)
+	}
+	if payload["TestNamespace.long"] != long {
+		t.Error("caller's payload was modified")
+	}
+
+	if other := truncateValue(strings.Repeat("a", 99)+"b", 20); other == truncated {
+		t.Errorf("different values truncated to the same value %q", other)
+	}
+	if again := truncateValue(long, 20); again != truncated {
+		t.Errorf("same value truncated to %q and %q", truncated, again)
+	}
+}
+
+func TestTruncateValue_utf8(t *testing.T) {
+	got := truncateValue(strings.Repeat("ü", 20), 20)
+	if !utf8.ValidString(got) || len(got) > 20 {
+		t.Errorf("truncateValue() = %q, want valid UTF-8 of at most 20 bytes", got)
+	}
+}
+
+func TestTruncateValues_string(t *testing.T) {
+	payload := map[string]string{"TestNamespace.long": strings.Repeat("a", 100)}
+	if got := truncateValues(payload, 16)["TestNamespace.long"]; len(got) != 16 {
+		t.Errorf("truncated value = %q, want 16 bytes", got)
+	}
+}